@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
 	"my-chat-jobsity-challenge/pkg/utl/secure"
 
 	"github.com/go-pg/pg/v9"
@@ -29,7 +30,12 @@ func main() {
 	db := pg.Connect(u)
 	_, err = db.Exec("SELECT 1")
 	checkErr(err)
-	createSchema(db, &jobsity.Company{}, &jobsity.Location{}, &jobsity.Role{}, &jobsity.User{})
+	createSchema(db, &jobsity.Company{}, &jobsity.Location{}, &jobsity.Role{}, &jobsity.User{}, &jobsity.Message{}, &jobsity.Room{}, &jobsity.RoomMember{}, &jobsity.Sanction{}, &jobsity.ModerationLog{}, &jobsity.Conversation{}, &jobsity.ReadCursor{}, &jobsity.MessageEdit{}, &jobsity.Reaction{}, &jobsity.Mention{})
+
+	for _, index := range []string{pgsql.RoomNameIndex, pgsql.ReactionIndex, pgsql.ReadCursorIndex, pgsql.ConversationIndex} {
+		_, err = db.Exec(index)
+		checkErr(err)
+	}
 
 	for _, v := range queries[0 : len(queries)-1] {
 		_, err := db.Exec(v)
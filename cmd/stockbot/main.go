@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+
+	"my-chat-jobsity-challenge/pkg/stockbot"
+
+	"my-chat-jobsity-challenge/pkg/utl/config"
+)
+
+func main() {
+
+	cfgPath := flag.String("p", "./cmd/api/conf.local.yaml", "Path to config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*cfgPath)
+	checkErr(err)
+
+	checkErr(stockbot.Start(cfg))
+}
+
+func checkErr(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
@@ -0,0 +1,29 @@
+package jobsity
+
+import "fmt"
+
+// DirectKey returns the key the messages between two users are stored under, like the messages of a room.
+// Keys cannot clash with room keys, since room names cannot contain @.
+func DirectKey(userID, peerID int) string {
+	if userID > peerID {
+		userID, peerID = peerID, userID
+	}
+	return fmt.Sprintf("@%d:%d", userID, peerID)
+}
+
+// Conversation represents a user's side of a direct conversation with another user
+type Conversation struct {
+	Base
+	UserID     int   `json:"user_id"`
+	PeerID     int   `json:"peer_id"`
+	Peer       *User `json:"peer,omitempty"`
+	LastReadID int   `json:"last_read_id"`
+
+	LastMessage *Message `json:"last_message,omitempty" pg:"-"`
+	Unread      int      `json:"unread" pg:"-"`
+}
+
+// Key returns the key the conversation's messages are stored under
+func (c Conversation) Key() string {
+	return DirectKey(c.UserID, c.PeerID)
+}
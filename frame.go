@@ -0,0 +1,124 @@
+package jobsity
+
+import (
+	"time"
+)
+
+// ProtocolVersion is the version of the chat wire protocol
+const ProtocolVersion = 1
+
+// Frame types sent by clients
+const (
+	FrameJoin    = "join"
+	FrameLeave   = "leave"
+	FrameMessage = "message"
+	FrameCommand = "command"
+	// FrameReply is a message replying to the message with the frame's parent ID, threaded under it
+	FrameReply = "reply"
+	// FrameDirect is a direct message to a user, echoed to the sender's other sessions
+	FrameDirect = "direct"
+	// FrameAway and FrameBack mark the connection away or back, and announce the changes
+	// of the user's status to the user's rooms
+	FrameAway = "away"
+	FrameBack = "back"
+	// FrameTyping and FrameStopTyping tell that the user started or stopped typing in a room.
+	// They are relayed to the room's other clients, but never persisted.
+	FrameTyping     = "typing"
+	FrameStopTyping = "stop_typing"
+	// FrameRead marks the room read up to the message with the frame's ID. Rooms with few users
+	// present are sent it back as a read receipt of the user.
+	FrameRead = "read"
+	// FrameReact toggles the user's reaction with the frame's emoji to the message with the frame's ID
+	FrameReact = "react"
+)
+
+// Frame types sent by the server. Chat messages use FrameMessage as well.
+const (
+	FrameError  = "error"
+	FrameBot    = "bot"
+	FrameSystem = "system"
+	// FrameWarning tells a client its message was not sent, like when sending messages too fast
+	FrameWarning = "warning"
+	// FrameMessageEdited carries the new version of an edited message, and FrameMessageDeleted
+	// the ID of a deleted one, for clients to update them in place
+	FrameMessageEdited  = "message.edited"
+	FrameMessageDeleted = "message.deleted"
+	// FrameReactionAdded and FrameReactionRemoved tell that the sender added or removed their reaction
+	// with the frame's emoji to the message with the frame's ID
+	FrameReactionAdded   = "reaction.added"
+	FrameReactionRemoved = "reaction.removed"
+	// FrameMention notifies a user mentioned in a room's message, wherever the user is connected
+	FrameMention = "mention"
+)
+
+// Frame represents chat wire protocol envelope, used for both client commands and server events
+type Frame struct {
+	Version   int       `json:"version"`
+	Type      string    `json:"type"`
+	Room      string    `json:"room,omitempty"`
+	ID        int       `json:"id,omitempty"`
+	ParentID  int       `json:"parent_id,omitempty"`
+	Sender    string    `json:"sender,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Payload   Payload   `json:"payload"`
+}
+
+// Payload holds frame contents
+type Payload struct {
+	Text string `json:"text,omitempty"`
+	// Mentions are the usernames the text mentions, for clients to highlight them
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// NewFrame creates a new frame of the current protocol version
+func NewFrame(typ, room, sender, text string) Frame {
+	return Frame{
+		Version:   ProtocolVersion,
+		Type:      typ,
+		Room:      room,
+		Sender:    sender,
+		Timestamp: time.Now(),
+		Payload:   Payload{Text: text},
+	}
+}
+
+// MessageFrame creates a frame of a persisted message
+func MessageFrame(typ string, m Message) Frame {
+	f := NewFrame(typ, m.Room, m.Username, m.Body)
+	f.ID = m.ID
+	f.ParentID = m.ParentID
+	f.Payload.Mentions = m.Mentions
+	f.Timestamp = m.CreatedAt
+	return f
+}
+
+// String formats frame the way it is displayed by plain text clients
+func (f Frame) String() string {
+	switch f.Type {
+	case FrameMessage, FrameBot:
+		return f.Sender + ": " + f.Payload.Text
+	case FrameJoin:
+		return f.Sender + " joined the room"
+	case FrameLeave:
+		return f.Sender + " left the room"
+	case FrameAway:
+		return f.Sender + " is away"
+	case FrameBack:
+		return f.Sender + " is back"
+	case FrameTyping:
+		return f.Sender + " is typing…"
+	case FrameReply:
+		return f.Sender + " (in thread): " + f.Payload.Text
+	case FrameMessageEdited:
+		return f.Sender + " edited a message: " + f.Payload.Text
+	case FrameMention:
+		return f.Sender + " mentioned you in " + f.Room + ": " + f.Payload.Text
+	case FrameMessageDeleted:
+		return "A message of " + f.Sender + " was deleted"
+	case FrameDirect:
+		return f.Sender + " to " + f.To + ": " + f.Payload.Text
+	default:
+		return f.Payload.Text
+	}
+}
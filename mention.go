@@ -0,0 +1,19 @@
+package jobsity
+
+// MentionRoom is the mention of every member of a room, as in @room
+const MentionRoom = "room"
+
+// Mention represents a user mentioned in a room's message, who is notified of it
+type Mention struct {
+	Base
+	MessageID int    `json:"message_id"`
+	UserID    int    `json:"user_id"`
+	Room      string `json:"room"`
+	// All tells the user was only mentioned along with the whole room
+	All bool `json:"all"`
+
+	Message *Message `json:"message,omitempty"`
+
+	// Unread tells the user has not read the room up to the message, when listed
+	Unread bool `json:"unread" pg:"-"`
+}
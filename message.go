@@ -0,0 +1,38 @@
+package jobsity
+
+import "time"
+
+// Message represents chat message domain model
+type Message struct {
+	Base
+	Room     string `json:"room"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Body     string `json:"body"`
+	// EditedAt is when the message was last edited, if ever
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// ParentID is the ID of the message replies are threaded under
+	ParentID int `json:"parent_id,omitempty"`
+
+	// Replies is the number of replies of a parent message
+	Replies int `json:"replies,omitempty" pg:"-"`
+	// Mentions are the usernames the message mentions, and MentionRoom when it mentions the whole room
+	Mentions []string `json:"mentions,omitempty" pg:",array"`
+	// Reactions are the message's reactions, by emoji
+	Reactions []ReactionCount `json:"reactions,omitempty" pg:"-"`
+}
+
+// Thread represents a message and its replies
+type Thread struct {
+	Parent  Message   `json:"parent"`
+	Replies []Message `json:"replies"`
+}
+
+// MessageEdit represents a previous version of an edited message, kept for admins
+type MessageEdit struct {
+	Base
+	MessageID int    `json:"message_id"`
+	Body      string `json:"body"`
+	// EditedBy is the user who replaced this version of the message
+	EditedBy int `json:"edited_by"`
+}
@@ -0,0 +1,48 @@
+package jobsity
+
+import "time"
+
+// SanctionKind represents the kind of a room sanction
+type SanctionKind string
+
+// Sanction kinds
+const (
+	// SanctionBan keeps the user from joining the room
+	SanctionBan SanctionKind = "ban"
+	// SanctionMute keeps the user from sending messages to the room
+	SanctionMute SanctionKind = "mute"
+)
+
+// Sanction represents a ban or a mute of a user in a room. Sanctions without expiry are permanent.
+type Sanction struct {
+	Base
+	RoomID    int          `json:"room_id"`
+	UserID    int          `json:"user_id"`
+	Kind      SanctionKind `json:"kind"`
+	Reason    string       `json:"reason"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+	CreatedBy int          `json:"created_by"`
+}
+
+// ModerationAction represents an action taken by a room moderator
+type ModerationAction string
+
+// Moderation actions
+const (
+	ActionKick  ModerationAction = "kick"
+	ActionBan   ModerationAction = "ban"
+	ActionUnban ModerationAction = "unban"
+	ActionMute  ModerationAction = "mute"
+)
+
+// ModerationLog represents a moderation action taken against a user in a room. Actions the chat takes
+// itself, like muting users who flood a room, have no moderator.
+type ModerationLog struct {
+	Base
+	RoomID      int              `json:"room_id"`
+	Action      ModerationAction `json:"action"`
+	UserID      int              `json:"user_id"`
+	ModeratorID int              `json:"moderator_id"`
+	Reason      string           `json:"reason"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+}
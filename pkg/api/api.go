@@ -34,12 +34,17 @@ package api
 import (
 	"crypto/sha1"
 	"os"
+	"time"
 
 	"my-chat-jobsity-challenge/pkg/utl/zlog"
 
 	"my-chat-jobsity-challenge/pkg/api/auth"
 	al "my-chat-jobsity-challenge/pkg/api/auth/logging"
 	at "my-chat-jobsity-challenge/pkg/api/auth/transport"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	cl "my-chat-jobsity-challenge/pkg/api/chat/logging"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	ct "my-chat-jobsity-challenge/pkg/api/chat/transport"
 	"my-chat-jobsity-challenge/pkg/api/password"
 	pl "my-chat-jobsity-challenge/pkg/api/password/logging"
 	pt "my-chat-jobsity-challenge/pkg/api/password/transport"
@@ -47,6 +52,8 @@ import (
 	ul "my-chat-jobsity-challenge/pkg/api/user/logging"
 	ut "my-chat-jobsity-challenge/pkg/api/user/transport"
 
+	"my-chat-jobsity-challenge/pkg/stockbot"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
 	"my-chat-jobsity-challenge/pkg/utl/config"
 	"my-chat-jobsity-challenge/pkg/utl/jwt"
 	authMw "my-chat-jobsity-challenge/pkg/utl/middleware/auth"
@@ -70,6 +77,21 @@ func Start(cfg *config.Configuration) error {
 		return err
 	}
 
+	brokerURL := os.Getenv("RABBITMQ_URL")
+	if brokerURL == "" {
+		brokerURL = cfg.Broker.URL
+	}
+	b, err := broker.New(cfg.Broker.Driver, brokerURL, cfg.Broker.Exchange)
+	if err != nil {
+		return err
+	}
+	if cfg.Broker.Driver != broker.DriverAMQP {
+		// The in-process broker cannot reach a separate stock bot process, so the bot runs in-process as well
+		if err := stockbot.New(stockbot.NewProvider(cfg.StockBot)).Serve(b, cfg.Chat.StockQueue); err != nil {
+			return err
+		}
+	}
+
 	log := zlog.New()
 
 	e := server.New()
@@ -84,7 +106,47 @@ func Start(cfg *config.Configuration) error {
 
 	ut.NewHTTP(ul.New(user.Initialize(db, rbac, sec), log), v1)
 	pt.NewHTTP(pl.New(password.Initialize(db, rbac, sec), log), v1)
-	//ct.NewHTTP(chat.Initialize(db, rbac, sec)), v1)
+	hub := ws.NewHub(ws.Config{
+		QueueSize:     cfg.Chat.QueueSize,
+		HighWaterMark: cfg.Chat.HighWaterMark,
+		WriteTimeout:  time.Duration(cfg.Chat.WriteTimeout) * time.Second,
+	})
+	stockBot := stockbot.NewClient(b, cfg.Chat.StockQueue, time.Duration(cfg.Chat.StockTimeout)*time.Second)
+	var limits chat.RateLimit
+	if rl := cfg.Chat.RateLimit; rl != nil {
+		limits = chat.RateLimit{
+			UserMessages:    rl.UserMessages,
+			UserInterval:    time.Duration(rl.UserInterval) * time.Second,
+			RoomMessages:    rl.RoomMessages,
+			RoomInterval:    time.Duration(rl.RoomInterval) * time.Second,
+			Violations:      rl.Violations,
+			ViolationWindow: time.Duration(rl.ViolationWindow) * time.Second,
+			MuteDuration:    time.Duration(rl.MuteDuration) * time.Second,
+		}
+	}
+	var filters chat.FilterConfig
+	if f := cfg.Chat.Filters; f != nil {
+		filters = chat.FilterConfig{
+			Default:          f.Default,
+			MaxLength:        f.MaxLength,
+			ProfanityFile:    f.ProfanityFile,
+			BlockedWordsFile: f.BlockedWordsFile,
+		}
+	}
+	chatSvc, err := chat.Initialize(cfg.Chat.Rooms, db, b, stockBot, hub, rbac, limits, filters)
+	if err != nil {
+		return err
+	}
+	chatSvc.SetReadReceipts(cfg.Chat.ReadReceipts)
+	if cfg.Chat.EditWindow > 0 {
+		chatSvc.SetEditWindow(time.Duration(cfg.Chat.EditWindow) * time.Second)
+	}
+	heartbeat := chat.DefaultHeartbeatInterval
+	if cfg.Chat.Heartbeat > 0 {
+		heartbeat = time.Duration(cfg.Chat.Heartbeat) * time.Second
+	}
+	chatSvc.SetHeartbeat(heartbeat)
+	ct.NewHTTP(cl.New(chatSvc, log), v1)
 
 	server.Start(e, &server.Config{
 		Port:                cfg.Server.Port,
@@ -1,236 +1,406 @@
 package chat
 
 import (
-	"encoding/csv"
 	"fmt"
 	"github.com/labstack/echo"
 	"golang.org/x/net/websocket"
 	jobsity "my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	websocket2 "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
 	"net/http"
-	"strconv"
 	"strings"
 )
 
-func (s *Chat) JoinRoom(c echo.Context, conn *websocket.Conn, roomName string, user jobsity.AuthUser) error {
-	// Look up the room in the map
-	room, ok := s.Rooms[roomName]
+// Custom errors
+var (
+	ErrNotInRoom     = echo.NewHTTPError(http.StatusBadRequest, "not in room")
+	ErrAlreadyInRoom = echo.NewHTTPError(http.StatusBadRequest, "already in room")
+)
+
+// JoinRoom adds the connection to a room and back-fills it with the room's history. Rooms are
+// looked up in the user's company first and among the global rooms then.
+func (s *Chat) JoinRoom(c echo.Context, conn *websocket.Conn, roomName string) error {
+	if err := s.provisionTenantRooms(s.rbac.User(c)); err != nil {
+		return err
+	}
+	room, err := s.room(c, roomName)
+	if err == pgsql.ErrRoomNotFound {
+		return websocket2.ErrRoomNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if room.Archived {
+		return ErrRoomArchived
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return err
+	}
+	cl := s.session(c, conn)
+	if err := s.enforceNotSanctioned(room, cl.User.ID, jobsity.SanctionBan); err != nil {
+		return err
+	}
+
+	if _, ok := cl.joinedRoom(room.Name); ok {
+		return ErrAlreadyInRoom
+	}
+
+	// Rooms not started yet, like the default rooms provisioned after startup, are started on their first join
+	key := room.Key()
+	if !s.hub.Has(key) {
+		if err := s.openRoom(key, false); err != nil && !s.hub.Has(key) {
+			return err
+		}
+	}
+
+	// Back-fill the client with the room's latest messages
+	history, err := s.mdb.List(s.db, key, jobsity.Pagination{Limit: HistoryLimit})
+	if err != nil {
+		return err
+	}
+	for _, msg := range history {
+		msg.Room = room.Name
+		cl.Send(messageFrame(msg))
+	}
+	cl.Send(jobsity.NewFrame(jobsity.FrameSystem, room.Name, "", "Welcome to the "+room.Name+" chat room!"))
+
+	// Add the client to the room and let the other members know, unless the user was already present
+	if err := s.hub.Join(key, cl.Client); err != nil {
+		return err
+	}
+	cl.setRoom(room)
+	return s.updatePresence(cl.User, cl.Client)
+}
+
+// LeaveRoom removes the connection from a room
+func (s *Chat) LeaveRoom(c echo.Context, conn *websocket.Conn, roomName string) error {
+	cl, ok := s.lookup(conn)
+	if !ok {
+		return ErrNotInRoom
+	}
+	return s.leave(cl, roomName)
+}
+
+func (s *Chat) leave(cl *client, roomName string) error {
+	room, ok := cl.joinedRoom(roomName)
 	if !ok {
-		return fmt.Errorf("room %s not found", roomName)
+		return ErrNotInRoom
 	}
 
-	// Add the client to the room
-	//room.AddClient(conn, user)
-	s.ws.AddClient(conn, room)
-	return nil
+	cl.unsetRoom(roomName)
+	if err := s.hub.Leave(room.Key(), cl.Client); err != nil {
+		return err
+	}
+	if err := s.stopTyping(room, cl.User); err != nil {
+		return err
+	}
+	// Users stay present in the room while any of their sessions is in it
+	return s.updatePresence(cl.User, nil)
 }
 
-func (s *Chat) LeaveRoom(c echo.Context, roomName string, conn *websocket.Conn, user *jobsity.AuthUser) error {
-	if room, ok := s.Rooms[roomName]; ok {
-		s.ws.RemoveClient(conn, room)
-		s.sendMessageToRoom(roomName, fmt.Sprintf("%s left the room", user.Username), nil)
+// room returns the room named roomName in the current user's company, or the global one
+func (s *Chat) room(c echo.Context, roomName string) (jobsity.Room, error) {
+	return s.rdb.View(s.db, roomName, s.rbac.User(c).CompanyID)
+}
+
+// Disconnect removes the connection from every room it joined and closes its session.
+// Users go offline with their last session.
+func (s *Chat) Disconnect(c echo.Context, conn *websocket.Conn) error {
+	s.mu.Lock()
+	cl, ok := s.sessions[conn]
+	delete(s.sessions, conn)
+	s.mu.Unlock()
+	if !ok {
 		return nil
 	}
-	return fmt.Errorf("room %s not found", roomName)
-}
 
-func (s *Chat) CreateRoom(c echo.Context, roomName string, user *jobsity.AuthUser) error {
-	// Only admins can create new rooms
-	if user.Role != jobsity.AdminRole {
-		return fmt.Errorf("not authorized")
+	for _, roomName := range cl.joined() {
+		// The room might have been deleted meanwhile, so errors are not relevant here
+		s.leave(cl, roomName)
 	}
+	cl.Close()
+	err := s.updatePresence(cl.User, nil)
+	if reason := cl.Reason(); reason != "" {
+		return fmt.Errorf("client evicted: %s", reason)
+	}
+	return err
+}
 
-	// Check if the room already exists
-	if _, ok := s.Rooms[roomName]; ok {
-		return fmt.Errorf("room %s already exists", roomName)
+// session returns the client attached to the connection, creating it with
+// the authenticated user of the WebSocket handshake on first use
+func (s *Chat) session(c echo.Context, conn *websocket.Conn) *client {
+	if cl, ok := s.lookup(conn); ok {
+		return cl
+	}
+	s.mu.Lock()
+	if cl, ok := s.sessions[conn]; ok {
+		s.mu.Unlock()
+		return cl
 	}
+	cl := &client{
+		Client: s.hub.Connect(conn, s.rbac.User(c)),
+		rooms:  make(map[string]jobsity.Room),
+	}
+	s.sessions[conn] = cl
+	s.mu.Unlock()
+
+	// The user is online from their first session. Failing to share it with the
+	// other instances does not prevent the session from being used.
+	s.updatePresence(cl.User, nil)
+	return cl
+}
 
-	// Create a new room and start it
-	room := jobsity.NewRoom(roomName, s.rabbit)
-	s.Rooms[roomName] = room
-	go s.ws.Run(room)
+func (s *Chat) lookup(conn *websocket.Conn) (*client, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cl, ok := s.sessions[conn]
+	return cl, ok
+}
 
-	return nil
+// joinedRoom returns a room the client joined by its name, regardless of case like the room lookups
+func (cl *client) joinedRoom(roomName string) (jobsity.Room, bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	room, ok := cl.rooms[strings.ToLower(roomName)]
+	return room, ok
 }
 
-func (s *Chat) DeleteRoom(c echo.Context, roomName string, user *jobsity.AuthUser) error {
-	// Only admins can delete rooms
-	if user.Role != jobsity.AdminRole {
-		return fmt.Errorf("not authorized")
+// roomByKey returns a room the client joined by its key
+func (cl *client) roomByKey(key string) (jobsity.Room, bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, room := range cl.rooms {
+		if room.Key() == key {
+			return room, true
+		}
 	}
+	return jobsity.Room{}, false
+}
 
-	// Check if the room exists
-	if room, ok := s.Rooms[roomName]; ok {
-		// Stop the room's message broadcasting loop
-		room.Quit <- true
-		delete(s.Rooms, roomName)
-		return nil
+func (cl *client) setRoom(room jobsity.Room) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.rooms[strings.ToLower(room.Name)] = room
+}
+
+func (cl *client) unsetRoom(roomName string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	delete(cl.rooms, strings.ToLower(roomName))
+}
+
+func (cl *client) joined() []string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	rooms := make([]string, 0, len(cl.rooms))
+	for _, room := range cl.rooms {
+		rooms = append(rooms, room.Name)
 	}
-	return fmt.Errorf("room %s not found", roomName)
+	return rooms
 }
 
-func (s *Chat) SendMessageOne(c echo.Context, roomName string, user *jobsity.AuthUser, message string) error {
-	if _, ok := s.Rooms[roomName]; ok {
-		msg := fmt.Sprintf("%s: %s", user.Username, message)
-		s.sendMessageToRoom(roomName, msg, user)
-		return nil
+// HandleFrame decodes a frame received through the connection and executes it. Frames which
+// do not name a room are addressed to roomName. Failures are reported back to the connection
+// as error frames, besides being returned.
+func (s *Chat) HandleFrame(c echo.Context, conn *websocket.Conn, roomName string, data []byte) (jobsity.Frame, error) {
+	f, err := websocket2.Decode(websocket2.Protocol(conn), data, roomName)
+	if err != nil {
+		s.session(c, conn).Send(errorFrame(roomName, err))
+		return f, err
 	}
-	return fmt.Errorf("room %s not found", roomName)
+
+	switch f.Type {
+	case jobsity.FrameJoin:
+		err = s.JoinRoom(c, conn, f.Room)
+	case jobsity.FrameLeave:
+		err = s.LeaveRoom(c, conn, f.Room)
+	case jobsity.FrameMessage:
+		err = s.SendMessage(c, conn, f.Room, f.Payload.Text)
+	case jobsity.FrameReply:
+		err = s.SendReply(c, conn, f.Room, f.ParentID, f.Payload.Text)
+	case jobsity.FrameCommand:
+		err = s.HandleCommand(c, conn, f.Room, f.Payload.Text)
+	case jobsity.FrameDirect:
+		_, err = s.SendDirect(c, f.To, f.Payload.Text)
+	case jobsity.FrameAway, jobsity.FrameBack:
+		err = s.SetAway(c, conn, f.Type == jobsity.FrameAway)
+	case jobsity.FrameTyping, jobsity.FrameStopTyping:
+		err = s.Typing(c, conn, f.Room, f.Type == jobsity.FrameTyping)
+	case jobsity.FrameRead:
+		_, err = s.MarkRead(c, f.Room, f.ID)
+	case jobsity.FrameReact:
+		_, err = s.ToggleReaction(c, f.Room, f.ID, f.Payload.Text)
+	default:
+		err = fmt.Errorf("unsupported frame type: %s", f.Type)
+	}
+	if err != nil {
+		s.session(c, conn).Send(errorFrame(f.Room, err))
+	}
+	return f, err
 }
 
-func (s *Chat) HandleCommand(c echo.Context, conn *websocket.Conn, roomName string, message string) error {
-	if strings.HasPrefix(message, "/join ") {
-		// Join the specified room
-		room := strings.TrimPrefix(message, "/join ")
-		return s.JoinRoom(c, conn, room, jobsity.AuthUser{})
-	} else if strings.HasPrefix(message, "/leave") {
-		// Leave the current room
-		return s.LeaveRoom(c, roomName, conn, nil)
-	} else if strings.HasPrefix(message, "/users") {
-		// Get the list of users in the current room
-		users, err := s.GetUsersInRoom(c, roomName)
-		if err != nil {
-			return err
-		}
-		// Construct a message with the list of users
-		var msg string
-		if len(users) == 0 {
-			msg = "There are no users in this room."
-		} else {
-			msg = "Users in this room: " + strings.Join(users, ", ")
-		}
-		// Send the message to the client
-		err = websocket.Message.Send(conn, msg)
-		if err != nil {
-			return err
+// errorFrame creates the frame reporting err to a client. Throttled messages are reported with a warning.
+func errorFrame(roomName string, err error) jobsity.Frame {
+	typ, text := jobsity.FrameError, err.Error()
+	if he, ok := err.(*echo.HTTPError); ok {
+		text = fmt.Sprint(he.Message)
+		if he.Code == http.StatusTooManyRequests {
+			typ = jobsity.FrameWarning
 		}
-		return nil
-	} else if strings.HasPrefix(message, "/stock=") {
-		// Handle stock command
-		stockCode := strings.TrimPrefix(message, "/stock=")
-		go s.handleStockCommand(conn, roomName, nil, stockCode)
-		return nil
-	} else if strings.HasPrefix(message, "/create ") {
-		// Create a new room (admin only)
-		//if r.RoleID < jobsity.SuperAdminRole || r.RoleID > jobsity.UserRole {
-		//	return jobsity.ErrBadRequest
-		//}
-		//err := s.RBAC.IsLowerRole(c, jobsity.UserRole)
-		//if err != nil {
-		//	return err
-		//}
-		roomName := strings.TrimPrefix(message, "/create ")
-		return s.CreateRoom(c, roomName, nil)
-	} else {
-		// Unrecognized command
-		return fmt.Errorf("unrecognized command: %s", message)
 	}
+	return jobsity.NewFrame(typ, roomName, "", text)
 }
 
+// GetUsersInRoom returns usernames of the users present in a room on any chat instance, ordered by username
 func (s *Chat) GetUsersInRoom(c echo.Context, roomName string) ([]string, error) {
-	// Get the list of clients in the room
-	clients, ok := s.clients[roomName]
-	if !ok {
-		return nil, fmt.Errorf("room %s not found", roomName)
+	room, err := s.room(c, roomName)
+	if err == pgsql.ErrRoomNotFound {
+		return nil, websocket2.ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return nil, err
+	}
+	key := room.Key()
+	if !s.hub.Has(key) {
+		return nil, websocket2.ErrRoomNotFound
 	}
 
-	// Extract the list of usernames from the clients
+	// Extract the list of usernames from the users in the room
 	var usernames []string
-	for _, client := range clients {
-		usernames = append(usernames, client.username)
+	for _, p := range s.presence.list(inRoom(key)) {
+		usernames = append(usernames, p.Username)
 	}
 
 	return usernames, nil
 }
 
-func (s *Chat) HandleMessage(ws *websocket.Conn, room string, message string) {
-	if strings.HasPrefix(message, "/stock=") {
-		// Handle stock command
-		stockCode := strings.TrimPrefix(message, "/stock=")
-		go s.handleStockCommand(ws, room, nil, stockCode)
-	} else {
-		// Broadcast the message to all clients in the room
-		roomFrom, ok := s.Rooms[room]
-		if !ok {
-			return
-		}
+// SendMessage persists a message sent through the connection and broadcasts it to the room
+func (s *Chat) SendMessage(c echo.Context, conn *websocket.Conn, roomName string, message string) error {
+	return s.send(c, conn, roomName, 0, message)
+}
 
-		s.ws.BroadcastMessage([]byte(message), ws, roomFrom)
+// send persists a message sent through the connection, replying to the message with parentID if any,
+// and broadcasts it to the room
+func (s *Chat) send(c echo.Context, conn *websocket.Conn, roomName string, parentID int, message string) error {
+	if strings.TrimSpace(message) == "" {
+		return ErrEmptyMessage
+	}
+	cl := s.session(c, conn)
+	room, err := s.postableRoom(c, conn, roomName)
+	if err != nil {
+		return err
+	}
+	key := room.Key()
+	if !s.hub.Has(key) {
+		return websocket2.ErrRoomNotFound
+	}
 
+	// Stock quote requests may also be sent as regular messages
+	if strings.HasPrefix(message, "/stock=") {
+		return s.HandleCommand(c, conn, roomName, message)
+	}
+	if parentID != 0 {
+		parent, err := s.threadHead(room, parentID)
+		if err != nil {
+			return err
+		}
+		parentID = parent.ID
+	}
+	if err := s.throttle(c, room); err != nil {
+		return err
 	}
-}
 
-func (s *Chat) SendMessage(roomName string, user *jobsity.AuthUser, message string) error {
-	if room, ok := s.Rooms[roomName]; ok {
-		// Handle stock command messages
-		if strings.HasPrefix(message, "/stock=") {
-			stockCode := strings.TrimPrefix(message, "/stock=")
-			err := s.handleStockCommand(nil, roomName, user, stockCode)
-			if err != nil {
-				return err
-			}
-			return nil
+	// Persist and broadcast regular messages, once filtered, and notify the users they mention.
+	// Users stop typing with their message.
+	return s.sendFiltered(c, room, message, func(c echo.Context, room jobsity.Room, text string) error {
+		usernames, mentions, err := s.mentions(room, cl.User, text)
+		if err != nil {
+			return err
+		}
+		msg, err := s.saveMessage(key, parentID, &cl.User, text, usernames)
+		if err != nil {
+			return err
 		}
+		msg.Room = room.Name
+		if err := s.broadcast(key, messageFrame(msg), nil); err != nil {
+			return err
+		}
+		if err := s.notifyMentions(msg, mentions); err != nil {
+			return err
+		}
+		return s.stopTyping(room, cl.User)
+	})
+}
 
-		// Broadcast regular messages
-		msg := fmt.Sprintf("%s: %s", user.Username, message)
-		s.ws.BroadcastMessage([]byte(msg), nil, room)
-		return nil
+// handleStockCommand requests a stock quote from the stock bot and posts its reply to the room.
+// Failed requests, including the ones the stock bot did not reply to in time, post an error instead.
+func (s *Chat) handleStockCommand(room jobsity.Room, stockCode string) {
+	key := room.Key()
+	stockCode = strings.ToUpper(stockCode)
+	reply, err := s.bot.Quote(stockCode)
+	if err == nil && reply.Error != "" {
+		// The stock bot's errors are meant for the room, like "APPL.US quote not found"
+		s.broadcast(key, jobsity.NewFrame(jobsity.FrameError, room.Name, jobsity.StockBotName, reply.Error), nil)
+		return
 	}
-	return fmt.Errorf("room %s not found", roomName)
+	var msg jobsity.Message
+	if err == nil {
+		msg, err = s.saveMessage(key, 0, &jobsity.AuthUser{Username: jobsity.StockBotName},
+			fmt.Sprintf("%s quote is $%.2f per share", stockCode, reply.Price), nil)
+	}
+	if err != nil {
+		s.broadcast(key, jobsity.NewFrame(jobsity.FrameError, room.Name, jobsity.StockBotName,
+			fmt.Sprintf("Could not get %s quote: %v", stockCode, err)), nil)
+		return
+	}
+	msg.Room = room.Name
+	s.broadcast(key, jobsity.MessageFrame(jobsity.FrameBot, msg), nil)
 }
 
-func (s *Chat) handleStockCommand(ws *websocket.Conn, roomName string, user *jobsity.AuthUser, stockCode string) error {
-	if room, ok := s.Rooms[roomName]; ok {
-		return s.handleStockCommandInRoom(ws, room, user, stockCode)
+// messageFrame creates the frame of a persisted message. Bot replies are not sent by any user.
+func messageFrame(msg jobsity.Message) jobsity.Frame {
+	switch {
+	case msg.UserID == 0:
+		return jobsity.MessageFrame(jobsity.FrameBot, msg)
+	case msg.ParentID != 0:
+		return jobsity.MessageFrame(jobsity.FrameReply, msg)
+	default:
+		return jobsity.MessageFrame(jobsity.FrameMessage, msg)
 	}
-	return nil
 }
 
-func (s *Chat) handleStockCommandInRoom(ws *websocket.Conn, room *jobsity.Room, user *jobsity.AuthUser, stockCode string) error {
-	// Call the stock API to get the stock quote
-	stockQuote, err := s.FetchStockQuote(stockCode)
-	if err != nil {
-		return fmt.Errorf("failed to fetch stock quote for %s: %v", stockCode, err)
+// saveMessage persists a message sent by user to the room with the key, replying to the message with parentID if any,
+// and mentioning the users with the usernames
+func (s *Chat) saveMessage(roomKey string, parentID int, user *jobsity.AuthUser, body string, mentions []string) (jobsity.Message, error) {
+	msg := jobsity.Message{Room: roomKey, ParentID: parentID, Body: body, Mentions: mentions}
+	if user != nil {
+		msg.UserID = user.ID
+		msg.Username = user.Username
 	}
-
-	// Send the stock quote to the room
-	msg := fmt.Sprintf("%s quote is $%.2f per share", stockCode, stockQuote)
-	//&jobsity.AuthUser{Username: "Bot"}
-	s.ws.BroadcastMessage([]byte(msg), ws, room)
-	return nil
+	return s.mdb.Create(s.db, msg)
 }
 
-func (s *Chat) sendMessageToRoom(roomName string, message string, sender *jobsity.AuthUser) {
-	if room, ok := s.Rooms[roomName]; ok {
-		if sender != nil {
-			fmt.Printf("[%s] %s: %s", roomName, sender.Username, message)
-		} else {
-			fmt.Printf("[%s] %s", roomName, message)
-		}
-		room.Broadcast <- []byte(message)
-	}
+// Stats returns outbound queue counters of the chat connections
+func (s *Chat) Stats(c echo.Context) websocket2.Stats {
+	return s.hub.Stats()
 }
 
-func (s *Chat) FetchStockQuote(stockCode string) (float64, error) {
-	// Fetch the stock data from the API
-	resp, err := http.Get(fmt.Sprintf("https://stooq.com/q/l/?s=%s&f=sd2t2ohlcv&h&e=csv", stockCode))
+// ListMessages returns a page of room's message history, ordered by timestamp. The history of
+// private rooms is only listed to their members.
+func (s *Chat) ListMessages(c echo.Context, roomName string, p jobsity.Pagination) ([]jobsity.Message, error) {
+	room, err := s.room(c, roomName)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Parse the CSV data
-	reader := csv.NewReader(resp.Body)
-	record, err := reader.Read()
-	if err != nil {
-		return 0, err
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return nil, err
 	}
-
-	// Extract the last closing price
-	price, err := strconv.ParseFloat(record[6], 64)
-	if err != nil {
-		return 0, err
+	msgs, err := s.mdb.List(s.db, room.Key(), p)
+	for i := range msgs {
+		msgs[i].Room = room.Name
 	}
-
-	return price, nil
+	return msgs, err
 }
@@ -0,0 +1,416 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+func receive(t *testing.T, conn *websocket.Conn, want ...string) {
+	for _, w := range want {
+		var got string
+		if err := websocket.Message.Receive(conn, &got); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, w, got)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	cases := []struct {
+		name     string
+		room     string
+		join     bool
+		body     string
+		wantErr  bool
+		wantData *jobsity.Message
+		wantRecv string
+		mdb      *mockdb.Message
+	}{
+		{
+			name:    "Fail on room not found",
+			room:    "random",
+			body:    "hello",
+			wantErr: true,
+		},
+		{
+			name:    "Fail on not joined room",
+			room:    "general",
+			body:    "hello",
+			wantErr: true,
+		},
+		{
+			name:    "Fail on empty message",
+			room:    "general",
+			join:    true,
+			body:    " \n\t",
+			wantErr: true,
+		},
+		{
+			name:    "Fail on persisting message",
+			room:    "general",
+			join:    true,
+			body:    "hello",
+			wantErr: true,
+			mdb: &mockdb.Message{
+				CreateFn: func(orm.DB, jobsity.Message) (jobsity.Message, error) {
+					return jobsity.Message{}, jobsity.ErrGeneric
+				},
+			},
+		},
+		{
+			name:     "Success",
+			room:     "general",
+			join:     true,
+			body:     "hello",
+			wantData: &jobsity.Message{Room: "general", UserID: 1, Username: "johndoe", Body: "hello"},
+			wantRecv: "johndoe: hello",
+			mdb:      &mockdb.Message{},
+		},
+	}
+	rbac := &mock.RBAC{
+		UserFn: func(echo.Context) jobsity.AuthUser {
+			return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: jobsity.UserRole}
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *jobsity.Message
+			if tt.mdb == nil {
+				tt.mdb = &mockdb.Message{}
+			}
+			tt.mdb.ListFn = func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+				return nil, nil
+			}
+			if tt.mdb.CreateFn == nil {
+				tt.mdb.CreateFn = func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+					saved = new(jobsity.Message)
+					*saved = msg
+					msg.ID = 1
+					msg.CreatedAt = mock.TestTime(2000)
+					return msg, nil
+				}
+			}
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: tt.mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+			if tt.join {
+				if err := s.JoinRoom(nil, conn, tt.room); err != nil {
+					t.Fatal(err)
+				}
+				receive(t, peer, "Welcome to the general chat room!")
+			}
+			err = s.SendMessage(nil, conn, tt.room, tt.body)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantData, saved)
+			if tt.wantRecv != "" {
+				receive(t, peer, tt.wantRecv)
+			}
+		})
+	}
+}
+
+func TestGetUsersInRoom(t *testing.T) {
+	users := map[*websocket.Conn]jobsity.AuthUser{}
+	rbac := &mock.RBAC{
+		UserFn: func(c echo.Context) jobsity.AuthUser {
+			return users[c.Get("conn").(*websocket.Conn)]
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return []jobsity.Message{{Username: "janedoe", Body: "earlier"}}, nil
+		},
+	}
+	s, err := chat.New([]string{"general", "random"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	john, johnPeer := mock.NewWSConn(t, ws.ProtocolText)
+	jane, janePeer := mock.NewWSConn(t, ws.ProtocolText)
+	users[john] = jobsity.AuthUser{ID: 1, Username: "johndoe"}
+	users[jane] = jobsity.AuthUser{ID: 2, Username: "janedoe"}
+	for _, conn := range []*websocket.Conn{john, jane} {
+		if err := s.JoinRoom(mock.EchoCtxWithKeys([]string{"conn"}, conn), conn, "general"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive(t, johnPeer, "janedoe: earlier", "Welcome to the general chat room!", "janedoe joined the room")
+	receive(t, janePeer, "janedoe: earlier", "Welcome to the general chat room!")
+
+	c := mock.EchoCtxWithKeys([]string{"conn"}, john)
+
+	_, err = s.GetUsersInRoom(c, "notexists")
+	assert.NotNil(t, err)
+
+	got, err := s.GetUsersInRoom(c, "general")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"janedoe", "johndoe"}, got)
+
+	got, err = s.GetUsersInRoom(c, "random")
+	assert.Nil(t, err)
+	assert.Empty(t, got)
+
+	assert.NotNil(t, s.JoinRoom(c, john, "general"))
+	assert.NotNil(t, s.LeaveRoom(nil, john, "random"))
+	assert.Nil(t, s.Disconnect(nil, john))
+	receive(t, janePeer, "johndoe left the room")
+
+	got, err = s.GetUsersInRoom(c, "general")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"janedoe"}, got)
+}
+
+func TestHandleFrame(t *testing.T) {
+	rbac := &mock.RBAC{
+		UserFn: func(echo.Context) jobsity.AuthUser {
+			return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: jobsity.UserRole}
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			msg.ID = 1
+			msg.CreatedAt = mock.TestTime(2000)
+			return msg, nil
+		},
+	}
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, peer := mock.NewWSConn(t)
+
+	next := func() jobsity.Frame {
+		var f jobsity.Frame
+		if err := websocket.JSON.Receive(peer, &f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	cases := []struct {
+		name     string
+		data     string
+		wantErr  bool
+		wantType string
+		wantRoom string
+		wantRecv jobsity.Frame
+	}{
+		{
+			name:    "Fail on invalid frame",
+			data:    `hello`,
+			wantErr: true,
+			wantRecv: jobsity.Frame{Version: 1, Type: jobsity.FrameError, Room: "general",
+				Payload: jobsity.Payload{Text: "invalid character 'h' looking for beginning of value"}},
+		},
+		{
+			name:     "Fail on unsupported type",
+			data:     `{"version":1,"type":"dance"}`,
+			wantErr:  true,
+			wantType: "dance",
+			wantRoom: "general",
+			wantRecv: jobsity.Frame{Version: 1, Type: jobsity.FrameError, Room: "general",
+				Payload: jobsity.Payload{Text: "unsupported frame type: dance"}},
+		},
+		{
+			name:     "Fail on not joined room",
+			data:     `{"version":1,"type":"message","payload":{"text":"hello"}}`,
+			wantErr:  true,
+			wantType: jobsity.FrameMessage,
+			wantRoom: "general",
+			wantRecv: jobsity.Frame{Version: 1, Type: jobsity.FrameError, Room: "general",
+				Payload: jobsity.Payload{Text: "not in room"}},
+		},
+		{
+			name:     "Success on join",
+			data:     `{"version":1,"type":"join","room":"general"}`,
+			wantType: jobsity.FrameJoin,
+			wantRoom: "general",
+			wantRecv: jobsity.Frame{Version: 1, Type: jobsity.FrameSystem, Room: "general",
+				Payload: jobsity.Payload{Text: "Welcome to the general chat room!"}},
+		},
+		{
+			name:     "Success on message",
+			data:     `{"version":1,"type":"message","payload":{"text":"hello"}}`,
+			wantType: jobsity.FrameMessage,
+			wantRoom: "general",
+			wantRecv: jobsity.Frame{Version: 1, Type: jobsity.FrameMessage, Room: "general", ID: 1,
+				Sender: "johndoe", Timestamp: mock.TestTime(2000), Payload: jobsity.Payload{Text: "hello"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := s.HandleFrame(nil, conn, "general", []byte(tt.data))
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantType, f.Type)
+			assert.Equal(t, tt.wantRoom, f.Room)
+			got := next()
+			if tt.wantRecv.Type != jobsity.FrameMessage {
+				got.Timestamp = time.Time{}
+			}
+			assert.Equal(t, tt.wantRecv, got)
+		})
+	}
+}
+
+func TestStockCommand(t *testing.T) {
+	cases := []struct {
+		name     string
+		command  string
+		wantRecv string
+	}{
+		{
+			name:     "Fail on timeout",
+			command:  "/stock=msft.us",
+			wantRecv: "Could not get MSFT.US quote: no reply received in time",
+		},
+		{
+			name:     "Fail on bot error",
+			command:  "/stock=appl.us",
+			wantRecv: "APPL.US quote not found",
+		},
+		{
+			name:     "Success",
+			command:  "/stock=aapl.us",
+			wantRecv: "StockBot: AAPL.US quote is $93.42 per share",
+		},
+	}
+	rbac := &mock.RBAC{
+		UserFn: func(echo.Context) jobsity.AuthUser {
+			return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: jobsity.UserRole}
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			return msg, nil
+		},
+	}
+	bot := mock.StockBot{
+		QuoteFn: func(code string) (jobsity.StockReply, error) {
+			switch code {
+			case "AAPL.US":
+				return jobsity.StockReply{Code: code, Price: 93.42}, nil
+			case "APPL.US":
+				return jobsity.StockReply{Code: code, Error: "APPL.US quote not found"}, nil
+			default:
+				return jobsity.StockReply{}, broker.ErrTimeout
+			}
+		},
+	}
+	rdb := mockdb.NewRoom()
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb}), broker.NewMemory(), bot, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+	if err := s.JoinRoom(nil, conn, "general"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, peer, "Welcome to the general chat room!")
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Nil(t, s.HandleCommand(nil, conn, "general", tt.command))
+			receive(t, peer, tt.wantRecv)
+		})
+	}
+	// Quotes are only posted to the active rooms joined
+	assert.Equal(t, chat.ErrNotInRoom, s.HandleCommand(nil, conn, "random", "/stock aapl.us"))
+	room, err := rdb.View(nil, "general", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.Archived = true
+	assert.Nil(t, rdb.Update(nil, room))
+	assert.Equal(t, chat.ErrRoomArchived, s.HandleCommand(nil, conn, "general", "/stock aapl.us"))
+}
+
+func TestListMessages(t *testing.T) {
+	cases := []struct {
+		name     string
+		room     string
+		pgn      jobsity.Pagination
+		wantErr  bool
+		wantData []jobsity.Message
+		mdb      *mockdb.Message
+	}{
+		{
+			name:    "Fail on room not found",
+			room:    "lobby",
+			pgn:     jobsity.Pagination{Limit: 100},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on private room",
+			room:    "hr",
+			pgn:     jobsity.Pagination{Limit: 100},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on query",
+			room:    "general",
+			pgn:     jobsity.Pagination{Limit: 100},
+			wantErr: true,
+			mdb: &mockdb.Message{
+				ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+					return nil, jobsity.ErrGeneric
+				},
+			},
+		},
+		{
+			name: "Success",
+			room: "general",
+			pgn:  jobsity.Pagination{Limit: 100, Offset: 100},
+			wantData: []jobsity.Message{
+				{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "hi"},
+				{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "hello"},
+			},
+			mdb: &mockdb.Message{
+				ListFn: func(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+					if room != "general" || p.Limit != 100 || p.Offset != 100 {
+						return nil, jobsity.ErrGeneric
+					}
+					return []jobsity.Message{
+						{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "hi"},
+						{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "hello"},
+					}, nil
+				},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb := mockdb.NewRoom()
+			if _, err := rdb.Create(nil, jobsity.Room{Name: "hr", Private: true}); err != nil {
+				t.Fatal(err)
+			}
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: tt.mdb, Rooms: rdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs, err := s.ListMessages(nil, tt.room, tt.pgn)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantData, msgs)
+		})
+	}
+}
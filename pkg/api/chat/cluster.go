@@ -0,0 +1,209 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"my-chat-jobsity-challenge"
+	websocket2 "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+)
+
+// Broker topics shared by the chat instances. Rooms being opened are announced on
+// roomsTopic, while every other room event goes to the room's own topic. Events
+// addressed to users, like direct messages, and presence events go to usersTopic.
+const (
+	roomsTopic      = "chat.rooms"
+	roomTopicPrefix = "chat.room."
+	usersTopic      = "chat.users"
+)
+
+// dedupSize is the number of latest event IDs remembered to discard redelivered events
+const dedupSize = 4096
+
+// Room event kinds
+const (
+	eventFrame = "frame"
+	eventOpen  = "open"
+	eventClose = "close"
+	eventEvict = "evict"
+	eventUser  = "user"
+	// eventPresence carries a user's presence on the origin instance, and eventSync asks
+	// the other instances for the presence of their users
+	eventPresence = "presence"
+	eventSync     = "sync"
+	// eventHeartbeat tells the other instances the origin instance is alive
+	eventHeartbeat = "heartbeat"
+)
+
+// event represents a room or user event fanned out to the other chat instances through the broker
+type event struct {
+	ID     string         `json:"id"`
+	Origin string         `json:"origin"`
+	Kind   string         `json:"kind"`
+	Room   string         `json:"room"`
+	Frame  *jobsity.Frame `json:"frame,omitempty"`
+	UserID int            `json:"user_id,omitempty"`
+	Notice string         `json:"notice,omitempty"`
+
+	Presence *presenceState `json:"presence,omitempty"`
+}
+
+// dedup remembers a bounded number of event IDs
+type dedup struct {
+	mu   sync.Mutex
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+func newDedup(size int) *dedup {
+	return &dedup{
+		ids:  make(map[string]struct{}, size),
+		ring: make([]string, size),
+	}
+}
+
+// add remembers the ID, evicting the oldest one. It returns false if the ID was already seen.
+func (d *dedup) add(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.ids[id]; ok {
+		return false
+	}
+	delete(d.ids, d.ring[d.next])
+	d.ring[d.next] = id
+	d.ids[id] = struct{}{}
+	d.next = (d.next + 1) % len(d.ring)
+	return true
+}
+
+func newInstanceID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// openRoom starts a room and subscribes to its events. Rooms opened by this instance are announced to the others.
+func (s *Chat) openRoom(roomName string, announce bool) error {
+	if err := s.hub.Open(roomName); err != nil {
+		return err
+	}
+	sub, err := s.broker.Subscribe(roomTopicPrefix+roomName, s.handleEvent)
+	if err != nil {
+		s.hub.Close(roomName)
+		return err
+	}
+	s.subsMu.Lock()
+	s.subs[roomName] = sub
+	s.subsMu.Unlock()
+
+	if !announce {
+		return nil
+	}
+	return s.publish(roomsTopic, event{Kind: eventOpen, Room: roomName})
+}
+
+// closeRoom stops a room and unsubscribes from its events. Rooms closed by this instance are announced to the others.
+func (s *Chat) closeRoom(roomName string, announce bool) error {
+	s.subsMu.Lock()
+	sub, ok := s.subs[roomName]
+	delete(s.subs, roomName)
+	s.subsMu.Unlock()
+	if ok {
+		sub.Unsubscribe()
+	}
+	if err := s.hub.Close(roomName); err != nil {
+		return err
+	}
+
+	if !announce {
+		return nil
+	}
+	return s.publish(roomTopicPrefix+roomName, event{Kind: eventClose, Room: roomName})
+}
+
+// broadcast sends the frame to the room's clients connected to this instance,
+// except the sender if provided, and to the clients connected to the others
+func (s *Chat) broadcast(roomName string, f jobsity.Frame, except *websocket2.Client) error {
+	if err := s.hub.Broadcast(roomName, f, except); err != nil {
+		return err
+	}
+	return s.publish(roomTopicPrefix+roomName, event{Kind: eventFrame, Room: roomName, Frame: &f})
+}
+
+// sendToUser sends the frame to every client of the user, connected to this instance or to the others
+func (s *Chat) sendToUser(userID int, f jobsity.Frame) error {
+	s.sendLocal(userID, f)
+	return s.publish(usersTopic, event{Kind: eventUser, UserID: userID, Frame: &f})
+}
+
+// sendLocal sends the frame to the user's clients connected to this instance
+func (s *Chat) sendLocal(userID int, f jobsity.Frame) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cl := range s.sessions {
+		if cl.User.ID == userID {
+			cl.Send(f)
+		}
+	}
+}
+
+func (s *Chat) publish(topic string, e event) error {
+	e.Origin = s.instance
+	e.ID = fmt.Sprintf("%s-%d", s.instance, atomic.AddUint64(&s.seq, 1))
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.broker.Publish(topic, body)
+}
+
+// handleEvent applies an event published by another instance. Events published by this
+// instance were already applied, and events delivered more than once are discarded.
+func (s *Chat) handleEvent(body []byte) {
+	var e event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return
+	}
+	if e.Origin == s.instance || !s.seen.add(e.ID) {
+		return
+	}
+
+	switch e.Kind {
+	case eventFrame:
+		if e.Frame != nil {
+			// The room might have been closed meanwhile
+			s.hub.Broadcast(e.Room, *e.Frame, nil)
+		}
+	case eventOpen:
+		// Subscriptions are not changed from within a broker handler
+		go s.openRoom(e.Room, false)
+	case eventClose:
+		go s.closeRoom(e.Room, false)
+	case eventEvict:
+		go s.evict(e.Room, e.UserID, e.Notice)
+	case eventUser:
+		if e.Frame != nil {
+			s.sendLocal(e.UserID, *e.Frame)
+		}
+	case eventPresence:
+		s.presence.hear(e.Origin, time.Now())
+		if e.Presence != nil {
+			s.presence.set(e.Origin, *e.Presence)
+		}
+	case eventSync:
+		go s.syncPresence()
+	case eventHeartbeat:
+		// Instances heard from again after expiring, or the first time, are asked for the presence of their users
+		if s.presence.hear(e.Origin, time.Now()) {
+			go s.publish(usersTopic, event{Kind: eventSync})
+		}
+	}
+}
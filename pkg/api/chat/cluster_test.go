@@ -0,0 +1,212 @@
+package chat_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveUnordered receives the messages in any order, since messages published by
+// different instances are not ordered. Join notices are skipped, as remote ones race with local joins.
+func receiveUnordered(t *testing.T, conn *websocket.Conn, want ...string) {
+	var got []string
+	for len(got) < len(want) {
+		var msg string
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(msg, " joined the room") {
+			got = append(got, msg)
+		}
+	}
+	assert.ElementsMatch(t, want, got)
+}
+
+func TestCluster(t *testing.T) {
+	users := map[*websocket.Conn]jobsity.AuthUser{}
+	rbac := &mock.RBAC{
+		UserFn: func(c echo.Context) jobsity.AuthUser {
+			return users[c.Get("conn").(*websocket.Conn)]
+		},
+		EnforceRoleFn: func(c echo.Context, role jobsity.AccessRole) error {
+			if users[c.Get("conn").(*websocket.Conn)].Role > role {
+				return echo.ErrForbidden
+			}
+			return nil
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			return msg, nil
+		},
+	}
+
+	// Two chat instances sharing a broker and a database, each with its own hub
+	rdb := mockdb.NewRoom()
+	rmdb := mockdb.NewMember()
+	udb := mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe"}, jobsity.User{Base: jobsity.Base{ID: 2}, Username: "janedoe"})
+	b := broker.NewMemory()
+	defer b.Close()
+	first, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: udb}), b, nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: udb}), b, nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	john, johnPeer := mock.NewWSConn(t, ws.ProtocolText)
+	jane, janePeer := mock.NewWSConn(t, ws.ProtocolText)
+	users[john] = jobsity.AuthUser{ID: 1, Username: "johndoe"}
+	users[jane] = jobsity.AuthUser{ID: 2, Username: "janedoe"}
+	johnCtx := mock.EchoCtxWithKeys([]string{"conn"}, john)
+	janeCtx := mock.EchoCtxWithKeys([]string{"conn"}, jane)
+
+	assert.Nil(t, first.JoinRoom(johnCtx, john, "general"))
+	receive(t, johnPeer, "Welcome to the general chat room!")
+	assert.Nil(t, second.JoinRoom(janeCtx, jane, "general"))
+	receive(t, janePeer, "Welcome to the general chat room!")
+
+	// Every client receives each message once, whichever instance it is connected to
+	assert.Nil(t, second.SendMessage(janeCtx, jane, "general", "hello"))
+	assert.Nil(t, first.SendMessage(johnCtx, john, "general", "hi"))
+	receiveUnordered(t, johnPeer, "janedoe: hello", "johndoe: hi")
+	receiveUnordered(t, janePeer, "janedoe: hello", "johndoe: hi")
+
+	// Redelivered events are discarded
+	redelivered := `{"id":"third-1","origin":"third","kind":"frame","room":"general",` +
+		`"frame":{"version":1,"type":"message","room":"general","sender":"bob","payload":{"text":"once"}}}`
+	assert.Nil(t, b.Publish("chat.room.general", []byte(redelivered)))
+	assert.Nil(t, b.Publish("chat.room.general", []byte(redelivered)))
+	assert.Nil(t, first.SendMessage(johnCtx, john, "general", "bye"))
+	receiveUnordered(t, johnPeer, "bob: once", "johndoe: bye")
+	receiveUnordered(t, janePeer, "bob: once", "johndoe: bye")
+
+	// Direct messages reach the recipient on any instance
+	_, err = second.SendDirect(janeCtx, "johndoe", "psst")
+	assert.Nil(t, err)
+	receiveUnordered(t, johnPeer, "janedoe to johndoe: psst")
+	receiveUnordered(t, janePeer, "janedoe to johndoe: psst")
+
+	// Rooms created and deleted on one instance are opened and closed on the others
+	admin, _ := mock.NewWSConn(t)
+	users[admin] = jobsity.AuthUser{ID: 3, Username: "admin", Role: jobsity.AdminRole}
+	adminCtx := mock.EchoCtxWithKeys([]string{"conn"}, admin)
+
+	// Presence is shared by the instances, including the ones started later
+	third, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: udb}), b, nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	present := func(s *chat.Chat, want ...string) func() bool {
+		return func() bool {
+			got, err := s.GetUsersInRoom(adminCtx, "general")
+			return err == nil && assert.ObjectsAreEqual(want, got)
+		}
+	}
+	for _, s := range []*chat.Chat{first, second, third} {
+		assert.Eventually(t, present(s, "janedoe", "johndoe"), time.Second, 10*time.Millisecond)
+	}
+	roomExists := func(s *chat.Chat) func() bool {
+		return func() bool {
+			_, err := s.GetUsersInRoom(adminCtx, "random")
+			return err == nil
+		}
+	}
+	roomMissing := func(s *chat.Chat) func() bool {
+		exists := roomExists(s)
+		return func() bool {
+			return !exists()
+		}
+	}
+	_, err = first.CreateRoom(adminCtx, jobsity.Room{Name: "random"})
+	assert.Nil(t, err)
+	assert.Eventually(t, roomExists(second), time.Second, 10*time.Millisecond)
+	assert.Nil(t, first.JoinRoom(johnCtx, john, "random"))
+	receiveUnordered(t, johnPeer, "Welcome to the random chat room!")
+	assert.Nil(t, second.JoinRoom(janeCtx, jane, "random"))
+	receiveUnordered(t, janePeer, "Welcome to the random chat room!")
+
+	assert.Nil(t, first.DeleteRoom(adminCtx, "random"))
+	receiveUnordered(t, johnPeer, "Room random was deleted")
+	receiveUnordered(t, janePeer, "Room random was deleted")
+	assert.Eventually(t, roomMissing(second), time.Second, 10*time.Millisecond)
+
+	// Users going offline on one instance are gone from the others
+	assert.Nil(t, first.Disconnect(johnCtx, john))
+	receiveUnordered(t, janePeer, "johndoe left the room")
+	assert.Eventually(t, present(third, "janedoe"), time.Second, 10*time.Millisecond)
+}
+
+func TestClusterHeartbeat(t *testing.T) {
+	users := map[*websocket.Conn]jobsity.AuthUser{}
+	rbac := &mock.RBAC{
+		UserFn: func(c echo.Context) jobsity.AuthUser {
+			return users[c.Get("conn").(*websocket.Conn)]
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+	}
+	rdb := mockdb.NewRoom()
+	rmdb := mockdb.NewMember()
+	udb := mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe"}, jobsity.User{Base: jobsity.Base{ID: 2}, Username: "janedoe"})
+	b := broker.NewMemory()
+	defer b.Close()
+	first, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: udb}), b, nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: udb}), b, nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.SetHeartbeat(10 * time.Millisecond)
+	defer first.SetHeartbeat(0)
+	second.SetHeartbeat(10 * time.Millisecond)
+
+	john, johnPeer := mock.NewWSConn(t, ws.ProtocolText)
+	jane, _ := mock.NewWSConn(t, ws.ProtocolText)
+	users[john] = jobsity.AuthUser{ID: 1, Username: "johndoe"}
+	users[jane] = jobsity.AuthUser{ID: 2, Username: "janedoe"}
+	johnCtx := mock.EchoCtxWithKeys([]string{"conn"}, john)
+	janeCtx := mock.EchoCtxWithKeys([]string{"conn"}, jane)
+
+	assert.Nil(t, first.JoinRoom(johnCtx, john, "general"))
+	receive(t, johnPeer, "Welcome to the general chat room!")
+	assert.Nil(t, second.JoinRoom(janeCtx, jane, "general"))
+	present := func(want ...string) func() bool {
+		return func() bool {
+			got, err := first.GetUsersInRoom(johnCtx, "general")
+			return err == nil && assert.ObjectsAreEqual(want, got)
+		}
+	}
+	assert.Eventually(t, present("janedoe", "johndoe"), time.Second, 10*time.Millisecond)
+
+	// Users of instances still beating stay present
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, present("janedoe", "johndoe")())
+
+	// Users of instances no longer beating, like crashed ones, are gone from the others
+	second.SetHeartbeat(0)
+	assert.Eventually(t, present("johndoe"), time.Second, 10*time.Millisecond)
+	receiveUnordered(t, johnPeer, "janedoe left the room")
+}
@@ -0,0 +1,376 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	websocket2 "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+)
+
+// Custom errors
+var (
+	ErrEmptyCommand  = echo.NewHTTPError(http.StatusBadRequest, "empty command")
+	ErrUnclosedQuote = echo.NewHTTPError(http.StatusBadRequest, "unclosed quote in command")
+)
+
+// RegisterCommand adds a slash command to the chat. Command names must be unique.
+func (s *Chat) RegisterCommand(cmd Command) error {
+	return s.commands.register(cmd)
+}
+
+// HandleCommand executes a slash command sent through the connection to a room. Unknown
+// commands, commands the user is not allowed to run and invalid arguments are rejected.
+func (s *Chat) HandleCommand(c echo.Context, conn *websocket.Conn, roomName string, message string) error {
+	name, rest, single, err := parseCommand(message)
+	if err != nil {
+		return err
+	}
+	cmd, ok := s.commands.lookup(name)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("unknown command /%s, type /help for the list of commands", name))
+	}
+	if !canRun(s.session(c, conn).User, cmd) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("not allowed to run /%s", name))
+	}
+	args, err := cmd.bind(rest, single)
+	if err != nil {
+		return err
+	}
+	return cmd.Handler(c, conn, roomName, args)
+}
+
+// canRun reports whether the user's role is allowed to run the command. Lower roles are more privileged.
+func canRun(user jobsity.AuthUser, cmd *Command) bool {
+	return user.Role <= cmd.Role
+}
+
+// registerBuiltins adds the commands every chat supports
+func (s *Chat) registerBuiltins() error {
+	builtins := []Command{
+		{
+			Name:        "join",
+			Args:        "<room>",
+			Description: "Joins a room",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				return s.JoinRoom(c, conn, args[0])
+			},
+		},
+		{
+			Name:        "leave",
+			Args:        "[room]",
+			Description: "Leaves a room, the current one by default",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				if len(args) > 0 {
+					roomName = args[0]
+				}
+				return s.LeaveRoom(c, conn, roomName)
+			},
+		},
+		{
+			Name:        "users",
+			Description: "Lists the users in the current room",
+			Role:        jobsity.UserRole,
+			Handler:     s.usersCommand,
+		},
+		{
+			Name:        "stock",
+			Args:        "<stock_code>",
+			Description: "Posts a stock quote to the current room, also typed as /stock=<stock_code>",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				room, err := s.postableRoom(c, conn, roomName)
+				if err != nil {
+					return err
+				}
+				if err := s.throttle(c, room); err != nil {
+					return err
+				}
+				// The stock bot replies to the room asynchronously
+				go s.handleStockCommand(room, args[0])
+				return nil
+			},
+		},
+		{
+			Name:        "create",
+			Args:        "<room>",
+			Description: "Creates a new room",
+			Role:        jobsity.CompanyAdminRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				room, err := s.CreateRoom(c, jobsity.Room{Name: args[0]})
+				if err != nil {
+					return err
+				}
+				s.session(c, conn).Send(jobsity.NewFrame(jobsity.FrameSystem, roomName, "",
+					fmt.Sprintf("Room %s was created", room.Name)))
+				return nil
+			},
+		},
+		{
+			Name:        "msg",
+			Args:        "<user> <message...>",
+			Description: "Sends a direct message to a user",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				_, err := s.SendDirect(c, args[0], args[1])
+				return err
+			},
+		},
+		{
+			Name:        "away",
+			Description: "Marks you away until you are back",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				return s.SetAway(c, conn, true)
+			},
+		},
+		{
+			Name:        "back",
+			Description: "Marks you back",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				return s.SetAway(c, conn, false)
+			},
+		},
+		{
+			Name:        "help",
+			Description: "Lists the available commands",
+			Role:        jobsity.UserRole,
+			Handler:     s.helpCommand,
+		},
+	}
+	builtins = append(builtins, s.moderationCommands()...)
+	for _, cmd := range builtins {
+		if err := s.RegisterCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postableRoom returns a room the connection joined, for the current user to post to it. The room must
+// still be active and accessible to the user, who must be neither banned nor muted in it.
+func (s *Chat) postableRoom(c echo.Context, conn *websocket.Conn, roomName string) (jobsity.Room, error) {
+	cl := s.session(c, conn)
+	joined, ok := cl.joinedRoom(roomName)
+	if !ok {
+		return jobsity.Room{}, ErrNotInRoom
+	}
+	// The room's settings may have changed since it was joined
+	room, err := s.rdb.View(s.db, joined.Name, joined.CompanyID)
+	if err == pgsql.ErrRoomNotFound {
+		return jobsity.Room{}, websocket2.ErrRoomNotFound
+	}
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	if room.Archived {
+		return jobsity.Room{}, ErrRoomArchived
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return jobsity.Room{}, err
+	}
+	for _, kind := range []jobsity.SanctionKind{jobsity.SanctionBan, jobsity.SanctionMute} {
+		if err := s.enforceNotSanctioned(room, cl.User.ID, kind); err != nil {
+			return jobsity.Room{}, err
+		}
+	}
+	return room, nil
+}
+
+func (s *Chat) usersCommand(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+	users, err := s.GetUsersInRoom(c, roomName)
+	if err != nil {
+		return err
+	}
+	msg := "There are no users in this room."
+	if len(users) > 0 {
+		msg = "Users in this room: " + strings.Join(users, ", ")
+	}
+	s.session(c, conn).Send(jobsity.NewFrame(jobsity.FrameSystem, roomName, "", msg))
+	return nil
+}
+
+// helpCommand lists the commands the user is allowed to run
+func (s *Chat) helpCommand(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+	cl := s.session(c, conn)
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, cmd := range s.commands.list() {
+		if canRun(cl.User, cmd) {
+			fmt.Fprintf(&b, "\n%s - %s", cmd.Usage(), cmd.Description)
+		}
+	}
+	cl.Send(jobsity.NewFrame(jobsity.FrameSystem, roomName, "", b.String()))
+	return nil
+}
+
+// CommandHandler executes a command sent through the connection to a room, with the parsed arguments
+type CommandHandler func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error
+
+// Command represents a chat slash command
+type Command struct {
+	// Name is typed after the slash, as in /join
+	Name string
+	// Args is the argument syntax: <arg> is required, [arg] is optional,
+	// and a trailing ... takes the rest of the line, as in <message...>
+	Args        string
+	Description string
+	// Role is the minimum access role allowed to run the command
+	Role    jobsity.AccessRole
+	Handler CommandHandler
+
+	required int
+	max      int
+	variadic bool
+}
+
+// Usage returns the command's syntax
+func (cmd *Command) Usage() string {
+	if cmd.Args == "" {
+		return "/" + cmd.Name
+	}
+	return "/" + cmd.Name + " " + cmd.Args
+}
+
+// bind splits the arguments and validates their number against the syntax. A variadic argument takes
+// the rest of the line as typed.
+func (cmd *Command) bind(rest string, single bool) ([]string, error) {
+	args := []string{rest}
+	if !single {
+		n := -1
+		if cmd.variadic {
+			n = cmd.max
+		}
+		var err error
+		if args, err = splitArgs(rest, n); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) < cmd.required || len(args) > cmd.max {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "usage: "+cmd.Usage())
+	}
+	return args, nil
+}
+
+// commands is a registry of slash commands
+type commands struct {
+	mu   sync.RWMutex
+	cmds map[string]*Command
+}
+
+func newCommands() *commands {
+	return &commands{cmds: make(map[string]*Command)}
+}
+
+func (r *commands) register(cmd Command) error {
+	if cmd.Name == "" || strings.ContainsAny(cmd.Name, " =/") {
+		return fmt.Errorf("invalid command name %q", cmd.Name)
+	}
+	if cmd.Handler == nil {
+		return fmt.Errorf("command /%s has no handler", cmd.Name)
+	}
+	for i, arg := range strings.Fields(cmd.Args) {
+		if cmd.variadic {
+			return fmt.Errorf("command /%s has arguments after a variadic one", cmd.Name)
+		}
+		switch {
+		case strings.HasPrefix(arg, "<") && strings.HasSuffix(arg, ">"):
+			if cmd.required < i {
+				return fmt.Errorf("command /%s has required arguments after optional ones", cmd.Name)
+			}
+			cmd.required++
+		case strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]"):
+		default:
+			return fmt.Errorf("command /%s has invalid argument syntax %q", cmd.Name, arg)
+		}
+		cmd.variadic = strings.HasSuffix(arg[:len(arg)-1], "...")
+		cmd.max++
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cmds[cmd.Name]; ok {
+		return fmt.Errorf("command /%s is already registered", cmd.Name)
+	}
+	r.cmds[cmd.Name] = &cmd
+	return nil
+}
+
+func (r *commands) lookup(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.cmds[name]
+	return cmd, ok
+}
+
+// list returns the commands ordered by name
+func (r *commands) list() []*Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*Command, 0, len(r.cmds))
+	for _, cmd := range r.cmds {
+		list = append(list, cmd)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// parseCommand splits a slash command into its name and the rest of the line holding its arguments.
+// A single argument may also follow the name after an equal sign, as in /stock=aapl.us.
+func parseCommand(text string) (name, rest string, single bool, err error) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "/"))
+	name = text
+	if i := strings.IndexAny(text, " ="); i >= 0 {
+		name, rest, single = text[:i], text[i+1:], text[i] == '='
+	}
+	if name == "" {
+		return "", "", false, ErrEmptyCommand
+	}
+	return name, rest, single, nil
+}
+
+// splitArgs splits arguments separated by spaces, unless double quoted. Once n-1 arguments
+// are read, the rest of the text is the last one, as typed. A negative n splits all arguments.
+func splitArgs(text string, n int) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	quoted, started := false, false
+	for i, r := range text {
+		switch {
+		case !started && r != ' ' && len(args) == n-1:
+			return append(args, text[i:]), nil
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case r == ' ' && !quoted:
+			if started {
+				args = append(args, arg.String())
+				arg.Reset()
+				started = false
+			}
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, ErrUnclosedQuote
+	}
+	if started {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,215 @@
+package chat_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+func TestHandleCommand(t *testing.T) {
+	cases := []struct {
+		name     string
+		role     jobsity.AccessRole
+		command  string
+		wantErr  error
+		wantRecv string
+	}{
+		{
+			name:    "Fail on empty command",
+			role:    jobsity.UserRole,
+			command: "/",
+			wantErr: chat.ErrEmptyCommand,
+		},
+		{
+			name:    "Fail on unknown command",
+			role:    jobsity.UserRole,
+			command: "/dance now",
+			wantErr: echo.NewHTTPError(http.StatusBadRequest, "unknown command /dance, type /help for the list of commands"),
+		},
+		{
+			name:    "Fail on role",
+			role:    jobsity.UserRole,
+			command: "/create lobby",
+			wantErr: echo.NewHTTPError(http.StatusForbidden, "not allowed to run /create"),
+		},
+		{
+			name:    "Fail on missing argument",
+			role:    jobsity.UserRole,
+			command: "/join",
+			wantErr: echo.NewHTTPError(http.StatusBadRequest, "usage: /join <room>"),
+		},
+		{
+			name:    "Fail on extra argument",
+			role:    jobsity.UserRole,
+			command: "/users general random",
+			wantErr: echo.NewHTTPError(http.StatusBadRequest, "usage: /users"),
+		},
+		{
+			name:    "Fail on unclosed quote",
+			role:    jobsity.UserRole,
+			command: `/join "hr`,
+			wantErr: chat.ErrUnclosedQuote,
+		},
+		{
+			name:     "Success on users",
+			role:     jobsity.UserRole,
+			command:  "/users",
+			wantRecv: "Users in this room: johndoe",
+		},
+		{
+			name:     "Success on variadic arguments",
+			role:     jobsity.UserRole,
+			command:  `/shout hello  "big world`,
+			wantRecv: `johndoe: HELLO  "BIG WORLD`,
+		},
+		{
+			name:    "Success on help",
+			role:    jobsity.UserRole,
+			command: "/help",
+			wantRecv: "Available commands:" +
+				"\n/away - Marks you away until you are back" +
+				"\n/back - Marks you back" +
+				"\n/ban <user> [reason...] - Bans a user from the current room for good, or for a duration like 2h put before the reason" +
+				"\n/help - Lists the available commands" +
+				"\n/join <room> - Joins a room" +
+				"\n/kick <user> [reason...] - Kicks a user from the current room" +
+				"\n/leave [room] - Leaves a room, the current one by default" +
+				"\n/msg <user> <message...> - Sends a direct message to a user" +
+				"\n/mute <user> <duration> [reason...] - Mutes a user in the current room for a duration like 10m" +
+				"\n/shout <text...> - Shouts to the room" +
+				"\n/stock <stock_code> - Posts a stock quote to the current room, also typed as /stock=<stock_code>" +
+				"\n/unban <user> - Lifts the ban of a user from the current room" +
+				"\n/users - Lists the users in the current room",
+		},
+		{
+			name:    "Success on admin help",
+			role:    jobsity.SuperAdminRole,
+			command: "/help",
+			wantRecv: "Available commands:" +
+				"\n/away - Marks you away until you are back" +
+				"\n/back - Marks you back" +
+				"\n/ban <user> [reason...] - Bans a user from the current room for good, or for a duration like 2h put before the reason" +
+				"\n/create <room> - Creates a new room" +
+				"\n/help - Lists the available commands" +
+				"\n/join <room> - Joins a room" +
+				"\n/kick <user> [reason...] - Kicks a user from the current room" +
+				"\n/leave [room] - Leaves a room, the current one by default" +
+				"\n/msg <user> <message...> - Sends a direct message to a user" +
+				"\n/mute <user> <duration> [reason...] - Mutes a user in the current room for a duration like 10m" +
+				"\n/shout <text...> - Shouts to the room" +
+				"\n/stock <stock_code> - Posts a stock quote to the current room, also typed as /stock=<stock_code>" +
+				"\n/unban <user> - Lifts the ban of a user from the current room" +
+				"\n/users - Lists the users in the current room",
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			return msg, nil
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rbac := &mock.RBAC{
+				UserFn: func(echo.Context) jobsity.AuthUser {
+					return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: tt.role}
+				},
+			}
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.RegisterCommand(chat.Command{
+				Name:        "shout",
+				Args:        "<text...>",
+				Description: "Shouts to the room",
+				Role:        jobsity.UserRole,
+				Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+					assert.Len(t, args, 1)
+					return s.SendMessage(c, conn, roomName, strings.ToUpper(args[0]))
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+			if err := s.JoinRoom(nil, conn, "general"); err != nil {
+				t.Fatal(err)
+			}
+			receive(t, peer, "Welcome to the general chat room!")
+
+			err = s.HandleCommand(nil, conn, "general", tt.command)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantRecv != "" {
+				receive(t, peer, tt.wantRecv)
+			}
+		})
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	handler := func(echo.Context, *websocket.Conn, string, []string) error { return nil }
+	cases := []struct {
+		name    string
+		cmd     chat.Command
+		wantErr bool
+	}{
+		{
+			name:    "Fail on invalid name",
+			cmd:     chat.Command{Name: "stock=", Handler: handler},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on missing handler",
+			cmd:     chat.Command{Name: "dance"},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on duplicated name",
+			cmd:     chat.Command{Name: "join", Args: "<room>", Handler: handler},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on invalid syntax",
+			cmd:     chat.Command{Name: "dance", Args: "style", Handler: handler},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on required after optional argument",
+			cmd:     chat.Command{Name: "dance", Args: "[style] <partner>", Handler: handler},
+			wantErr: true,
+		},
+		{
+			name:    "Fail on argument after variadic one",
+			cmd:     chat.Command{Name: "dance", Args: "<moves...> [style]", Handler: handler},
+			wantErr: true,
+		},
+		{
+			name: "Success",
+			cmd:  chat.Command{Name: "dance", Args: "<partner> [style] [moves...]", Handler: handler},
+		},
+	}
+	s, err := chat.New(nil, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.RegisterCommand(tt.cmd)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Custom errors
+var (
+	ErrEmptyMessage = echo.NewHTTPError(http.StatusBadRequest, "message cannot be empty")
+	ErrSelfMessage  = echo.NewHTTPError(http.StatusBadRequest, "cannot send a direct message to yourself")
+	ErrOtherCompany = echo.NewHTTPError(http.StatusForbidden, "user is not in your company")
+)
+
+// SendDirect sends a direct message of the current user to another user of the company. The message is
+// delivered to every session of the recipient, and echoed to every session of the sender.
+func (s *Chat) SendDirect(c echo.Context, username string, text string) (jobsity.Message, error) {
+	if strings.TrimSpace(text) == "" {
+		return jobsity.Message{}, ErrEmptyMessage
+	}
+	user := s.rbac.User(c)
+	peer, err := s.peer(c, username)
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	if peer.ID == user.ID {
+		return jobsity.Message{}, ErrSelfMessage
+	}
+
+	// Direct messages go through the rate limits and the default filters, like the ones of a room without settings
+	key := jobsity.DirectKey(user.ID, peer.ID)
+	conversation := jobsity.Room{Name: key, CompanyID: user.CompanyID}
+	if err := s.limiter.allow(conversation, user.ID, false, time.Now()); err != nil {
+		return jobsity.Message{}, err
+	}
+	var msg jobsity.Message
+	err = s.sendFiltered(c, conversation, text, func(c echo.Context, room jobsity.Room, text string) error {
+		if msg, err = s.saveMessage(key, 0, &user, text, nil); err != nil {
+			return err
+		}
+		if err := s.ddb.Touch(s.db, user.ID, peer.ID); err != nil {
+			return err
+		}
+		// Senders have read their own messages
+		if err := s.ddb.MarkRead(s.db, user.ID, peer.ID, msg.ID); err != nil {
+			return err
+		}
+		f := directFrame(msg, peer.Username)
+		if err := s.sendToUser(peer.ID, f); err != nil {
+			return err
+		}
+		return s.sendToUser(user.ID, f)
+	})
+	return msg, err
+}
+
+// ListConversations returns a page of the current user's direct conversations, latest first
+func (s *Chat) ListConversations(c echo.Context, p jobsity.Pagination) ([]jobsity.Conversation, error) {
+	return s.ddb.List(s.db, s.rbac.User(c).ID, p)
+}
+
+// ListDirectMessages returns a page of the direct messages between the current user and another one,
+// ordered by timestamp, and marks them read
+func (s *Chat) ListDirectMessages(c echo.Context, username string, p jobsity.Pagination) ([]jobsity.Message, error) {
+	user := s.rbac.User(c)
+	peer, err := s.peer(c, username)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := s.mdb.List(s.db, jobsity.DirectKey(user.ID, peer.ID), p)
+	if err != nil || len(msgs) == 0 {
+		return msgs, err
+	}
+	return msgs, s.ddb.MarkRead(s.db, user.ID, peer.ID, msgs[len(msgs)-1].ID)
+}
+
+// peer looks up the user the current user talks to. Users talk to the users of their company, and admins to anyone.
+func (s *Chat) peer(c echo.Context, username string) (jobsity.User, error) {
+	peer, err := s.udb.FindByUsername(s.db, username)
+	if err != nil {
+		return jobsity.User{}, err
+	}
+	if peer.CompanyID != s.rbac.User(c).CompanyID && !s.isAdmin(c) {
+		return jobsity.User{}, ErrOtherCompany
+	}
+	return peer, nil
+}
+
+// directFrame creates the frame of a direct message to the recipient
+func directFrame(msg jobsity.Message, to string) jobsity.Frame {
+	f := jobsity.MessageFrame(jobsity.FrameDirect, msg)
+	f.Room = ""
+	f.To = to
+	return f
+}
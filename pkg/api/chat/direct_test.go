@@ -0,0 +1,125 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// newDirectChat returns a chat with the users of the private room tests, and a partner of another company
+func newDirectChat(t *testing.T) *chat.Chat {
+	users := []jobsity.User{{Base: jobsity.Base{ID: 6}, Username: "partner", CompanyID: 9}}
+	for _, u := range members {
+		users = append(users, jobsity.User{Base: jobsity.Base{ID: u.ID}, Username: u.Username})
+	}
+	s, err := chat.New([]string{"hr"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: mockdb.NewUser(users...)}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSendDirect(t *testing.T) {
+	cases := []struct {
+		name    string
+		user    string
+		to      string
+		text    string
+		wantErr error
+	}{
+		{
+			name:    "Fail on empty message",
+			user:    "member",
+			to:      "owner",
+			wantErr: chat.ErrEmptyMessage,
+		},
+		{
+			name:    "Fail on whitespace-only message",
+			user:    "member",
+			to:      "owner",
+			text:    "  \n",
+			wantErr: chat.ErrEmptyMessage,
+		},
+		{
+			name:    "Fail on self",
+			user:    "member",
+			to:      "member",
+			text:    "hello",
+			wantErr: chat.ErrSelfMessage,
+		},
+		{
+			name:    "Fail on unknown user",
+			user:    "member",
+			to:      "nobody",
+			text:    "hello",
+			wantErr: pgsql.ErrUserNotFound,
+		},
+		{
+			name:    "Fail on other company",
+			user:    "member",
+			to:      "partner",
+			text:    "hello",
+			wantErr: chat.ErrOtherCompany,
+		},
+		{
+			name: "Success on admin",
+			user: "admin",
+			to:   "partner",
+			text: "hello",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDirectChat(t)
+			msg, err := s.SendDirect(userCtx(tt.user), tt.to, tt.text)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, jobsity.DirectKey(5, 6), msg.Room)
+				assert.Equal(t, tt.text, msg.Body)
+			}
+		})
+	}
+}
+
+func TestDirectMessages(t *testing.T) {
+	s := newDirectChat(t)
+	// Direct messages reach every session of both users, whatever room they joined
+	member, memberPeer := joinPrivateRoom(t, s, "member")
+	_, otherMemberPeer := joinPrivateRoom(t, s, "member")
+	_, ownerPeer := joinPrivateRoom(t, s, "owner")
+	receive(t, memberPeer, "owner joined the room")
+	receive(t, otherMemberPeer, "owner joined the room")
+
+	assert.Nil(t, s.HandleCommand(userCtx("member"), member, "hr", `/msg owner are  you "there?`))
+	receive(t, ownerPeer, `member to owner: are  you "there?`)
+	receive(t, memberPeer, `member to owner: are  you "there?`)
+	receive(t, otherMemberPeer, `member to owner: are  you "there?`)
+	_, err := s.SendDirect(userCtx("owner"), "member", "yes")
+	assert.Nil(t, err)
+	receive(t, memberPeer, "owner to member: yes")
+
+	convs, err := s.ListConversations(userCtx("member"), jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Conversation{{UserID: 3, PeerID: 1, LastReadID: 1}}, convs)
+
+	// Listing the messages marks them read
+	msgs, err := s.ListDirectMessages(userCtx("member"), "owner", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, `are  you "there?`, msgs[0].Body)
+		assert.Equal(t, "yes", msgs[1].Body)
+	}
+	convs, err = s.ListConversations(userCtx("member"), jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Conversation{{UserID: 3, PeerID: 1, LastReadID: 2}}, convs)
+
+	_, err = s.ListDirectMessages(userCtx("member"), "partner", jobsity.Pagination{Limit: 10})
+	assert.Equal(t, chat.ErrOtherCompany, err)
+}
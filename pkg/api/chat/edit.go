@@ -0,0 +1,174 @@
+package chat
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// DefaultEditWindow is how long senders may edit or delete their messages after sending them
+const DefaultEditWindow = 15 * time.Minute
+
+// Custom errors
+var (
+	ErrEditWindowOver = echo.NewHTTPError(http.StatusForbidden, "message can no longer be edited or deleted")
+)
+
+// SetEditWindow sets how long senders may edit or delete their messages after sending them
+func (s *Chat) SetEditWindow(window time.Duration) {
+	s.editWindow.Store(int64(window))
+}
+
+// EditMessage replaces the body of a room's message, keeping the previous one in the message's edit history,
+// and lets the room know. Edited messages go through the message filters again, and the users they newly
+// mention are notified.
+func (s *Chat) EditMessage(c echo.Context, roomName string, id int, text string) (jobsity.Message, error) {
+	if strings.TrimSpace(text) == "" {
+		return jobsity.Message{}, ErrEmptyMessage
+	}
+	room, msg, err := s.editableMessage(c, roomName, id)
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	user := s.rbac.User(c)
+	if err := s.enforceNotSanctioned(room, user.ID, jobsity.SanctionMute); err != nil {
+		return jobsity.Message{}, err
+	}
+
+	err = s.sendFiltered(c, room, text, func(c echo.Context, room jobsity.Room, text string) error {
+		// Mentions are the sender's, whoever edits the message
+		sender := jobsity.AuthUser{ID: msg.UserID, Username: msg.Username}
+		_, notified, err := s.mentions(room, sender, msg.Body)
+		if err != nil {
+			return err
+		}
+		usernames, mentions, err := s.mentions(room, sender, text)
+		if err != nil {
+			return err
+		}
+		if _, err := s.mdb.CreateEdit(s.db, jobsity.MessageEdit{MessageID: msg.ID, Body: msg.Body, EditedBy: user.ID}); err != nil {
+			return err
+		}
+		now := time.Now()
+		msg.Body = text
+		msg.Mentions = usernames
+		msg.EditedAt = &now
+		if err := s.mdb.Update(s.db, msg); err != nil {
+			return err
+		}
+		msg.Room = room.Name
+		if err := s.broadcastRunning(room, jobsity.MessageFrame(jobsity.FrameMessageEdited, msg)); err != nil {
+			return err
+		}
+		return s.notifyMentions(msg, newMentions(mentions, notified))
+	})
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	return msg, nil
+}
+
+// newMentions returns the mentions of the users not notified yet
+func newMentions(mentions, notified []jobsity.Mention) []jobsity.Mention {
+	seen := make(map[int]bool)
+	for _, m := range notified {
+		seen[m.UserID] = true
+	}
+	var fresh []jobsity.Mention
+	for _, m := range mentions {
+		if !seen[m.UserID] {
+			fresh = append(fresh, m)
+		}
+	}
+	return fresh
+}
+
+// DeleteMessage deletes a room's message and lets the room know
+func (s *Chat) DeleteMessage(c echo.Context, roomName string, id int) error {
+	room, msg, err := s.editableMessage(c, roomName, id)
+	if err != nil {
+		return err
+	}
+	if err := s.mdb.Delete(s.db, msg); err != nil {
+		return err
+	}
+	f := jobsity.NewFrame(jobsity.FrameMessageDeleted, room.Name, msg.Username, "")
+	f.ID = msg.ID
+	return s.broadcastRunning(room, f)
+}
+
+// ListMessageEdits returns the previous versions of a room's message, from the first one. Only admins of
+// the room's company or location can list them.
+func (s *Chat) ListMessageEdits(c echo.Context, roomName string, id int) ([]jobsity.MessageEdit, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return nil, err
+	}
+	if !s.isRoomAdmin(c, room) {
+		return nil, echo.ErrForbidden
+	}
+	if _, err := s.roomMessage(room, id); err != nil {
+		return nil, err
+	}
+	return s.mdb.ListEdits(s.db, id)
+}
+
+// editableMessage returns a message of an active room the current user may edit or delete. Senders edit
+// their messages within the edit window, and room moderators and admins edit any message at any time.
+func (s *Chat) editableMessage(c echo.Context, roomName string, id int) (jobsity.Room, jobsity.Message, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Room{}, jobsity.Message{}, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return jobsity.Room{}, jobsity.Message{}, err
+	}
+	if room.Archived {
+		return jobsity.Room{}, jobsity.Message{}, ErrRoomArchived
+	}
+	msg, err := s.roomMessage(room, id)
+	if err != nil {
+		return jobsity.Room{}, jobsity.Message{}, err
+	}
+
+	_, err = s.enforceRoomRole(c, room, jobsity.RoomModeratorRole)
+	if err == nil {
+		return room, msg, nil
+	}
+	if err != echo.ErrForbidden {
+		return jobsity.Room{}, jobsity.Message{}, err
+	}
+	if msg.UserID != s.rbac.User(c).ID {
+		return jobsity.Room{}, jobsity.Message{}, echo.ErrForbidden
+	}
+	if time.Since(msg.CreatedAt) > time.Duration(s.editWindow.Load()) {
+		return jobsity.Room{}, jobsity.Message{}, ErrEditWindowOver
+	}
+	return room, msg, nil
+}
+
+// roomMessage returns a message of the room by its ID
+func (s *Chat) roomMessage(room jobsity.Room, id int) (jobsity.Message, error) {
+	msg, err := s.mdb.View(s.db, id)
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	if msg.Room != room.Key() {
+		return jobsity.Message{}, pgsql.ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// broadcastRunning broadcasts the frame to the room, unless it is not running
+func (s *Chat) broadcastRunning(room jobsity.Room, f jobsity.Frame) error {
+	key := room.Key()
+	if !s.hub.Has(key) {
+		return nil
+	}
+	return s.broadcast(key, f, nil)
+}
@@ -0,0 +1,155 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+func TestEditMessage(t *testing.T) {
+	rmdb := mockdb.NewMember()
+	if _, err := rmdb.Create(nil, jobsity.RoomMember{RoomID: 1, UserID: 2, Role: jobsity.RoomModeratorRole}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Members: rmdb, Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 1}, Username: "owner"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEditWindow(time.Hour)
+
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, _ := mock.NewWSConn(t, ws.ProtocolText)
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "general", "helo"))
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "hi"))
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "helo")
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "hi")
+
+	cases := []struct {
+		name    string
+		user    string
+		room    string
+		id      int
+		text    string
+		wantErr error
+	}{
+		{
+			name:    "Fail on empty text",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			wantErr: chat.ErrEmptyMessage,
+		},
+		{
+			name:    "Fail on whitespace-only text",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			text:    " \t\n",
+			wantErr: chat.ErrEmptyMessage,
+		},
+		{
+			name:    "Fail on unknown room",
+			user:    "member",
+			room:    "random",
+			id:      1,
+			text:    "hello",
+			wantErr: pgsql.ErrRoomNotFound,
+		},
+		{
+			name:    "Fail on unknown message",
+			user:    "member",
+			room:    "general",
+			id:      3,
+			text:    "hello",
+			wantErr: pgsql.ErrMessageNotFound,
+		},
+		{
+			name:    "Fail on other users' messages",
+			user:    "owner",
+			room:    "general",
+			id:      1,
+			text:    "hello",
+			wantErr: echo.ErrForbidden,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.EditMessage(userCtx(tt.user), tt.room, tt.id, tt.text)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+
+	// Senders edit their messages within the edit window, and moderators at any time
+	msg, err := s.EditMessage(userCtx("member"), "general", 1, "hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", msg.Body)
+	assert.NotNil(t, msg.EditedAt)
+	receiveFrame(t, ownerPeer, jobsity.FrameMessageEdited, "hello")
+	s.SetEditWindow(time.Nanosecond)
+	assert.Equal(t, chat.ErrEditWindowOver, s.DeleteMessage(userCtx("member"), "general", 1))
+	_, err = s.EditMessage(userCtx("moderator"), "general", 1, "hello!")
+	assert.Nil(t, err)
+	receiveFrame(t, ownerPeer, jobsity.FrameMessageEdited, "hello!")
+
+	// Users newly mentioned by an edit are notified, once
+	_, err = s.EditMessage(userCtx("moderator"), "general", 1, "hello @owner")
+	assert.Nil(t, err)
+	frames := make(map[string]jobsity.Frame)
+	for i := 0; i < 2; i++ {
+		var f jobsity.Frame
+		assert.Nil(t, websocket.JSON.Receive(ownerPeer, &f))
+		assert.Equal(t, "hello @owner", f.Payload.Text)
+		assert.Equal(t, []string{"owner"}, f.Payload.Mentions)
+		frames[f.Type] = f
+	}
+	assert.Contains(t, frames, jobsity.FrameMessageEdited)
+	assert.Contains(t, frames, jobsity.FrameMention)
+	_, err = s.EditMessage(userCtx("moderator"), "general", 1, "hello @owner!")
+	assert.Nil(t, err)
+	receiveFrame(t, ownerPeer, jobsity.FrameMessageEdited, "hello @owner!")
+
+	// Admins see the previous versions of a message
+	_, err = s.ListMessageEdits(userCtx("member"), "general", 1)
+	assert.Equal(t, echo.ErrForbidden, err)
+	edits, err := s.ListMessageEdits(userCtx("admin"), "general", 1)
+	assert.Nil(t, err)
+	if assert.Len(t, edits, 4) {
+		assert.Equal(t, "helo", edits[0].Body)
+		assert.Equal(t, 3, edits[0].EditedBy)
+		assert.Equal(t, "hello", edits[1].Body)
+		assert.Equal(t, 2, edits[1].EditedBy)
+		assert.Equal(t, "hello!", edits[2].Body)
+		assert.Equal(t, "hello @owner", edits[3].Body)
+	}
+
+	// Deleted messages are gone from the history, and no more mentions were sent meanwhile
+	assert.Nil(t, s.DeleteMessage(userCtx("moderator"), "general", 2))
+	var f jobsity.Frame
+	assert.Nil(t, websocket.JSON.Receive(ownerPeer, &f))
+	assert.Equal(t, jobsity.FrameMessageDeleted, f.Type)
+	assert.Equal(t, 2, f.ID)
+	assert.Equal(t, "owner", f.Sender)
+	msgs, err := s.ListMessages(userCtx("owner"), "general", jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "hello @owner!", msgs[0].Body)
+		assert.Equal(t, []string{"owner"}, msgs[0].Mentions)
+	}
+	_, err = s.ListMessageEdits(userCtx("admin"), "general", 2)
+	assert.Equal(t, pgsql.ErrMessageNotFound, err)
+}
@@ -0,0 +1,273 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Built-in message filters
+const (
+	FilterMaxLength    = "max_length"
+	FilterProfanity    = "profanity"
+	FilterBlockedWords = "blocked_words"
+	FilterStripLinks   = "strip_links"
+)
+
+// linkRegexp matches web links, with or without scheme
+var linkRegexp = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// MessageHandler sends a message of the current user to a room
+type MessageHandler func(c echo.Context, room jobsity.Room, text string) error
+
+// MessageFilter wraps the send path of the messages. Filters pass messages on to the next handler,
+// rewritten or not, or reject them with RejectMessage.
+type MessageFilter func(next MessageHandler) MessageHandler
+
+// FilterConfig holds the built-in message filters settings and the filters every room applies
+type FilterConfig struct {
+	// Default are the names of the filters every message goes through, in order, before the room's own filters
+	Default []string
+	// MaxLength is the number of characters of the longest message, for the max_length filter
+	MaxLength int
+	// ProfanityFile is the word list masked by the profanity filter
+	ProfanityFile string
+	// BlockedWordsFile is the word list rejected by the blocked_words filter
+	BlockedWordsFile string
+}
+
+// RejectMessage returns the error of a filter rejecting a message, telling its sender the reason
+func RejectMessage(reason string) error {
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, "your message was rejected: "+reason)
+}
+
+// RegisterFilter adds a message filter rooms can apply by name. Filter names must be unique.
+func (s *Chat) RegisterFilter(name string, f MessageFilter) error {
+	return s.filters.register(name, f)
+}
+
+// SetFilters sets up the built-in message filters and the filters every room applies
+func (s *Chat) SetFilters(cfg FilterConfig) error {
+	if cfg.MaxLength > 0 {
+		s.filters.set(FilterMaxLength, MaxLength(cfg.MaxLength))
+	}
+	if cfg.ProfanityFile != "" {
+		words, err := LoadWordList(cfg.ProfanityFile)
+		if err != nil {
+			return err
+		}
+		s.filters.set(FilterProfanity, MaskWords(words))
+	}
+	if cfg.BlockedWordsFile != "" {
+		words, err := LoadWordList(cfg.BlockedWordsFile)
+		if err != nil {
+			return err
+		}
+		s.filters.set(FilterBlockedWords, BlockWords(words))
+	}
+	return s.filters.setDefault(cfg.Default)
+}
+
+// sendFiltered sends a message to a room through the default filters and the room's own ones
+func (s *Chat) sendFiltered(c echo.Context, room jobsity.Room, text string, send MessageHandler) error {
+	return s.filters.chain(room.Filters, send)(c, room, text)
+}
+
+// MaxLength rejects messages longer than max characters
+func MaxLength(max int) MessageFilter {
+	return func(next MessageHandler) MessageHandler {
+		return func(c echo.Context, room jobsity.Room, text string) error {
+			if utf8.RuneCountInString(text) > max {
+				return RejectMessage(fmt.Sprintf("messages cannot be longer than %d characters", max))
+			}
+			return next(c, room, text)
+		}
+	}
+}
+
+// MaskWords replaces the letters of the listed words with asterisks
+func MaskWords(words *WordList) MessageFilter {
+	return func(next MessageHandler) MessageHandler {
+		return func(c echo.Context, room jobsity.Room, text string) error {
+			if re := words.regexp(room.CompanyID); re != nil {
+				text = re.ReplaceAllStringFunc(text, func(word string) string {
+					return strings.Repeat("*", utf8.RuneCountInString(word))
+				})
+			}
+			return next(c, room, text)
+		}
+	}
+}
+
+// BlockWords rejects messages containing any of the listed words
+func BlockWords(words *WordList) MessageFilter {
+	return func(next MessageHandler) MessageHandler {
+		return func(c echo.Context, room jobsity.Room, text string) error {
+			if re := words.regexp(room.CompanyID); re != nil {
+				if word := re.FindString(text); word != "" {
+					return RejectMessage(fmt.Sprintf("%q is not allowed here", strings.ToLower(word)))
+				}
+			}
+			return next(c, room, text)
+		}
+	}
+}
+
+// StripLinks replaces web links with a placeholder
+func StripLinks(next MessageHandler) MessageHandler {
+	return func(c echo.Context, room jobsity.Room, text string) error {
+		return next(c, room, linkRegexp.ReplaceAllString(text, "[link removed]"))
+	}
+}
+
+// WordList holds words matched regardless of case, either in every room or in the rooms of a company
+type WordList struct {
+	// words by company, where company 0 words apply to every room
+	words   map[int][]string
+	mu      sync.Mutex
+	regexps map[int]*regexp.Regexp
+}
+
+// LoadWordList reads a word list file. See ParseWordList for its format.
+func LoadWordList(path string) (*WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWordList(f)
+}
+
+// ParseWordList reads a word list with one word or phrase per line. Words apply to every room, unless
+// they follow a [<company_id>] line, which starts the words of that company's rooms. Blank lines and
+// lines starting with # are ignored.
+func ParseWordList(r io.Reader) (*WordList, error) {
+	wl := &WordList{words: make(map[int][]string), regexps: make(map[int]*regexp.Regexp)}
+	company := 0
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			id, err := strconv.Atoi(strings.TrimSpace(line[1 : len(line)-1]))
+			if err != nil || id < 0 {
+				return nil, fmt.Errorf("word list line %d: invalid company %q", n, line)
+			}
+			company = id
+		default:
+			wl.words[company] = append(wl.words[company], strings.ToLower(line))
+		}
+	}
+	return wl, scanner.Err()
+}
+
+// regexp returns the expression matching the words of a company's rooms, or nil when there are none
+func (wl *WordList) regexp(companyID int) *regexp.Regexp {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	if re, ok := wl.regexps[companyID]; ok {
+		return re
+	}
+	words := wl.words[0]
+	if companyID != 0 {
+		words = append(words[:len(words):len(words)], wl.words[companyID]...)
+	}
+	var re *regexp.Regexp
+	if len(words) > 0 {
+		quoted := make([]string, len(words))
+		for i, w := range words {
+			quoted[i] = regexp.QuoteMeta(w)
+		}
+		re = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+	wl.regexps[companyID] = re
+	return re
+}
+
+// filters is a registry of message filters and the names of the ones every room applies
+type filters struct {
+	mu       sync.RWMutex
+	registry map[string]MessageFilter
+	defaults []string
+}
+
+func newFilters() *filters {
+	return &filters{registry: map[string]MessageFilter{FilterStripLinks: StripLinks}}
+}
+
+func (r *filters) register(name string, f MessageFilter) error {
+	if name == "" || strings.ContainsAny(name, " ,") {
+		return fmt.Errorf("invalid filter name %q", name)
+	}
+	if f == nil {
+		return fmt.Errorf("filter %s is nil", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.registry[name]; ok {
+		return fmt.Errorf("filter %s is already registered", name)
+	}
+	r.registry[name] = f
+	return nil
+}
+
+// set adds a built-in filter, replacing its previous settings
+func (r *filters) set(name string, f MessageFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registry[name] = f
+}
+
+func (r *filters) setDefault(names []string) error {
+	if err := r.validate(names); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults = names
+	return nil
+}
+
+// validate checks whether the filters are registered
+func (r *filters) validate(names []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range names {
+		if _, ok := r.registry[name]; !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown message filter %s", name))
+		}
+	}
+	return nil
+}
+
+// chain wraps the handler with the default filters followed by the given ones. Filters that are
+// not registered anymore are skipped, and so are the ones already applied.
+func (r *filters) chain(names []string, h MessageHandler) MessageHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var chain []MessageFilter
+	applied := make(map[string]bool)
+	for _, name := range append(r.defaults[:len(r.defaults):len(r.defaults)], names...) {
+		if f, ok := r.registry[name]; ok && !applied[name] {
+			chain = append(chain, f)
+			applied[name] = true
+		}
+	}
+	// The first filter receives the message first
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h
+}
@@ -0,0 +1,125 @@
+package chat_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+)
+
+// filtered returns the text a filter passes on to the next handler, and its error
+func filtered(f chat.MessageFilter, room jobsity.Room, text string) (string, error) {
+	var got string
+	err := f(func(c echo.Context, room jobsity.Room, text string) error {
+		got = text
+		return nil
+	})(nil, room, text)
+	return got, err
+}
+
+func TestMessageFilters(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	assert.Nil(t, s.SetFilters(chat.FilterConfig{Default: []string{chat.FilterMaxLength}, MaxLength: 20}))
+	assert.NotNil(t, s.SetFilters(chat.FilterConfig{Default: []string{"shout"}}))
+	assert.Nil(t, s.RegisterFilter("shout", func(next chat.MessageHandler) chat.MessageHandler {
+		return func(c echo.Context, room jobsity.Room, text string) error {
+			return next(c, room, strings.ToUpper(text))
+		}
+	}))
+	assert.NotNil(t, s.RegisterFilter("shout", chat.StripLinks))
+
+	member, _ := joinPrivateRoom(t, s, "member")
+	_, ownerPeer := joinPrivateRoom(t, s, "owner")
+
+	unknown := []string{"whisper"}
+	_, err := s.UpdateRoom(userCtx("owner"), chat.UpdateRoom{Name: "hr", Filters: &unknown})
+	assert.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "unknown message filter whisper"), err)
+	roomFilters := []string{"shout", chat.FilterStripLinks}
+	room, err := s.UpdateRoom(userCtx("owner"), chat.UpdateRoom{Name: "hr", Filters: &roomFilters})
+	assert.Nil(t, err)
+	assert.Equal(t, roomFilters, room.Filters)
+
+	// Messages go through the default filters, then the room's ones
+	assert.Equal(t, chat.RejectMessage("messages cannot be longer than 20 characters"),
+		s.SendMessage(userCtx("member"), member, "hr", "this message is way too long"))
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", "see www.example.com"))
+	receive(t, ownerPeer, "member: SEE [link removed]")
+}
+
+func TestWordListFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# Everywhere\ndarn\n\n[2]\nheck\nno way\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	words, err := chat.LoadWordList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		filter   chat.MessageFilter
+		room     jobsity.Room
+		text     string
+		wantErr  error
+		wantText string
+	}{
+		{
+			name:     "Mask global words",
+			filter:   chat.MaskWords(words),
+			room:     jobsity.Room{Name: "general"},
+			text:     "Darn it, what the heck",
+			wantText: "**** it, what the heck",
+		},
+		{
+			name:     "Mask company words",
+			filter:   chat.MaskWords(words),
+			room:     jobsity.Room{Name: "general", CompanyID: 2},
+			text:     "Darn it, what the heck, no way",
+			wantText: "**** it, what the ****, ******",
+		},
+		{
+			name:     "Mask whole words only",
+			filter:   chat.MaskWords(words),
+			room:     jobsity.Room{Name: "general"},
+			text:     "darning socks",
+			wantText: "darning socks",
+		},
+		{
+			name:    "Reject blocked words",
+			filter:  chat.BlockWords(words),
+			room:    jobsity.Room{Name: "general", CompanyID: 2},
+			text:    "HECK no",
+			wantErr: chat.RejectMessage(`"heck" is not allowed here`),
+		},
+		{
+			name:     "Pass other companies",
+			filter:   chat.BlockWords(words),
+			room:     jobsity.Room{Name: "general", CompanyID: 3},
+			text:     "heck no",
+			wantText: "heck no",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := filtered(tt.filter, tt.room, tt.text)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantText, text)
+		})
+	}
+}
+
+func TestParseWordList(t *testing.T) {
+	_, err := chat.ParseWordList(strings.NewReader("darn\n[acme]\nheck\n"))
+	assert.EqualError(t, err, `word list line 2: invalid company "[acme]"`)
+	_, err = chat.LoadWordList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
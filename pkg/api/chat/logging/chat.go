@@ -0,0 +1,596 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+)
+
+// New creates new chat logging service
+func New(svc chat.Service, logger jobsity.Logger) *LogService {
+	return &LogService{
+		Service: svc,
+		logger:  logger,
+	}
+}
+
+// LogService represents chat logging service
+type LogService struct {
+	chat.Service
+	logger jobsity.Logger
+}
+
+const name = "chat"
+
+// HandleFrame logging
+func (ls *LogService) HandleFrame(c echo.Context, conn *websocket.Conn, roomName string, data []byte) (f jobsity.Frame, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Handle frame request", err,
+			map[string]interface{}{
+				"room": f.Room,
+				"type": f.Type,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.HandleFrame(c, conn, roomName, data)
+}
+
+// HandleCommand logging
+func (ls *LogService) HandleCommand(c echo.Context, conn *websocket.Conn, roomName string, message string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Handle command request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  message,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.HandleCommand(c, conn, roomName, message)
+}
+
+// JoinRoom logging
+func (ls *LogService) JoinRoom(c echo.Context, conn *websocket.Conn, roomName string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Join room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.JoinRoom(c, conn, roomName)
+}
+
+// LeaveRoom logging
+func (ls *LogService) LeaveRoom(c echo.Context, conn *websocket.Conn, roomName string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Leave room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.LeaveRoom(c, conn, roomName)
+}
+
+// Disconnect logging
+func (ls *LogService) Disconnect(c echo.Context, conn *websocket.Conn) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Disconnect request", err,
+			map[string]interface{}{
+				"stats": ls.Service.Stats(c),
+				"took":  time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.Disconnect(c, conn)
+}
+
+// SendMessage logging
+func (ls *LogService) SendMessage(c echo.Context, conn *websocket.Conn, roomName string, message string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Send message request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.SendMessage(c, conn, roomName, message)
+}
+
+// SendReply logging
+func (ls *LogService) SendReply(c echo.Context, conn *websocket.Conn, roomName string, parentID int, message string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Send reply request", err,
+			map[string]interface{}{
+				"room":      roomName,
+				"parent_id": parentID,
+				"took":      time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.SendReply(c, conn, roomName, parentID, message)
+}
+
+// GetUsersInRoom logging
+func (ls *LogService) GetUsersInRoom(c echo.Context, roomName string) (resp []string, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Get users in room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.GetUsersInRoom(c, roomName)
+}
+
+// CreateRoom logging
+func (ls *LogService) CreateRoom(c echo.Context, req jobsity.Room) (resp jobsity.Room, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Create room request", err,
+			map[string]interface{}{
+				"req":  req,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.CreateRoom(c, req)
+}
+
+// ListRooms logging
+func (ls *LogService) ListRooms(c echo.Context, archived bool, p jobsity.Pagination) (resp []jobsity.Room, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List rooms request", err,
+			map[string]interface{}{
+				"archived": archived,
+				"req":      p,
+				"took":     time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListRooms(c, archived, p)
+}
+
+// ViewRoom logging
+func (ls *LogService) ViewRoom(c echo.Context, roomName string) (resp jobsity.Room, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "View room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ViewRoom(c, roomName)
+}
+
+// UpdateRoom logging
+func (ls *LogService) UpdateRoom(c echo.Context, req chat.UpdateRoom) (resp jobsity.Room, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Update room request", err,
+			map[string]interface{}{
+				"req":  req,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.UpdateRoom(c, req)
+}
+
+// ArchiveRoom logging
+func (ls *LogService) ArchiveRoom(c echo.Context, roomName string) (resp jobsity.Room, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Archive room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ArchiveRoom(c, roomName)
+}
+
+// DeleteRoom logging
+func (ls *LogService) DeleteRoom(c echo.Context, roomName string) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Delete room request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.DeleteRoom(c, roomName)
+}
+
+// ListMembers logging
+func (ls *LogService) ListMembers(c echo.Context, roomName string) (resp []jobsity.RoomMember, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List members request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListMembers(c, roomName)
+}
+
+// InviteMember logging
+func (ls *LogService) InviteMember(c echo.Context, roomName string, req jobsity.RoomMember) (resp jobsity.RoomMember, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Invite member request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  req,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.InviteMember(c, roomName, req)
+}
+
+// RemoveMember logging
+func (ls *LogService) RemoveMember(c echo.Context, roomName string, userID int) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Remove member request", err,
+			map[string]interface{}{
+				"room":    roomName,
+				"user_id": userID,
+				"took":    time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.RemoveMember(c, roomName, userID)
+}
+
+// KickUser logging
+func (ls *LogService) KickUser(c echo.Context, roomName string, req chat.Moderation) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Kick user request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  req,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.KickUser(c, roomName, req)
+}
+
+// BanUser logging
+func (ls *LogService) BanUser(c echo.Context, roomName string, req chat.Moderation) (resp jobsity.Sanction, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Ban user request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  req,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.BanUser(c, roomName, req)
+}
+
+// UnbanUser logging
+func (ls *LogService) UnbanUser(c echo.Context, roomName string, userID int) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Unban user request", err,
+			map[string]interface{}{
+				"room":    roomName,
+				"user_id": userID,
+				"took":    time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.UnbanUser(c, roomName, userID)
+}
+
+// MuteUser logging
+func (ls *LogService) MuteUser(c echo.Context, roomName string, req chat.Moderation) (resp jobsity.Sanction, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Mute user request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  req,
+				"resp": resp,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.MuteUser(c, roomName, req)
+}
+
+// ListModerationLog logging
+func (ls *LogService) ListModerationLog(c echo.Context, roomName string, p jobsity.Pagination) (resp []jobsity.ModerationLog, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List moderation log request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListModerationLog(c, roomName, p)
+}
+
+// ListMessages logging
+func (ls *LogService) ListMessages(c echo.Context, roomName string, p jobsity.Pagination) (resp []jobsity.Message, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List messages request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListMessages(c, roomName, p)
+}
+
+// EditMessage logging
+func (ls *LogService) EditMessage(c echo.Context, roomName string, id int, text string) (resp jobsity.Message, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Edit message request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"id":   id,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.EditMessage(c, roomName, id, text)
+}
+
+// DeleteMessage logging
+func (ls *LogService) DeleteMessage(c echo.Context, roomName string, id int) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Delete message request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"id":   id,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.DeleteMessage(c, roomName, id)
+}
+
+// ListMessageEdits logging
+func (ls *LogService) ListMessageEdits(c echo.Context, roomName string, id int) (resp []jobsity.MessageEdit, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List message edits request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"id":   id,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListMessageEdits(c, roomName, id)
+}
+
+// ViewThread logging
+func (ls *LogService) ViewThread(c echo.Context, roomName string, id int, p jobsity.Pagination) (resp jobsity.Thread, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "View thread request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"id":   id,
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ViewThread(c, roomName, id, p)
+}
+
+// ToggleReaction logging
+func (ls *LogService) ToggleReaction(c echo.Context, roomName string, id int, emoji string) (resp jobsity.Message, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Toggle reaction request", err,
+			map[string]interface{}{
+				"room":  roomName,
+				"id":    id,
+				"emoji": emoji,
+				"took":  time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ToggleReaction(c, roomName, id, emoji)
+}
+
+// SendDirect logging
+func (ls *LogService) SendDirect(c echo.Context, username string, text string) (resp jobsity.Message, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Send direct message request", err,
+			map[string]interface{}{
+				"to":   username,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.SendDirect(c, username, text)
+}
+
+// ListConversations logging
+func (ls *LogService) ListConversations(c echo.Context, p jobsity.Pagination) (resp []jobsity.Conversation, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List conversations request", err,
+			map[string]interface{}{
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListConversations(c, p)
+}
+
+// ListMentions logging
+func (ls *LogService) ListMentions(c echo.Context, p jobsity.Pagination) (resp []jobsity.Mention, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List mentions request", err,
+			map[string]interface{}{
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListMentions(c, p)
+}
+
+// ListDirectMessages logging
+func (ls *LogService) ListDirectMessages(c echo.Context, username string, p jobsity.Pagination) (resp []jobsity.Message, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List direct messages request", err,
+			map[string]interface{}{
+				"with": username,
+				"req":  p,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListDirectMessages(c, username, p)
+}
+
+// SetAway logging
+func (ls *LogService) SetAway(c echo.Context, conn *websocket.Conn, away bool) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Set away request", err,
+			map[string]interface{}{
+				"away": away,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.SetAway(c, conn, away)
+}
+
+// ListPresence logging
+func (ls *LogService) ListPresence(c echo.Context, roomName string) (resp []jobsity.Presence, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "List presence request", err,
+			map[string]interface{}{
+				"room": roomName,
+				"took": time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.ListPresence(c, roomName)
+}
+
+// Typing logging
+func (ls *LogService) Typing(c echo.Context, conn *websocket.Conn, roomName string, typing bool) (err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Typing request", err,
+			map[string]interface{}{
+				"room":   roomName,
+				"typing": typing,
+				"took":   time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.Typing(c, conn, roomName, typing)
+}
+
+// MarkRead logging
+func (ls *LogService) MarkRead(c echo.Context, roomName string, messageID int) (resp jobsity.ReadCursor, err error) {
+	defer func(begin time.Time) {
+		ls.logger.Log(
+			c,
+			name, "Mark read request", err,
+			map[string]interface{}{
+				"room":       roomName,
+				"message_id": messageID,
+				"took":       time.Since(begin),
+			},
+		)
+	}(time.Now())
+	return ls.Service.MarkRead(c, roomName, messageID)
+}
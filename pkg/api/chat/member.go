@@ -0,0 +1,205 @@
+package chat
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Custom errors
+var (
+	ErrPrivateRoom   = echo.NewHTTPError(http.StatusForbidden, "room is private")
+	ErrInvalidRole   = echo.NewHTTPError(http.StatusBadRequest, "invalid room role")
+	ErrRoleTooHigh   = echo.NewHTTPError(http.StatusForbidden, "cannot grant a role higher than yours")
+	ErrMemberTooHigh = echo.NewHTTPError(http.StatusForbidden, "cannot remove a member with a role higher than yours")
+	ErrOtherLocation = echo.NewHTTPError(http.StatusForbidden, "room belongs to another location")
+	ErrOtherTenant   = echo.NewHTTPError(http.StatusForbidden, "user is not in the room's company or location")
+)
+
+// isAdmin reports whether the current user is an admin, who has every room role
+func (s *Chat) isAdmin(c echo.Context) bool {
+	return s.rbac.EnforceRole(c, jobsity.AdminRole) == nil
+}
+
+// isRoomAdmin reports whether the current user administers the room's tenant. Admins administer
+// the global rooms, company admins the rooms of their company and location admins the rooms of their location.
+func (s *Chat) isRoomAdmin(c echo.Context, room jobsity.Room) bool {
+	if room.CompanyID == 0 {
+		return s.isAdmin(c)
+	}
+	if s.rbac.EnforceCompany(c, room.CompanyID) == nil {
+		return true
+	}
+	return room.LocationID != 0 && s.rbac.User(c).CompanyID == room.CompanyID &&
+		s.rbac.EnforceLocation(c, room.LocationID) == nil
+}
+
+// administersRoom reports whether the user administers the room's tenant, like isRoomAdmin does for the current user
+func administersRoom(user jobsity.User, room jobsity.Room) bool {
+	switch {
+	case user.RoleID <= jobsity.AdminRole:
+		return true
+	case room.CompanyID == 0 || user.CompanyID != room.CompanyID:
+		return false
+	case user.RoleID <= jobsity.CompanyAdminRole:
+		return true
+	default:
+		return room.LocationID != 0 && user.RoleID <= jobsity.LocationAdminRole && user.LocationID == room.LocationID
+	}
+}
+
+// reachesRoom reports whether the user belongs to the room's tenant, as rooms are listed to users. Admins
+// reach every room, company admins the rooms of their company and other users the rooms of their location.
+func reachesRoom(user jobsity.User, room jobsity.Room) bool {
+	switch {
+	case user.RoleID <= jobsity.AdminRole:
+		return true
+	case room.CompanyID != 0 && user.CompanyID != room.CompanyID:
+		return false
+	case user.RoleID <= jobsity.CompanyAdminRole:
+		return true
+	default:
+		return room.LocationID == 0 || user.LocationID == room.LocationID
+	}
+}
+
+// enforceRoomRole checks whether the current user has at least the role in the room, returning the
+// user's membership. Admins of the room's tenant are treated as owners of the room.
+func (s *Chat) enforceRoomRole(c echo.Context, room jobsity.Room, role jobsity.RoomRole) (jobsity.RoomMember, error) {
+	user := s.rbac.User(c)
+	if s.isRoomAdmin(c, room) {
+		return jobsity.RoomMember{RoomID: room.ID, UserID: user.ID, Role: jobsity.RoomOwnerRole}, nil
+	}
+	member, err := s.rmdb.View(s.db, room.ID, user.ID)
+	if err == pgsql.ErrMemberNotFound {
+		return member, echo.ErrForbidden
+	}
+	if err != nil {
+		return member, err
+	}
+	if member.Role > role {
+		return member, echo.ErrForbidden
+	}
+	return member, nil
+}
+
+// enforceRoomAccess checks whether the current user may read and join the room. Public rooms are open to
+// anyone in the room's company, or in the room's location for location rooms.
+func (s *Chat) enforceRoomAccess(c echo.Context, room jobsity.Room) error {
+	if room.LocationID != 0 && s.rbac.User(c).LocationID != room.LocationID && !s.isRoomAdmin(c, room) {
+		return ErrOtherLocation
+	}
+	if !room.Private {
+		return nil
+	}
+	if _, err := s.enforceRoomRole(c, room, jobsity.RoomMemberRole); err != nil {
+		if err == echo.ErrForbidden {
+			return ErrPrivateRoom
+		}
+		return err
+	}
+	return nil
+}
+
+// ListMembers returns members of a room. Members of private rooms are only listed to the other members.
+func (s *Chat) ListMembers(c echo.Context, roomName string) ([]jobsity.RoomMember, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return nil, err
+	}
+	return s.rmdb.List(s.db, room.ID)
+}
+
+// InviteMember adds a user of the room's company and location to a room. Only owners and moderators can
+// invite, granting up to their own role.
+func (s *Chat) InviteMember(c echo.Context, roomName string, req jobsity.RoomMember) (jobsity.RoomMember, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.RoomMember{}, err
+	}
+	inviter, err := s.enforceRoomRole(c, room, jobsity.RoomModeratorRole)
+	if err != nil {
+		return jobsity.RoomMember{}, err
+	}
+
+	if req.Role == 0 {
+		req.Role = jobsity.RoomMemberRole
+	}
+	switch req.Role {
+	case jobsity.RoomOwnerRole, jobsity.RoomModeratorRole, jobsity.RoomMemberRole:
+	default:
+		return jobsity.RoomMember{}, ErrInvalidRole
+	}
+	if req.Role < inviter.Role {
+		return jobsity.RoomMember{}, ErrRoleTooHigh
+	}
+	invitee, err := s.udb.View(s.db, req.UserID)
+	if err != nil {
+		return jobsity.RoomMember{}, err
+	}
+	if !reachesRoom(invitee, room) {
+		return jobsity.RoomMember{}, ErrOtherTenant
+	}
+
+	req.RoomID = room.ID
+	return s.rmdb.Create(s.db, req)
+}
+
+// RemoveMember removes a user from a room. Users can remove themselves, while owners and moderators
+// can remove members up to their own role. Removed users are evicted from private rooms.
+func (s *Chat) RemoveMember(c echo.Context, roomName string, userID int) error {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return err
+	}
+	member, err := s.rmdb.View(s.db, room.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.rbac.User(c).ID != userID {
+		remover, err := s.enforceRoomRole(c, room, jobsity.RoomModeratorRole)
+		if err != nil {
+			return err
+		}
+		if member.Role < remover.Role {
+			return ErrMemberTooHigh
+		}
+	}
+
+	if err := s.rmdb.Delete(s.db, member); err != nil {
+		return err
+	}
+	if !room.Private || room.Archived {
+		return nil
+	}
+	notice := "You were removed from the " + room.Name + " chat room"
+	s.evict(room.Key(), userID, notice)
+	return s.publish(roomTopicPrefix+room.Key(), event{Kind: eventEvict, Room: room.Key(), UserID: userID, Notice: notice})
+}
+
+// evict removes the user's clients connected to this instance from the room with the key, sending them the notice
+func (s *Chat) evict(roomKey string, userID int, notice string) {
+	s.mu.RLock()
+	var evicted []*client
+	for _, cl := range s.sessions {
+		if cl.User.ID == userID {
+			evicted = append(evicted, cl)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, cl := range evicted {
+		room, ok := cl.roomByKey(roomKey)
+		// The client might have left meanwhile
+		if ok && s.leave(cl, room.Name) == nil {
+			cl.Send(jobsity.NewFrame(jobsity.FrameSystem, room.Name, "", notice))
+		}
+	}
+}
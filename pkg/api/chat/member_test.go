@@ -0,0 +1,322 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// Users of the private room tests, by username
+var members = map[string]jobsity.AuthUser{
+	"owner":     {ID: 1, Username: "owner", Role: jobsity.UserRole},
+	"moderator": {ID: 2, Username: "moderator", Role: jobsity.UserRole},
+	"member":    {ID: 3, Username: "member", Role: jobsity.UserRole},
+	"outsider":  {ID: 4, Username: "outsider", Role: jobsity.UserRole},
+	"admin":     {ID: 5, Username: "admin", Role: jobsity.AdminRole},
+}
+
+// membersRBAC returns a RBAC mock authenticating the user named by the context's username key
+func membersRBAC() *mock.RBAC {
+	return &mock.RBAC{
+		UserFn: func(c echo.Context) jobsity.AuthUser {
+			return members[c.Get("username").(string)]
+		},
+		EnforceRoleFn: func(c echo.Context, role jobsity.AccessRole) error {
+			if members[c.Get("username").(string)].Role > role {
+				return echo.ErrForbidden
+			}
+			return nil
+		},
+	}
+}
+
+func userCtx(username string) echo.Context {
+	return mock.EchoCtxWithKeys([]string{"username"}, username)
+}
+
+// newPrivateRoom returns a chat running the private hr room, owned, moderated and joined by the respective users
+func newPrivateRoom(t *testing.T, hub *ws.Hub) *chat.Chat {
+	rdb := mockdb.NewRoom()
+	room, err := rdb.Create(nil, jobsity.Room{Name: "hr", Private: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmdb := mockdb.NewMember()
+	for _, m := range []jobsity.RoomMember{
+		{RoomID: room.ID, UserID: 1, Role: jobsity.RoomOwnerRole},
+		{RoomID: room.ID, UserID: 2, Role: jobsity.RoomModeratorRole},
+		{RoomID: room.ID, UserID: 3, Role: jobsity.RoomMemberRole},
+	} {
+		if _, err := rmdb.Create(nil, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			return msg, nil
+		},
+	}
+	var users []jobsity.User
+	for _, u := range members {
+		users = append(users, jobsity.User{Base: jobsity.Base{ID: u.ID}, Username: u.Username, RoleID: u.Role})
+	}
+	s, err := chat.New(nil, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Rooms: rdb, Members: rmdb, Users: mockdb.NewUser(users...)}), broker.NewMemory(), nil, hub, membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJoinPrivateRoom(t *testing.T) {
+	cases := []struct {
+		name    string
+		user    string
+		wantErr error
+	}{
+		{
+			name:    "Fail on non-member",
+			user:    "outsider",
+			wantErr: chat.ErrPrivateRoom,
+		},
+		{
+			name: "Success on member",
+			user: "member",
+		},
+		{
+			name: "Success on admin",
+			user: "admin",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+			conn, _ := mock.NewWSConn(t, ws.ProtocolText)
+			assert.Equal(t, tt.wantErr, s.JoinRoom(userCtx(tt.user), conn, "hr"))
+		})
+	}
+}
+
+func TestGetUsersInPrivateRoom(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	conn, _ := joinPrivateRoom(t, s, "member")
+
+	got, err := s.GetUsersInRoom(userCtx("member"), "hr")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"member"}, got)
+
+	// Non-members do not see who is present, not even through /users
+	_, err = s.GetUsersInRoom(userCtx("outsider"), "hr")
+	assert.Equal(t, chat.ErrPrivateRoom, err)
+	outsider, _ := mock.NewWSConn(t, ws.ProtocolText)
+	assert.Equal(t, chat.ErrPrivateRoom, s.HandleCommand(userCtx("outsider"), outsider, "hr", "/users"))
+	assert.Nil(t, s.HandleCommand(userCtx("member"), conn, "hr", "/users"))
+}
+
+func TestInviteMember(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     string
+		room     string
+		req      jobsity.RoomMember
+		wantErr  error
+		wantData jobsity.RoomMember
+	}{
+		{
+			name:    "Fail on room not found",
+			user:    "owner",
+			room:    "lobby",
+			req:     jobsity.RoomMember{UserID: 4},
+			wantErr: pgsql.ErrRoomNotFound,
+		},
+		{
+			name:    "Fail on non-member",
+			user:    "outsider",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 4},
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on member",
+			user:    "member",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 4},
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on invalid role",
+			user:    "owner",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 4, Role: 150},
+			wantErr: chat.ErrInvalidRole,
+		},
+		{
+			name:    "Fail on granting higher role",
+			user:    "moderator",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 4, Role: jobsity.RoomOwnerRole},
+			wantErr: chat.ErrRoleTooHigh,
+		},
+		{
+			name:    "Fail on unknown user",
+			user:    "owner",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 9},
+			wantErr: pgsql.ErrUserNotFound,
+		},
+		{
+			name:    "Fail on existing member",
+			user:    "owner",
+			room:    "hr",
+			req:     jobsity.RoomMember{UserID: 3},
+			wantErr: pgsql.ErrAlreadyMember,
+		},
+		{
+			name:     "Success on moderator",
+			user:     "moderator",
+			room:     "hr",
+			req:      jobsity.RoomMember{UserID: 4},
+			wantData: jobsity.RoomMember{Base: jobsity.Base{ID: 4}, RoomID: 1, UserID: 4, Role: jobsity.RoomMemberRole},
+		},
+		{
+			name:     "Success on admin",
+			user:     "admin",
+			room:     "hr",
+			req:      jobsity.RoomMember{UserID: 4, Role: jobsity.RoomOwnerRole},
+			wantData: jobsity.RoomMember{Base: jobsity.Base{ID: 4}, RoomID: 1, UserID: 4, Role: jobsity.RoomOwnerRole},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+			member, err := s.InviteMember(userCtx(tt.user), tt.room, tt.req)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantData, member)
+		})
+	}
+}
+
+func TestInviteTenantMember(t *testing.T) {
+	users := []jobsity.User{
+		{Base: jobsity.Base{ID: 1}, Username: "alice", RoleID: jobsity.UserRole, CompanyID: 1, LocationID: 1},
+		{Base: jobsity.Base{ID: 2}, Username: "carol", RoleID: jobsity.UserRole, CompanyID: 1, LocationID: 2},
+		{Base: jobsity.Base{ID: 3}, Username: "boss", RoleID: jobsity.CompanyAdminRole, CompanyID: 1, LocationID: 2},
+		{Base: jobsity.Base{ID: 4}, Username: "bob", RoleID: jobsity.UserRole, CompanyID: 2, LocationID: 3},
+		{Base: jobsity.Base{ID: 5}, Username: "admin", RoleID: jobsity.AdminRole},
+	}
+	cases := []struct {
+		name    string
+		userID  int
+		wantErr error
+	}{
+		{
+			name:    "Fail on other company",
+			userID:  4,
+			wantErr: chat.ErrOtherTenant,
+		},
+		{
+			name:    "Fail on other location",
+			userID:  2,
+			wantErr: chat.ErrOtherTenant,
+		},
+		{
+			name:   "Success on location user",
+			userID: 1,
+		},
+		{
+			name:   "Success on company admin",
+			userID: 3,
+		},
+		{
+			name:   "Success on admin",
+			userID: 5,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb := mockdb.NewRoom()
+			if _, err := rdb.Create(nil, jobsity.Room{Name: "paris", CompanyID: 1, LocationID: 1, Private: true}); err != nil {
+				t.Fatal(err)
+			}
+			s, err := chat.New(nil, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb, Users: mockdb.NewUser(users...)}),
+				broker.NewMemory(), nil, ws.NewHub(ws.Config{}), tenantRBAC(jobsity.CompanyAdminRole, 1, 2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.InviteMember(nil, "paris", jobsity.RoomMember{UserID: tt.userID})
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	cases := []struct {
+		name    string
+		user    string
+		userID  int
+		wantErr error
+	}{
+		{
+			name:    "Fail on non-member",
+			user:    "owner",
+			userID:  4,
+			wantErr: pgsql.ErrMemberNotFound,
+		},
+		{
+			name:    "Fail on member",
+			user:    "member",
+			userID:  2,
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on removing higher role",
+			user:    "moderator",
+			userID:  1,
+			wantErr: chat.ErrMemberTooHigh,
+		},
+		{
+			name:   "Success on self",
+			user:   "member",
+			userID: 3,
+		},
+		{
+			name:   "Success on moderator",
+			user:   "moderator",
+			userID: 3,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := ws.NewHub(ws.Config{})
+			s := newPrivateRoom(t, hub)
+			conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+			if err := s.JoinRoom(userCtx("member"), conn, "hr"); err != nil {
+				t.Fatal(err)
+			}
+			receive(t, peer, "Welcome to the hr chat room!")
+
+			assert.Equal(t, tt.wantErr, s.RemoveMember(userCtx(tt.user), "hr", tt.userID))
+			if tt.wantErr != nil {
+				return
+			}
+			// The removed member is evicted from the room
+			receive(t, peer, "You were removed from the hr chat room")
+			users, err := s.GetUsersInRoom(userCtx("admin"), "hr")
+			assert.Nil(t, err)
+			assert.Empty(t, users)
+			assert.Equal(t, chat.ErrPrivateRoom, s.JoinRoom(userCtx("member"), conn, "hr"))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// mentionRegexp matches @username and @room mentions, unless they follow a word like in email addresses
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@(\w+)`)
+
+// ListMentions returns a page of the current user's mentions with their messages, latest first. Mentions
+// of messages past the user's read cursor of their room are unread.
+func (s *Chat) ListMentions(c echo.Context, p jobsity.Pagination) ([]jobsity.Mention, error) {
+	user := s.rbac.User(c)
+	mentions, err := s.mdb.ListMentions(s.db, user.ID, p)
+	if err != nil || len(mentions) == 0 {
+		return mentions, err
+	}
+
+	var keys []string
+	lastRead := make(map[string]int)
+	for _, m := range mentions {
+		if _, ok := lastRead[m.Room]; !ok {
+			lastRead[m.Room] = 0
+			keys = append(keys, m.Room)
+		}
+	}
+	cursors, err := s.rcdb.List(s.db, user.ID, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cursors {
+		lastRead[keys[i]] = cursors[i].LastReadID
+	}
+
+	// Users are only mentioned in the rooms of their company and the global ones
+	prefix := strconv.Itoa(user.CompanyID) + ":"
+	for i := range mentions {
+		m := &mentions[i]
+		m.Unread = m.MessageID > lastRead[m.Room]
+		m.Room = strings.TrimPrefix(m.Room, prefix)
+		if m.Message != nil {
+			m.Message.Room = m.Room
+		}
+	}
+	return mentions, nil
+}
+
+// mentions returns the usernames the text mentions among the users who can read the room, in order, and
+// the mentions of the users to notify, the sender left out. Mentions of anyone else are left as they are.
+// @room mentions every member of a private room, and the users present in any other room.
+func (s *Chat) mentions(room jobsity.Room, sender jobsity.AuthUser, text string) ([]string, []jobsity.Mention, error) {
+	var usernames []string
+	var mentions []jobsity.Mention
+	notified := make(map[int]int)
+	notify := func(userID int, all bool) {
+		if userID == sender.ID {
+			return
+		}
+		if i, ok := notified[userID]; ok {
+			mentions[i].All = mentions[i].All && all
+			return
+		}
+		notified[userID] = len(mentions)
+		mentions = append(mentions, jobsity.Mention{UserID: userID, Room: room.Key(), All: all})
+	}
+
+	for _, username := range parseMentions(text) {
+		if username == jobsity.MentionRoom {
+			users, err := s.roomAudience(room)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, userID := range users {
+				notify(userID, true)
+			}
+			usernames = append(usernames, username)
+			continue
+		}
+
+		user, err := s.udb.FindByUsername(s.db, username)
+		if err == pgsql.ErrUserNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ok, err := s.readsRoom(room, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			notify(user.ID, false)
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames, mentions, nil
+}
+
+// notifyMentions saves the mentions of a message and sends them to the mentioned users, wherever they are connected
+func (s *Chat) notifyMentions(msg jobsity.Message, mentions []jobsity.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	for i := range mentions {
+		mentions[i].MessageID = msg.ID
+	}
+	if err := s.mdb.CreateMentions(s.db, mentions); err != nil {
+		return err
+	}
+	f := jobsity.MessageFrame(jobsity.FrameMention, msg)
+	for _, m := range mentions {
+		if err := s.sendToUser(m.UserID, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readsRoom reports whether the user can read the room, being in its company and location, and a member of
+// it if private
+func (s *Chat) readsRoom(room jobsity.Room, user jobsity.User) (bool, error) {
+	if room.CompanyID != 0 && user.CompanyID != room.CompanyID {
+		return false, nil
+	}
+	if room.LocationID != 0 && user.LocationID != room.LocationID {
+		return false, nil
+	}
+	if !room.Private {
+		return true, nil
+	}
+	_, err := s.rmdb.View(s.db, room.ID, user.ID)
+	if err == pgsql.ErrMemberNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// roomAudience returns the IDs of the users an @room mention notifies: the members of a private room, and
+// the users present in any other room, since everyone may read it
+func (s *Chat) roomAudience(room jobsity.Room) ([]int, error) {
+	var users []int
+	if room.Private {
+		members, err := s.rmdb.List(s.db, room.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			users = append(users, m.UserID)
+		}
+		return users, nil
+	}
+	for _, p := range s.presence.list(inRoom(room.Key())) {
+		users = append(users, p.UserID)
+	}
+	return users, nil
+}
+
+// parseMentions returns the usernames mentioned in the text, in order and once each
+func parseMentions(text string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		if username := match[1]; !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,122 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveMentioned receives the next two frames of a JSON connection, checking they are a message with the
+// mentions and the notification of the mention, in any order since they are sent separately
+func receiveMentioned(t *testing.T, conn *websocket.Conn, text string, mentions []string) jobsity.Frame {
+	frames := make(map[string]jobsity.Frame)
+	for i := 0; i < 2; i++ {
+		var f jobsity.Frame
+		if err := websocket.JSON.Receive(conn, &f); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, text, f.Payload.Text)
+		assert.Equal(t, mentions, f.Payload.Mentions)
+		frames[f.Type] = f
+	}
+	assert.Contains(t, frames, jobsity.FrameMessage)
+	assert.Contains(t, frames, jobsity.FrameMention)
+	return frames[jobsity.FrameMention]
+}
+
+func TestMentions(t *testing.T) {
+	rdb := mockdb.NewRoom()
+	if _, err := rdb.Create(nil, jobsity.Room{Name: "general"}); err != nil {
+		t.Fatal(err)
+	}
+	room, err := rdb.Create(nil, jobsity.Room{Name: "hr", Private: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rmdb := mockdb.NewMember()
+	for _, userID := range []int{1, 2, 3} {
+		if _, err := rmdb.Create(nil, jobsity.RoomMember{RoomID: room.ID, UserID: userID, Role: jobsity.RoomMemberRole}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var users []jobsity.User
+	for _, u := range members {
+		users = append(users, jobsity.User{Base: jobsity.Base{ID: u.ID}, Username: u.Username})
+	}
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb, Members: rmdb, Users: mockdb.NewUser(users...)}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, memberPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "hr"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the hr chat room!")
+	receiveFrame(t, memberPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+
+	// Mentions of the room's members are highlighted, and the others left as they are
+	text := "@member @moderator @outsider @nobody mail owner@example.com, cc @owner"
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "hr", text))
+	var f jobsity.Frame
+	assert.Nil(t, websocket.JSON.Receive(ownerPeer, &f))
+	assert.Equal(t, jobsity.FrameMessage, f.Type)
+	assert.Equal(t, []string{"member", "moderator", "owner"}, f.Payload.Mentions)
+
+	// Mentioned users are notified outside of the room, and the sender is not
+	assert.Nil(t, websocket.JSON.Receive(memberPeer, &f))
+	assert.Equal(t, jobsity.FrameMention, f.Type)
+	assert.Equal(t, "hr", f.Room)
+	assert.Equal(t, "owner", f.Sender)
+	assert.Equal(t, 1, f.ID)
+	assert.Equal(t, []string{"member", "moderator", "owner"}, f.Payload.Mentions)
+
+	// @room mentions every member of a private room
+	_, err = s.HandleFrame(userCtx("member"), member, "hr", []byte(`{"version":1,"type":"join"}`))
+	assert.Nil(t, err)
+	receiveFrame(t, memberPeer, jobsity.FrameMessage, text)
+	receiveFrame(t, memberPeer, jobsity.FrameSystem, "Welcome to the hr chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", "standup @room"))
+	receiveMentioned(t, ownerPeer, "standup @room", []string{jobsity.MentionRoom})
+	receiveFrame(t, memberPeer, jobsity.FrameMessage, "standup @room")
+
+	// and the users present in any other room
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, memberPeer, jobsity.FrameJoin, "owner")
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "@room lunch?"))
+	receiveMentioned(t, memberPeer, "@room lunch?", []string{jobsity.MentionRoom})
+
+	// Offline users find their mentions later, latest first, until they read the room
+	mentions, err := s.ListMentions(userCtx("moderator"), jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, mentions, 2) {
+		assert.Equal(t, 2, mentions[0].MessageID)
+		assert.True(t, mentions[0].All)
+		assert.True(t, mentions[0].Unread)
+		assert.Equal(t, "hr", mentions[0].Room)
+		assert.Equal(t, "standup @room", mentions[0].Message.Body)
+		assert.Equal(t, 1, mentions[1].MessageID)
+		assert.False(t, mentions[1].All)
+	}
+	_, err = s.MarkRead(userCtx("moderator"), "hr", 1)
+	assert.Nil(t, err)
+	mentions, err = s.ListMentions(userCtx("moderator"), jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, mentions, 2) {
+		assert.True(t, mentions[0].Unread)
+		assert.False(t, mentions[1].Unread)
+	}
+	mentions, err = s.ListMentions(userCtx("outsider"), jobsity.Pagination{})
+	assert.Nil(t, err)
+	assert.Empty(t, mentions)
+}
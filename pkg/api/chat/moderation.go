@@ -0,0 +1,319 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Custom errors
+var (
+	ErrBanned          = echo.NewHTTPError(http.StatusForbidden, "you are banned from this room")
+	ErrMuted           = echo.NewHTTPError(http.StatusForbidden, "you are muted in this room")
+	ErrSelfModeration  = echo.NewHTTPError(http.StatusBadRequest, "cannot moderate yourself")
+	ErrTargetTooHigh   = echo.NewHTTPError(http.StatusForbidden, "cannot moderate a member with a role higher than yours")
+	ErrInvalidDuration = echo.NewHTTPError(http.StatusBadRequest, "duration must be a positive time span like 30s, 10m or 2h")
+)
+
+// Moderation contains the user targeted by a moderation action, its reason and the duration of
+// the resulting sanction. Sanctions without duration are permanent.
+type Moderation struct {
+	UserID   int
+	Reason   string
+	Duration time.Duration
+}
+
+// KickUser removes a user from a room on every instance. The user may join again. Only room
+// owners and moderators can kick, and only users up to their own role.
+func (s *Chat) KickUser(c echo.Context, roomName string, req Moderation) error {
+	room, target, err := s.moderate(c, roomName, req)
+	if err != nil {
+		return err
+	}
+	if err := s.logModeration(room, jobsity.ActionKick, req, nil, s.rbac.User(c).ID); err != nil {
+		return err
+	}
+	return s.dismiss(c, room, target, "kicked", req)
+}
+
+// BanUser keeps a user from joining a room, either for a while or permanently, and removes the user
+// from it on every instance. Only room owners and moderators can ban, and only users up to their own role.
+func (s *Chat) BanUser(c echo.Context, roomName string, req Moderation) (jobsity.Sanction, error) {
+	room, target, err := s.moderate(c, roomName, req)
+	if err != nil {
+		return jobsity.Sanction{}, err
+	}
+	sanction, err := s.sanction(room, jobsity.SanctionBan, req, s.rbac.User(c).ID)
+	if err != nil {
+		return jobsity.Sanction{}, err
+	}
+	return sanction, s.dismiss(c, room, target, "banned", req)
+}
+
+// UnbanUser lifts the ban of a user from a room. Only room owners and moderators can unban.
+func (s *Chat) UnbanUser(c echo.Context, roomName string, userID int) error {
+	room, target, err := s.moderate(c, roomName, Moderation{UserID: userID})
+	if err != nil {
+		return err
+	}
+	ban, err := s.modb.ViewSanction(s.db, room.ID, userID, jobsity.SanctionBan)
+	if err != nil {
+		return err
+	}
+	if err := s.modb.DeleteSanction(s.db, ban); err != nil {
+		return err
+	}
+	if err := s.logModeration(room, jobsity.ActionUnban, Moderation{UserID: userID}, nil, s.rbac.User(c).ID); err != nil {
+		return err
+	}
+	return s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+		fmt.Sprintf("%s was unbanned by %s", target.Username, s.rbac.User(c).Username)), nil)
+}
+
+// MuteUser keeps a user from sending messages to a room for a while. Only room owners and moderators
+// can mute, and only users up to their own role.
+func (s *Chat) MuteUser(c echo.Context, roomName string, req Moderation) (jobsity.Sanction, error) {
+	if req.Duration <= 0 {
+		return jobsity.Sanction{}, ErrInvalidDuration
+	}
+	room, target, err := s.moderate(c, roomName, req)
+	if err != nil {
+		return jobsity.Sanction{}, err
+	}
+	sanction, err := s.sanction(room, jobsity.SanctionMute, req, s.rbac.User(c).ID)
+	if err != nil {
+		return jobsity.Sanction{}, err
+	}
+	return sanction, s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+		moderationNotice(target.Username+" was muted by "+s.rbac.User(c).Username, req)), nil)
+}
+
+// ListModerationLog returns a page of the moderation actions taken in a room, latest first. Only
+// admins of the room's company or location can list them.
+func (s *Chat) ListModerationLog(c echo.Context, roomName string, p jobsity.Pagination) ([]jobsity.ModerationLog, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return nil, err
+	}
+	if !s.isRoomAdmin(c, room) {
+		return nil, echo.ErrForbidden
+	}
+	return s.modb.ListLog(s.db, room.ID, p)
+}
+
+// moderate checks whether the current user may moderate the target user in a room, returning both
+func (s *Chat) moderate(c echo.Context, roomName string, req Moderation) (jobsity.Room, jobsity.User, error) {
+	if req.Duration < 0 {
+		return jobsity.Room{}, jobsity.User{}, ErrInvalidDuration
+	}
+	if req.UserID == s.rbac.User(c).ID {
+		return jobsity.Room{}, jobsity.User{}, ErrSelfModeration
+	}
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Room{}, jobsity.User{}, err
+	}
+	if room.Archived {
+		return jobsity.Room{}, jobsity.User{}, ErrRoomArchived
+	}
+	moderator, err := s.enforceRoomRole(c, room, jobsity.RoomModeratorRole)
+	if err != nil {
+		return jobsity.Room{}, jobsity.User{}, err
+	}
+
+	target, err := s.udb.View(s.db, req.UserID)
+	if err != nil {
+		return jobsity.Room{}, jobsity.User{}, err
+	}
+	// Admins of the room's tenant and users with a higher access role are out of reach, members or not
+	if administersRoom(target, room) || target.RoleID < s.rbac.User(c).Role {
+		return jobsity.Room{}, jobsity.User{}, ErrTargetTooHigh
+	}
+	member, err := s.rmdb.View(s.db, room.ID, req.UserID)
+	if err == nil && member.Role < moderator.Role {
+		return jobsity.Room{}, jobsity.User{}, ErrTargetTooHigh
+	}
+	if err != nil && err != pgsql.ErrMemberNotFound {
+		return jobsity.Room{}, jobsity.User{}, err
+	}
+	return room, target, nil
+}
+
+// sanction persists and logs a sanction of the target user in a room. Sanctions the chat imposes itself have no moderator.
+func (s *Chat) sanction(room jobsity.Room, kind jobsity.SanctionKind, req Moderation, moderatorID int) (jobsity.Sanction, error) {
+	var expiresAt *time.Time
+	if req.Duration > 0 {
+		t := time.Now().Add(req.Duration)
+		expiresAt = &t
+	}
+	sanction, err := s.modb.CreateSanction(s.db, jobsity.Sanction{
+		RoomID:    room.ID,
+		UserID:    req.UserID,
+		Kind:      kind,
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+		CreatedBy: moderatorID,
+	})
+	if err != nil {
+		return jobsity.Sanction{}, err
+	}
+	action := jobsity.ActionBan
+	if kind == jobsity.SanctionMute {
+		action = jobsity.ActionMute
+	}
+	return sanction, s.logModeration(room, action, req, expiresAt, moderatorID)
+}
+
+func (s *Chat) logModeration(room jobsity.Room, action jobsity.ModerationAction, req Moderation, expiresAt *time.Time, moderatorID int) error {
+	_, err := s.modb.CreateLog(s.db, jobsity.ModerationLog{
+		RoomID:      room.ID,
+		Action:      action,
+		UserID:      req.UserID,
+		ModeratorID: moderatorID,
+		Reason:      req.Reason,
+		ExpiresAt:   expiresAt,
+	})
+	return err
+}
+
+// dismiss removes the target user from a room on every instance and lets the room know why
+func (s *Chat) dismiss(c echo.Context, room jobsity.Room, target jobsity.User, verb string, req Moderation) error {
+	notice := moderationNotice("You were "+verb+" from the "+room.Name+" chat room", req)
+	s.evict(room.Key(), target.ID, notice)
+	if err := s.publish(roomTopicPrefix+room.Key(), event{Kind: eventEvict, Room: room.Key(), UserID: target.ID, Notice: notice}); err != nil {
+		return err
+	}
+	return s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+		moderationNotice(target.Username+" was "+verb+" by "+s.rbac.User(c).Username, req)), nil)
+}
+
+// enforceNotSanctioned checks whether the user is free of an unexpired sanction of the kind in a room
+func (s *Chat) enforceNotSanctioned(room jobsity.Room, userID int, kind jobsity.SanctionKind) error {
+	_, err := s.modb.ViewSanction(s.db, room.ID, userID, kind)
+	switch {
+	case err == pgsql.ErrSanctionNotFound:
+		return nil
+	case err != nil:
+		return err
+	case kind == jobsity.SanctionBan:
+		return ErrBanned
+	default:
+		return ErrMuted
+	}
+}
+
+// moderationNotice appends the duration and the reason of a moderation action to the notice
+func moderationNotice(notice string, req Moderation) string {
+	if req.Duration > 0 {
+		notice += " for " + formatDuration(req.Duration)
+	}
+	if req.Reason != "" {
+		notice += ": " + req.Reason
+	}
+	return notice
+}
+
+// formatDuration formats d without its trailing zero units, like 1h30m instead of 1h30m0s
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// moderationCommands returns the commands of room owners and moderators
+func (s *Chat) moderationCommands() []Command {
+	return []Command{
+		{
+			Name:        "kick",
+			Args:        "<user> [reason...]",
+			Description: "Kicks a user from the current room",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				req, err := s.moderationTarget(args[0], optionalArg(args, 1))
+				if err != nil {
+					return err
+				}
+				return s.KickUser(c, roomName, req)
+			},
+		},
+		{
+			Name:        "ban",
+			Args:        "<user> [reason...]",
+			Description: "Bans a user from the current room for good, or for a duration like 2h put before the reason",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				req, err := s.moderationTarget(args[0], optionalArg(args, 1))
+				if err != nil {
+					return err
+				}
+				// The duration is optional, so it is only taken when the reason starts with one
+				duration, reason, _ := strings.Cut(req.Reason, " ")
+				if d, err := time.ParseDuration(duration); err == nil {
+					req.Duration = d
+					req.Reason = strings.TrimLeft(reason, " ")
+				}
+				_, err = s.BanUser(c, roomName, req)
+				return err
+			},
+		},
+		{
+			Name:        "unban",
+			Args:        "<user>",
+			Description: "Lifts the ban of a user from the current room",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				req, err := s.moderationTarget(args[0], "")
+				if err != nil {
+					return err
+				}
+				return s.UnbanUser(c, roomName, req.UserID)
+			},
+		},
+		{
+			Name:        "mute",
+			Args:        "<user> <duration> [reason...]",
+			Description: "Mutes a user in the current room for a duration like 10m",
+			Role:        jobsity.UserRole,
+			Handler: func(c echo.Context, conn *websocket.Conn, roomName string, args []string) error {
+				req, err := s.moderationTarget(args[0], optionalArg(args, 2))
+				if err != nil {
+					return err
+				}
+				if req.Duration, err = time.ParseDuration(args[1]); err != nil {
+					return ErrInvalidDuration
+				}
+				_, err = s.MuteUser(c, roomName, req)
+				return err
+			},
+		},
+	}
+}
+
+// moderationTarget looks up the user targeted by a moderation command
+func (s *Chat) moderationTarget(username string, reason string) (Moderation, error) {
+	user, err := s.udb.FindByUsername(s.db, username)
+	if err != nil {
+		return Moderation{}, err
+	}
+	return Moderation{UserID: user.ID, Reason: reason}, nil
+}
+
+// optionalArg returns the i-th command argument, or an empty string when it was left out
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
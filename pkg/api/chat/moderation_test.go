@@ -0,0 +1,162 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+// joinPrivateRoom joins the user to the hr room of newPrivateRoom, returning its connection and peer
+func joinPrivateRoom(t *testing.T, s *chat.Chat, user string) (*websocket.Conn, *websocket.Conn) {
+	conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+	if err := s.JoinRoom(userCtx(user), conn, "hr"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, peer, "Welcome to the hr chat room!")
+	return conn, peer
+}
+
+func TestKickUser(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     string
+		req      chat.Moderation
+		wantErr  error
+		wantRecv string
+	}{
+		{
+			name:    "Fail on self",
+			user:    "moderator",
+			req:     chat.Moderation{UserID: 2},
+			wantErr: chat.ErrSelfModeration,
+		},
+		{
+			name:    "Fail on member",
+			user:    "member",
+			req:     chat.Moderation{UserID: 4},
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on higher role",
+			user:    "moderator",
+			req:     chat.Moderation{UserID: 1},
+			wantErr: chat.ErrTargetTooHigh,
+		},
+		{
+			name:    "Fail on room admin out of the room",
+			user:    "owner",
+			req:     chat.Moderation{UserID: 5},
+			wantErr: chat.ErrTargetTooHigh,
+		},
+		{
+			name:    "Fail on unknown user",
+			user:    "moderator",
+			req:     chat.Moderation{UserID: 99},
+			wantErr: pgsql.ErrUserNotFound,
+		},
+		{
+			name:     "Success",
+			user:     "moderator",
+			req:      chat.Moderation{UserID: 3, Reason: "spam"},
+			wantRecv: "You were kicked from the hr chat room: spam",
+		},
+		{
+			name:     "Success on admin",
+			user:     "admin",
+			req:      chat.Moderation{UserID: 3},
+			wantRecv: "You were kicked from the hr chat room",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+			conn, peer := joinPrivateRoom(t, s, "member")
+
+			assert.Equal(t, tt.wantErr, s.KickUser(userCtx(tt.user), "hr", tt.req))
+			if tt.wantErr != nil {
+				return
+			}
+			receive(t, peer, tt.wantRecv)
+			// Kicked users may join again
+			assert.Nil(t, s.JoinRoom(userCtx("member"), conn, "hr"))
+		})
+	}
+}
+
+func TestBanUser(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	moderator, moderatorPeer := joinPrivateRoom(t, s, "moderator")
+	member, memberPeer := joinPrivateRoom(t, s, "member")
+	receive(t, moderatorPeer, "member joined the room")
+
+	assert.Equal(t, pgsql.ErrSanctionNotFound, s.UnbanUser(userCtx("moderator"), "hr", 3))
+	_, err := s.BanUser(userCtx("moderator"), "hr", chat.Moderation{UserID: 3, Duration: -time.Hour})
+	assert.Equal(t, chat.ErrInvalidDuration, err)
+
+	assert.Nil(t, s.HandleCommand(userCtx("moderator"), moderator, "hr", "/ban member 1h  flooding the room"))
+	receive(t, memberPeer, "You were banned from the hr chat room for 1h: flooding the room")
+	receive(t, moderatorPeer, "member left the room", "member was banned by moderator for 1h: flooding the room")
+	assert.Equal(t, chat.ErrBanned, s.JoinRoom(userCtx("member"), member, "hr"))
+
+	assert.Nil(t, s.HandleCommand(userCtx("moderator"), moderator, "hr", "/unban member"))
+	receive(t, moderatorPeer, "member was unbanned by moderator")
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "hr"))
+	receive(t, memberPeer, "Welcome to the hr chat room!")
+
+	// Bans without duration are permanent, and the reason is optional
+	ban, err := s.BanUser(userCtx("owner"), "hr", chat.Moderation{UserID: 3})
+	assert.Nil(t, err)
+	assert.Nil(t, ban.ExpiresAt)
+	assert.Equal(t, jobsity.SanctionBan, ban.Kind)
+	receive(t, memberPeer, "You were banned from the hr chat room")
+
+	_, err = s.ListModerationLog(userCtx("owner"), "hr", jobsity.Pagination{Limit: 10})
+	assert.Equal(t, echo.ErrForbidden, err)
+	log, err := s.ListModerationLog(userCtx("admin"), "hr", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, log, 3) {
+		assert.Equal(t, jobsity.ActionBan, log[0].Action)
+		assert.Equal(t, 1, log[0].ModeratorID)
+		assert.Equal(t, jobsity.ActionUnban, log[1].Action)
+		assert.Equal(t, jobsity.ActionBan, log[2].Action)
+		assert.Equal(t, "flooding the room", log[2].Reason)
+		assert.NotNil(t, log[2].ExpiresAt)
+	}
+}
+
+func TestMuteUser(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	moderator, moderatorPeer := joinPrivateRoom(t, s, "moderator")
+	member, memberPeer := joinPrivateRoom(t, s, "member")
+	receive(t, moderatorPeer, "member joined the room")
+
+	assert.Equal(t, echo.NewHTTPError(400, "usage: /mute <user> <duration> [reason...]"),
+		s.HandleCommand(userCtx("moderator"), moderator, "hr", "/mute member"))
+	assert.Equal(t, chat.ErrInvalidDuration, s.HandleCommand(userCtx("moderator"), moderator, "hr", "/mute member soon"))
+	assert.Equal(t, pgsql.ErrUserNotFound, s.HandleCommand(userCtx("moderator"), moderator, "hr", "/mute nobody 10m"))
+	_, err := s.MuteUser(userCtx("moderator"), "hr", chat.Moderation{UserID: 3})
+	assert.Equal(t, chat.ErrInvalidDuration, err)
+
+	assert.Nil(t, s.HandleCommand(userCtx("moderator"), moderator, "hr", "/mute member 10m calm down"))
+	receive(t, memberPeer, "member was muted by moderator for 10m: calm down")
+	assert.Equal(t, chat.ErrMuted, s.SendMessage(userCtx("member"), member, "hr", "hello?"))
+	assert.Equal(t, chat.ErrMuted, s.SendMessage(userCtx("member"), member, "hr", "/stock=aapl.us"))
+
+	// Mutes expire
+	mute, err := s.MuteUser(userCtx("owner"), "hr", chat.Moderation{UserID: 3, Duration: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.SanctionMute, mute.Kind)
+	receive(t, memberPeer, "member was muted by owner for 50ms")
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", "hello"))
+	receive(t, moderatorPeer, "member was muted by moderator for 10m: calm down", "member was muted by owner for 50ms", "member: hello")
+}
@@ -0,0 +1,93 @@
+package pgsql
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Direct represents the client for conversations table
+type Direct struct{}
+
+// ConversationIndex makes conversations unique per user and peer, among the conversations not deleted
+const ConversationIndex = `CREATE UNIQUE INDEX IF NOT EXISTS conversations_user_id_peer_id_key ON conversations (user_id, peer_id) WHERE deleted_at IS NULL`
+
+// Touch records a message between two users, starting the conversation on both sides on the first message
+func (d Direct) Touch(db orm.DB, userID, peerID int) error {
+	for _, c := range []jobsity.Conversation{{UserID: userID, PeerID: peerID}, {UserID: peerID, PeerID: userID}} {
+		res, err := db.Model(&c).Set("updated_at = ?", time.Now()).Where("user_id = ?", c.UserID).
+			Where("peer_id = ?", c.PeerID).Where("deleted_at is null").Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() > 0 {
+			continue
+		}
+		// A conversation started concurrently is caught by ConversationIndex, and is kept
+		if _, err := db.Model(&c).OnConflict("DO NOTHING").Insert(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conversationStats holds the latest message of a conversation and the number of messages unread by the user
+type conversationStats struct {
+	jobsity.Message
+	Conversation string
+	Unread       int
+}
+
+// conversationStatsQuery fetches the stats of conversations by their keys and last read message IDs at once
+const conversationStatsQuery = `SELECT c.key AS conversation, unread.count AS unread, last.*
+FROM unnest(?::text[], ?::bigint[]) AS c(key, last_read_id)
+LEFT JOIN LATERAL (
+	SELECT * FROM messages WHERE room = c.key AND deleted_at IS NULL ORDER BY id DESC LIMIT 1
+) AS last ON true
+CROSS JOIN LATERAL (
+	SELECT count(*) FROM messages WHERE room = c.key AND id > c.last_read_id AND user_id != ? AND deleted_at IS NULL
+) AS unread`
+
+// List returns a page of user's conversations with their peers, latest message and unread count, latest first
+func (d Direct) List(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Conversation, error) {
+	var convs []jobsity.Conversation
+	err := db.Model(&convs).Relation("Peer").Where("conversation.user_id = ?", userID).
+		Where("conversation.deleted_at is null").Order("conversation.updated_at desc").
+		Limit(p.Limit).Offset(p.Offset).Select()
+	if err != nil || len(convs) == 0 {
+		return convs, err
+	}
+
+	keys := make([]string, len(convs))
+	lastRead := make([]int, len(convs))
+	for i, c := range convs {
+		keys[i], lastRead[i] = c.Key(), c.LastReadID
+	}
+	var stats []conversationStats
+	if _, err := db.Query(&stats, conversationStatsQuery, pg.Array(keys), pg.Array(lastRead), userID); err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]conversationStats, len(stats))
+	for _, s := range stats {
+		byKey[s.Conversation] = s
+	}
+	for i := range convs {
+		s := byKey[convs[i].Key()]
+		convs[i].Unread = s.Unread
+		if s.ID != 0 {
+			last := s.Message
+			convs[i].LastMessage = &last
+		}
+	}
+	return convs, nil
+}
+
+// MarkRead marks user's side of a conversation read up to a message
+func (d Direct) MarkRead(db orm.DB, userID, peerID, messageID int) error {
+	_, err := db.Model((*jobsity.Conversation)(nil)).Set("last_read_id = greatest(last_read_id, ?)", messageID).
+		Where("user_id = ?", userID).Where("peer_id = ?", peerID).Where("deleted_at is null").Update()
+	return err
+}
@@ -0,0 +1,75 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestDirect(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Role{}, &jobsity.User{}, &jobsity.Message{}, &jobsity.Conversation{})
+	if _, err := db.Exec(pgsql.ConversationIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Role{ID: jobsity.UserRole, AccessLevel: jobsity.UserRole, Name: "USER"},
+		&jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe", RoleID: jobsity.UserRole},
+		&jobsity.User{Base: jobsity.Base{ID: 2}, Username: "janedoe", RoleID: jobsity.UserRole},
+		&jobsity.User{Base: jobsity.Base{ID: 3}, Username: "joe", RoleID: jobsity.UserRole},
+	); err != nil {
+		t.Error(err)
+	}
+
+	ddb := pgsql.Direct{}
+	mdb := pgsql.Message{}
+
+	// Conversations start on both sides with the first message
+	msg, err := mdb.Create(db, jobsity.Message{Room: jobsity.DirectKey(1, 2), UserID: 1, Username: "johndoe", Body: "hi"})
+	assert.Nil(t, err)
+	assert.Nil(t, ddb.Touch(db, 1, 2))
+	assert.Nil(t, ddb.MarkRead(db, 1, 2, msg.ID))
+	_, err = mdb.Create(db, jobsity.Message{Room: jobsity.DirectKey(3, 1), UserID: 3, Username: "joe", Body: "hey"})
+	assert.Nil(t, err)
+	assert.Nil(t, ddb.Touch(db, 3, 1))
+
+	convs, err := ddb.List(db, 1, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, convs, 2) {
+		assert.Equal(t, "joe", convs[0].Peer.Username)
+		assert.Equal(t, "hey", convs[0].LastMessage.Body)
+		assert.Equal(t, 1, convs[0].Unread)
+		assert.Equal(t, "janedoe", convs[1].Peer.Username)
+		assert.Equal(t, 0, convs[1].Unread)
+	}
+
+	convs, err = ddb.List(db, 2, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, convs, 1) {
+		assert.Equal(t, 1, convs[0].Unread)
+	}
+
+	// Reads never go back
+	assert.Nil(t, ddb.MarkRead(db, 2, 1, msg.ID))
+	assert.Nil(t, ddb.MarkRead(db, 2, 1, 0))
+	convs, err = ddb.List(db, 2, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, convs, 1) {
+		assert.Equal(t, msg.ID, convs[0].LastReadID)
+		assert.Equal(t, 0, convs[0].Unread)
+	}
+
+	// Users have a single conversation per peer
+	err = db.Insert(&jobsity.Conversation{UserID: 1, PeerID: 2})
+	if pgErr, ok := err.(pg.Error); assert.True(t, ok) {
+		assert.True(t, pgErr.IntegrityViolation())
+	}
+}
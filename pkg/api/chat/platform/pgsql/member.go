@@ -0,0 +1,59 @@
+package pgsql
+
+import (
+	"net/http"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Member represents the client for room_members table
+type Member struct{}
+
+// Custom errors
+var (
+	ErrMemberNotFound = echo.NewHTTPError(http.StatusNotFound, "user is not a member of the room")
+	ErrAlreadyMember  = echo.NewHTTPError(http.StatusConflict, "user is already a member of the room")
+)
+
+// Create adds a member to a room on database
+func (m Member) Create(db orm.DB, member jobsity.RoomMember) (jobsity.RoomMember, error) {
+	exists, err := db.Model((*jobsity.RoomMember)(nil)).Where("room_id = ?", member.RoomID).
+		Where("user_id = ?", member.UserID).Where("deleted_at is null").Exists()
+	if err != nil {
+		return jobsity.RoomMember{}, err
+	}
+	if exists {
+		return jobsity.RoomMember{}, ErrAlreadyMember
+	}
+
+	err = db.Insert(&member)
+	return member, err
+}
+
+// View returns the membership of a user in a room
+func (m Member) View(db orm.DB, roomID, userID int) (jobsity.RoomMember, error) {
+	var member jobsity.RoomMember
+	err := db.Model(&member).Where("room_id = ?", roomID).Where("user_id = ?", userID).
+		Where("room_member.deleted_at is null").Select()
+	if err == pg.ErrNoRows {
+		return member, ErrMemberNotFound
+	}
+	return member, err
+}
+
+// List returns members of a room with their users, ordered by role
+func (m Member) List(db orm.DB, roomID int) ([]jobsity.RoomMember, error) {
+	var members []jobsity.RoomMember
+	err := db.Model(&members).Relation("User").Where("room_id = ?", roomID).
+		Where("room_member.deleted_at is null").Order("room_member.role", "room_member.id").Select()
+	return members, err
+}
+
+// Delete sets deleted_at for a membership
+func (m Member) Delete(db orm.DB, member jobsity.RoomMember) error {
+	return db.Delete(&member)
+}
@@ -0,0 +1,51 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestMember(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Role{}, &jobsity.User{}, &jobsity.Room{}, &jobsity.RoomMember{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Role{ID: jobsity.UserRole, AccessLevel: jobsity.UserRole, Name: "USER"},
+		&jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe", RoleID: jobsity.UserRole},
+		&jobsity.User{Base: jobsity.Base{ID: 2}, Username: "janedoe", RoleID: jobsity.UserRole},
+		&jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "hr", Private: true},
+		&jobsity.RoomMember{Base: jobsity.Base{ID: 1}, RoomID: 1, UserID: 1, Role: jobsity.RoomOwnerRole},
+	); err != nil {
+		t.Error(err)
+	}
+
+	rmdb := pgsql.Member{}
+
+	_, err := rmdb.Create(db, jobsity.RoomMember{RoomID: 1, UserID: 1, Role: jobsity.RoomMemberRole})
+	assert.Equal(t, pgsql.ErrAlreadyMember, err)
+
+	member, err := rmdb.Create(db, jobsity.RoomMember{RoomID: 1, UserID: 2, Role: jobsity.RoomMemberRole})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, member.ID)
+
+	members, err := rmdb.List(db, 1)
+	assert.Nil(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, jobsity.RoomOwnerRole, members[0].Role)
+		assert.Equal(t, "johndoe", members[0].User.Username)
+		assert.Equal(t, "janedoe", members[1].User.Username)
+	}
+
+	member, err = rmdb.View(db, 1, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, rmdb.Delete(db, member))
+	_, err = rmdb.View(db, 1, 2)
+	assert.Equal(t, pgsql.ErrMemberNotFound, err)
+}
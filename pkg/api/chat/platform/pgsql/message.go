@@ -0,0 +1,189 @@
+package pgsql
+
+import (
+	"net/http"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Message represents the client for message table
+type Message struct{}
+
+// Custom errors
+var (
+	ErrMessageNotFound = echo.NewHTTPError(http.StatusNotFound, "message not found")
+)
+
+// ReactionIndex makes the reactions of a user to a message unique by emoji, among the reactions not deleted
+const ReactionIndex = `CREATE UNIQUE INDEX IF NOT EXISTS reactions_message_id_user_id_emoji_key ON reactions (message_id, user_id, emoji) WHERE deleted_at IS NULL`
+
+// Create creates a new message on database
+func (m Message) Create(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+	err := db.Insert(&msg)
+	return msg, err
+}
+
+// View returns single message by ID, with its reply count and reactions
+func (m Message) View(db orm.DB, id int) (jobsity.Message, error) {
+	var msg jobsity.Message
+	err := db.Model(&msg).Where("id = ?", id).Where("deleted_at is null").Select()
+	if err == pg.ErrNoRows {
+		return msg, ErrMessageNotFound
+	}
+	if err != nil {
+		return msg, err
+	}
+	msgs := []jobsity.Message{msg}
+	if err := countReplies(db, msgs); err != nil {
+		return msg, err
+	}
+	err = setReactions(db, msgs)
+	return msgs[0], err
+}
+
+// List returns a page of room's latest messages, ordered by timestamp from oldest to newest, with their
+// reply counts and reactions. Replies are left out, and listed with ListReplies.
+func (m Message) List(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+	var msgs []jobsity.Message
+	err := db.Model(&msgs).Where("room = ?", room).Where("parent_id is null").Where("deleted_at is null").
+		Order("created_at desc", "id desc").Limit(p.Limit).Offset(p.Offset).Select()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	if err := countReplies(db, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, setReactions(db, msgs)
+}
+
+// ListReplies returns a page of the replies to a message, ordered by timestamp from oldest to newest, with
+// their reactions
+func (m Message) ListReplies(db orm.DB, parentID int, p jobsity.Pagination) ([]jobsity.Message, error) {
+	var msgs []jobsity.Message
+	err := db.Model(&msgs).Where("parent_id = ?", parentID).Where("deleted_at is null").
+		Order("created_at", "id").Limit(p.Limit).Offset(p.Offset).Select()
+	if err != nil {
+		return nil, err
+	}
+	return msgs, setReactions(db, msgs)
+}
+
+// countReplies sets the reply count of each message
+func countReplies(db orm.DB, msgs []jobsity.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.ID
+	}
+	var counts []struct {
+		ParentID int
+		Count    int
+	}
+	err := db.Model((*jobsity.Message)(nil)).Column("parent_id").ColumnExpr("count(*) as count").
+		Where("parent_id in (?)", pg.In(ids)).Where("deleted_at is null").Group("parent_id").Select(&counts)
+	if err != nil {
+		return err
+	}
+	replies := make(map[int]int, len(counts))
+	for _, c := range counts {
+		replies[c.ParentID] = c.Count
+	}
+	for i := range msgs {
+		msgs[i].Replies = replies[msgs[i].ID]
+	}
+	return nil
+}
+
+// setReactions sets the reactions of each message, counted by emoji
+func setReactions(db orm.DB, msgs []jobsity.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.ID
+	}
+	var reactions []jobsity.Reaction
+	err := db.Model(&reactions).Where("message_id in (?)", pg.In(ids)).Where("deleted_at is null").
+		Order("id").Select()
+	if err != nil {
+		return err
+	}
+	byMessage := make(map[int][]jobsity.Reaction)
+	for _, r := range reactions {
+		byMessage[r.MessageID] = append(byMessage[r.MessageID], r)
+	}
+	for i := range msgs {
+		msgs[i].Reactions = jobsity.CountReactions(byMessage[msgs[i].ID])
+	}
+	return nil
+}
+
+// Update updates message's body and edit time
+func (m Message) Update(db orm.DB, msg jobsity.Message) error {
+	_, err := db.Model(&msg).Column("body", "mentions", "edited_at", "updated_at").WherePK().Update()
+	return err
+}
+
+// Delete sets deleted_at for a message
+func (m Message) Delete(db orm.DB, msg jobsity.Message) error {
+	return db.Delete(&msg)
+}
+
+// CreateEdit adds a previous version of a message to its edit history
+func (m Message) CreateEdit(db orm.DB, edit jobsity.MessageEdit) (jobsity.MessageEdit, error) {
+	err := db.Insert(&edit)
+	return edit, err
+}
+
+// ListEdits returns the edit history of a message, from its first version to the latest replaced one
+func (m Message) ListEdits(db orm.DB, messageID int) ([]jobsity.MessageEdit, error) {
+	var edits []jobsity.MessageEdit
+	err := db.Model(&edits).Where("message_id = ?", messageID).Where("deleted_at is null").
+		Order("id").Select()
+	return edits, err
+}
+
+// ToggleReaction adds the user's reaction to a message, or removes it if the user already reacted with the
+// same emoji. It reports whether the reaction was added.
+func (m Message) ToggleReaction(db orm.DB, r jobsity.Reaction) (bool, error) {
+	var existing jobsity.Reaction
+	err := db.Model(&existing).Where("message_id = ?", r.MessageID).Where("user_id = ?", r.UserID).
+		Where("emoji = ?", r.Emoji).Where("deleted_at is null").Select()
+	switch err {
+	case nil:
+		return false, db.Delete(&existing)
+	case pg.ErrNoRows:
+		// The same reaction added concurrently is caught by ReactionIndex, and is kept
+		_, err := db.Model(&r).OnConflict("DO NOTHING").Insert()
+		return true, err
+	default:
+		return false, err
+	}
+}
+
+// CreateMentions adds the mentions of a message
+func (m Message) CreateMentions(db orm.DB, mentions []jobsity.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	return db.Insert(&mentions)
+}
+
+// ListMentions returns a page of the user's mentions with their messages, latest first
+func (m Message) ListMentions(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Mention, error) {
+	var mentions []jobsity.Mention
+	err := db.Model(&mentions).Relation("Message").Where("mention.user_id = ?", userID).
+		Where("mention.deleted_at is null").Where("message.deleted_at is null").
+		Order("mention.id desc").Limit(p.Limit).Offset(p.Offset).Select()
+	return mentions, err
+}
@@ -0,0 +1,294 @@
+package pgsql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestCreate(t *testing.T) {
+	cases := []struct {
+		name     string
+		wantErr  bool
+		req      jobsity.Message
+		wantData jobsity.Message
+	}{
+		{
+			name:    "Fail on insert duplicate ID",
+			wantErr: true,
+			req: jobsity.Message{
+				Base: jobsity.Base{ID: 1},
+				Room: "general",
+				Body: "duplicate",
+			},
+		},
+		{
+			name: "Success",
+			req: jobsity.Message{
+				Room:     "general",
+				UserID:   1,
+				Username: "johndoe",
+				Body:     "hello",
+			},
+			wantData: jobsity.Message{
+				Base:     jobsity.Base{ID: 2},
+				Room:     "general",
+				UserID:   1,
+				Username: "johndoe",
+				Body:     "hello",
+			},
+		},
+	}
+
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.Reaction{})
+
+	if err := mock.InsertMultiple(db, &jobsity.Message{
+		Base: jobsity.Base{ID: 1},
+		Room: "general",
+		Body: "first",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	mdb := pgsql.Message{}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := mdb.Create(db, tt.req)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantData.ID != 0 {
+				tt.wantData.CreatedAt = resp.CreatedAt
+				tt.wantData.UpdatedAt = resp.UpdatedAt
+				assert.Equal(t, tt.wantData, resp)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	cases := []struct {
+		name     string
+		wantErr  bool
+		room     string
+		pg       jobsity.Pagination
+		wantData []jobsity.Message
+	}{
+		{
+			name:    "Invalid pagination values",
+			wantErr: true,
+			room:    "general",
+			pg:      jobsity.Pagination{Limit: -100},
+		},
+		{
+			name: "Success",
+			room: "general",
+			pg:   jobsity.Pagination{Limit: 2},
+			wantData: []jobsity.Message{
+				{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "second"},
+				{Base: jobsity.Base{ID: 4}, Room: "general", UserID: 1, Username: "johndoe", Body: "third"},
+			},
+		},
+	}
+
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.Reaction{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "first"},
+		&jobsity.Message{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "second"},
+		&jobsity.Message{Base: jobsity.Base{ID: 3}, Room: "random", UserID: 2, Username: "janedoe", Body: "elsewhere"},
+		&jobsity.Message{Base: jobsity.Base{ID: 4}, Room: "general", UserID: 1, Username: "johndoe", Body: "third"},
+	); err != nil {
+		t.Error(err)
+	}
+
+	mdb := pgsql.Message{}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := mdb.List(db, tt.room, tt.pg)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantData != nil {
+				for i, v := range msgs {
+					tt.wantData[i].CreatedAt = v.CreatedAt
+					tt.wantData[i].UpdatedAt = v.UpdatedAt
+				}
+				assert.Equal(t, tt.wantData, msgs)
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.MessageEdit{}, &jobsity.Reaction{})
+
+	mdb := pgsql.Message{}
+	msg, err := mdb.Create(db, jobsity.Message{Room: "general", UserID: 1, Username: "johndoe", Body: "helo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edits keep the previous versions of the message
+	for _, body := range []string{"hello", "hello!"} {
+		_, err := mdb.CreateEdit(db, jobsity.MessageEdit{MessageID: msg.ID, Body: msg.Body, EditedBy: 1})
+		assert.Nil(t, err)
+		now := time.Now()
+		msg.Body = body
+		msg.EditedAt = &now
+		assert.Nil(t, mdb.Update(db, msg))
+	}
+	view, err := mdb.View(db, msg.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello!", view.Body)
+	assert.NotNil(t, view.EditedAt)
+	edits, err := mdb.ListEdits(db, msg.ID)
+	assert.Nil(t, err)
+	if assert.Len(t, edits, 2) {
+		assert.Equal(t, "helo", edits[0].Body)
+		assert.Equal(t, "hello", edits[1].Body)
+	}
+
+	// Deleted messages are gone
+	assert.Nil(t, mdb.Delete(db, view))
+	_, err = mdb.View(db, msg.ID)
+	assert.Equal(t, pgsql.ErrMessageNotFound, err)
+	msgs, err := mdb.List(db, "general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestThread(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.Reaction{})
+
+	mdb := pgsql.Message{}
+	parent, err := mdb.Create(db, jobsity.Message{Room: "general", UserID: 1, Username: "johndoe", Body: "lunch?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"sure", "great"} {
+		if _, err := mdb.Create(db, jobsity.Message{Room: "general", UserID: 2, Username: "janedoe", Body: body, ParentID: parent.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Histories leave the replies out, counting them on their parent
+	msgs, err := mdb.List(db, "general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, 2, msgs[0].Replies)
+	}
+	view, err := mdb.View(db, parent.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, view.Replies)
+
+	replies, err := mdb.ListReplies(db, parent.ID, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, replies, 2) {
+		assert.Equal(t, "sure", replies[0].Body)
+		assert.Equal(t, parent.ID, replies[1].ParentID)
+	}
+}
+
+func TestReaction(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.Reaction{})
+	if _, err := db.Exec(pgsql.ReactionIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	mdb := pgsql.Message{}
+	msg, err := mdb.Create(db, jobsity.Message{Room: "general", UserID: 1, Username: "johndoe", Body: "lunch?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reactions := []jobsity.Reaction{
+		{MessageID: msg.ID, UserID: 2, Username: "janedoe", Emoji: "👍"},
+		{MessageID: msg.ID, UserID: 1, Username: "johndoe", Emoji: "🍕"},
+		{MessageID: msg.ID, UserID: 1, Username: "johndoe", Emoji: "👍"},
+	}
+	for _, r := range reactions {
+		added, err := mdb.ToggleReaction(db, r)
+		assert.Nil(t, err)
+		assert.True(t, added)
+	}
+
+	// Reactions are counted by emoji, in the order the emojis were first used
+	msgs, err := mdb.List(db, "general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, []jobsity.ReactionCount{
+			{Emoji: "👍", Count: 2, Users: []string{"janedoe", "johndoe"}},
+			{Emoji: "🍕", Count: 1, Users: []string{"johndoe"}},
+		}, msgs[0].Reactions)
+	}
+
+	// Reacting again with the same emoji removes the reaction
+	added, err := mdb.ToggleReaction(db, reactions[0])
+	assert.Nil(t, err)
+	assert.False(t, added)
+	view, err := mdb.View(db, msg.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.ReactionCount{
+		{Emoji: "🍕", Count: 1, Users: []string{"johndoe"}},
+		{Emoji: "👍", Count: 1, Users: []string{"johndoe"}},
+	}, view.Reactions)
+
+	// Users react once with each emoji
+	err = db.Insert(&jobsity.Reaction{MessageID: msg.ID, UserID: 1, Username: "johndoe", Emoji: "🍕"})
+	if pgErr, ok := err.(pg.Error); assert.True(t, ok) {
+		assert.True(t, pgErr.IntegrityViolation())
+	}
+}
+
+func TestMention(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.Mention{})
+
+	mdb := pgsql.Message{}
+	var msgs []jobsity.Message
+	mentions := [][]string{{"janedoe"}, {jobsity.MentionRoom}}
+	for i, body := range []string{"@janedoe lunch?", "@room standup"} {
+		msg, err := mdb.Create(db, jobsity.Message{Room: "1:general", UserID: 1, Username: "johndoe", Body: body, Mentions: mentions[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	assert.Nil(t, mdb.CreateMentions(db, nil))
+	assert.Nil(t, mdb.CreateMentions(db, []jobsity.Mention{
+		{MessageID: msgs[0].ID, UserID: 2, Room: "1:general"},
+		{MessageID: msgs[1].ID, UserID: 2, Room: "1:general", All: true},
+		{MessageID: msgs[1].ID, UserID: 3, Room: "1:general", All: true},
+	}))
+	assert.Nil(t, mdb.Delete(db, msgs[1]))
+
+	// Mentions of deleted messages are left out
+	list, err := mdb.ListMentions(db, 2, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, list, 1) {
+		assert.False(t, list[0].All)
+		assert.Equal(t, "@janedoe lunch?", list[0].Message.Body)
+		assert.Equal(t, []string{"janedoe"}, list[0].Message.Mentions)
+	}
+}
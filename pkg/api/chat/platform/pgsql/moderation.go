@@ -0,0 +1,62 @@
+package pgsql
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Moderation represents the client for sanctions and moderation_logs tables
+type Moderation struct{}
+
+// Custom errors
+var (
+	ErrSanctionNotFound = echo.NewHTTPError(http.StatusNotFound, "sanction not found")
+)
+
+// CreateSanction sanctions a user in a room on database, replacing the user's active sanction of the same kind
+func (m Moderation) CreateSanction(db orm.DB, s jobsity.Sanction) (jobsity.Sanction, error) {
+	if _, err := db.Model((*jobsity.Sanction)(nil)).Where("room_id = ?", s.RoomID).Where("user_id = ?", s.UserID).
+		Where("kind = ?", s.Kind).Where("deleted_at is null").Delete(); err != nil {
+		return jobsity.Sanction{}, err
+	}
+
+	err := db.Insert(&s)
+	return s, err
+}
+
+// ViewSanction returns the unexpired sanction of a kind of a user in a room
+func (m Moderation) ViewSanction(db orm.DB, roomID, userID int, kind jobsity.SanctionKind) (jobsity.Sanction, error) {
+	var s jobsity.Sanction
+	err := db.Model(&s).Where("room_id = ?", roomID).Where("user_id = ?", userID).Where("kind = ?", kind).
+		Where("expires_at is null or expires_at > ?", time.Now()).Where("deleted_at is null").
+		Order("id desc").Limit(1).Select()
+	if err == pg.ErrNoRows {
+		return s, ErrSanctionNotFound
+	}
+	return s, err
+}
+
+// DeleteSanction sets deleted_at for a sanction
+func (m Moderation) DeleteSanction(db orm.DB, s jobsity.Sanction) error {
+	return db.Delete(&s)
+}
+
+// CreateLog records a moderation action on database
+func (m Moderation) CreateLog(db orm.DB, l jobsity.ModerationLog) (jobsity.ModerationLog, error) {
+	err := db.Insert(&l)
+	return l, err
+}
+
+// ListLog returns a page of the moderation actions taken in a room, latest first
+func (m Moderation) ListLog(db orm.DB, roomID int, p jobsity.Pagination) ([]jobsity.ModerationLog, error) {
+	var logs []jobsity.ModerationLog
+	err := db.Model(&logs).Where("room_id = ?", roomID).Where("deleted_at is null").
+		Order("id desc").Limit(p.Limit).Offset(p.Offset).Select()
+	return logs, err
+}
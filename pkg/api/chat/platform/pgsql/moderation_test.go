@@ -0,0 +1,64 @@
+package pgsql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestModeration(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Sanction{}, &jobsity.ModerationLog{})
+
+	modb := pgsql.Moderation{}
+
+	_, err := modb.ViewSanction(db, 1, 2, jobsity.SanctionBan)
+	assert.Equal(t, pgsql.ErrSanctionNotFound, err)
+
+	// Expired sanctions are ignored
+	expired := time.Now().Add(-time.Minute)
+	_, err = modb.CreateSanction(db, jobsity.Sanction{RoomID: 1, UserID: 2, Kind: jobsity.SanctionMute, ExpiresAt: &expired})
+	assert.Nil(t, err)
+	_, err = modb.ViewSanction(db, 1, 2, jobsity.SanctionMute)
+	assert.Equal(t, pgsql.ErrSanctionNotFound, err)
+
+	ban, err := modb.CreateSanction(db, jobsity.Sanction{RoomID: 1, UserID: 2, Kind: jobsity.SanctionBan, Reason: "spam", CreatedBy: 1})
+	assert.Nil(t, err)
+	sanction, err := modb.ViewSanction(db, 1, 2, jobsity.SanctionBan)
+	assert.Nil(t, err)
+	assert.Equal(t, "spam", sanction.Reason)
+
+	// A new sanction replaces the active one of the same kind
+	expires := time.Now().Add(time.Hour)
+	_, err = modb.CreateSanction(db, jobsity.Sanction{RoomID: 1, UserID: 2, Kind: jobsity.SanctionBan, Reason: "flood", ExpiresAt: &expires, CreatedBy: 1})
+	assert.Nil(t, err)
+	sanction, err = modb.ViewSanction(db, 1, 2, jobsity.SanctionBan)
+	assert.Nil(t, err)
+	assert.NotEqual(t, ban.ID, sanction.ID)
+	assert.Equal(t, "flood", sanction.Reason)
+
+	assert.Nil(t, modb.DeleteSanction(db, sanction))
+	_, err = modb.ViewSanction(db, 1, 2, jobsity.SanctionBan)
+	assert.Equal(t, pgsql.ErrSanctionNotFound, err)
+
+	for _, action := range []jobsity.ModerationAction{jobsity.ActionBan, jobsity.ActionUnban} {
+		_, err := modb.CreateLog(db, jobsity.ModerationLog{RoomID: 1, Action: action, UserID: 2, ModeratorID: 1})
+		assert.Nil(t, err)
+	}
+	_, err = modb.CreateLog(db, jobsity.ModerationLog{RoomID: 3, Action: jobsity.ActionKick, UserID: 2, ModeratorID: 1})
+	assert.Nil(t, err)
+
+	logs, err := modb.ListLog(db, 1, jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, jobsity.ActionUnban, logs[0].Action)
+		assert.Equal(t, jobsity.ActionBan, logs[1].Action)
+	}
+}
@@ -0,0 +1,106 @@
+package pgsql
+
+import (
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Read represents the client for read_cursors table
+type Read struct{}
+
+// ReadCursorIndex makes read cursors unique per user and room, among the cursors not deleted
+const ReadCursorIndex = `CREATE UNIQUE INDEX IF NOT EXISTS read_cursors_user_id_room_key ON read_cursors (user_id, room) WHERE deleted_at IS NULL`
+
+// MarkRead moves user's read cursor of a room forward to a message of the room, reporting whether it moved.
+// Cursors are created on the first read, and never go back.
+func (r Read) MarkRead(db orm.DB, userID int, room string, messageID int) (bool, error) {
+	exists, err := db.Model((*jobsity.Message)(nil)).Where("id = ?", messageID).Where("room = ?", room).
+		Where("deleted_at is null").Exists()
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrMessageNotFound
+	}
+
+	cursor := jobsity.ReadCursor{UserID: userID, Room: room, LastReadID: messageID}
+	exists, err = db.Model(&cursor).Where("user_id = ?", userID).Where("room = ?", room).
+		Where("deleted_at is null").Exists()
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		// A cursor created concurrently is caught by ReadCursorIndex, and moved forward below instead
+		res, err := db.Model(&cursor).OnConflict("DO NOTHING").Insert()
+		if err != nil {
+			return false, err
+		}
+		if res.RowsAffected() > 0 {
+			return true, nil
+		}
+	}
+	res, err := db.Model(&cursor).Set("last_read_id = ?", messageID).Set("updated_at = ?", time.Now()).
+		Where("user_id = ?", userID).Where("room = ?", room).Where("deleted_at is null").
+		Where("last_read_id < ?", messageID).Update()
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// roomUnread holds the number of messages of a room unread by the user
+type roomUnread struct {
+	Room   string
+	Unread int
+}
+
+// roomUnreadQuery counts the unread messages of rooms by their keys and last read message IDs at once
+const roomUnreadQuery = `SELECT r.key AS room, unread.count AS unread
+FROM unnest(?::text[], ?::bigint[]) AS r(key, last_read_id)
+CROSS JOIN LATERAL (
+	SELECT count(*) FROM messages WHERE room = r.key AND id > r.last_read_id AND user_id != ? AND deleted_at IS NULL
+) AS unread`
+
+// List returns user's read cursors of the rooms with the keys, in the same order, with their unread
+// message counts. Rooms the user never read have an empty cursor. Users' own messages are not unread.
+func (r Read) List(db orm.DB, userID int, rooms []string) ([]jobsity.ReadCursor, error) {
+	if len(rooms) == 0 {
+		return nil, nil
+	}
+	var existing []jobsity.ReadCursor
+	err := db.Model(&existing).Where("user_id = ?", userID).Where("room in (?)", pg.In(rooms)).
+		Where("deleted_at is null").Select()
+	if err != nil {
+		return nil, err
+	}
+	byRoom := make(map[string]jobsity.ReadCursor, len(existing))
+	for _, cursor := range existing {
+		byRoom[cursor.Room] = cursor
+	}
+
+	cursors := make([]jobsity.ReadCursor, len(rooms))
+	lastRead := make([]int, len(rooms))
+	for i, room := range rooms {
+		cursor, ok := byRoom[room]
+		if !ok {
+			cursor = jobsity.ReadCursor{UserID: userID, Room: room}
+		}
+		cursors[i], lastRead[i] = cursor, cursor.LastReadID
+	}
+	var unread []roomUnread
+	if _, err := db.Query(&unread, roomUnreadQuery, pg.Array(rooms), pg.Array(lastRead), userID); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(unread))
+	for _, u := range unread {
+		counts[u.Room] = u.Unread
+	}
+	for i := range cursors {
+		cursors[i].Unread = counts[cursors[i].Room]
+	}
+	return cursors, nil
+}
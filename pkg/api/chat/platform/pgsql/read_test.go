@@ -0,0 +1,74 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestRead(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Message{}, &jobsity.ReadCursor{})
+	if _, err := db.Exec(pgsql.ReadCursorIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	mdb := pgsql.Message{}
+	rcdb := pgsql.Read{}
+	var msgs []jobsity.Message
+	for _, m := range []jobsity.Message{
+		{Room: "general", UserID: 1, Username: "johndoe", Body: "hi"},
+		{Room: "general", UserID: 2, Username: "janedoe", Body: "hello"},
+		{Room: "general", UserID: 2, Username: "janedoe", Body: "how are you?"},
+		{Room: "1:general", UserID: 2, Username: "janedoe", Body: "hey"},
+	} {
+		msg, err := mdb.Create(db, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	// Rooms never read have every message of the others unread
+	cursors, err := rcdb.List(db, 1, []string{"general", "1:general", "random"})
+	assert.Nil(t, err)
+	if assert.Len(t, cursors, 3) {
+		assert.Equal(t, 2, cursors[0].Unread)
+		assert.Equal(t, 1, cursors[1].Unread)
+		assert.Equal(t, "random", cursors[2].Room)
+		assert.Equal(t, 0, cursors[2].Unread)
+	}
+
+	// Cursors only move forward, to the messages of their room
+	_, err = rcdb.MarkRead(db, 1, "general", msgs[3].ID)
+	assert.Equal(t, pgsql.ErrMessageNotFound, err)
+	moved, err := rcdb.MarkRead(db, 1, "general", msgs[1].ID)
+	assert.Nil(t, err)
+	assert.True(t, moved)
+	moved, err = rcdb.MarkRead(db, 1, "general", msgs[2].ID)
+	assert.Nil(t, err)
+	assert.True(t, moved)
+	moved, err = rcdb.MarkRead(db, 1, "general", msgs[1].ID)
+	assert.Nil(t, err)
+	assert.False(t, moved)
+
+	cursors, err = rcdb.List(db, 1, []string{"general"})
+	assert.Nil(t, err)
+	if assert.Len(t, cursors, 1) {
+		assert.Equal(t, msgs[2].ID, cursors[0].LastReadID)
+		assert.Equal(t, 0, cursors[0].Unread)
+	}
+
+	// Users have a single cursor per room
+	err = db.Insert(&jobsity.ReadCursor{UserID: 1, Room: "general", LastReadID: msgs[0].ID})
+	if pgErr, ok := err.(pg.Error); assert.True(t, ok) {
+		assert.True(t, pgErr.IntegrityViolation())
+	}
+}
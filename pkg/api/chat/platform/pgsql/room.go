@@ -0,0 +1,82 @@
+package pgsql
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Room represents the client for room table
+type Room struct{}
+
+// Custom errors
+var (
+	ErrRoomNotFound      = echo.NewHTTPError(http.StatusNotFound, "room not found")
+	ErrRoomAlreadyExists = echo.NewHTTPError(http.StatusConflict, "room already exists")
+)
+
+// RoomNameIndex makes room names unique per company regardless of case, among the rooms not deleted
+const RoomNameIndex = `CREATE UNIQUE INDEX IF NOT EXISTS rooms_name_key ON rooms (lower(name), company_id) WHERE deleted_at IS NULL`
+
+// Create creates a new room on database. Room names are unique per company regardless of case.
+func (r Room) Create(db orm.DB, room jobsity.Room) (jobsity.Room, error) {
+	exists, err := db.Model((*jobsity.Room)(nil)).Where("lower(name) = ?", strings.ToLower(room.Name)).
+		Where("company_id = ?", room.CompanyID).Where("deleted_at is null").Exists()
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	if exists {
+		return jobsity.Room{}, ErrRoomAlreadyExists
+	}
+
+	// Rooms created concurrently with the same name are caught by RoomNameIndex
+	err = db.Insert(&room)
+	if pgErr, ok := err.(pg.Error); ok && pgErr.IntegrityViolation() {
+		return jobsity.Room{}, ErrRoomAlreadyExists
+	}
+	return room, err
+}
+
+// View returns single room by name regardless of case, looking it up in the company first and among the
+// global rooms then
+func (r Room) View(db orm.DB, name string, companyID int) (jobsity.Room, error) {
+	var room jobsity.Room
+	err := db.Model(&room).Where("lower(name) = ?", strings.ToLower(name)).Where("company_id in (0, ?)", companyID).
+		Where("deleted_at is null").Order("company_id desc").Limit(1).Select()
+	if err == pg.ErrNoRows {
+		return room, ErrRoomNotFound
+	}
+	return room, err
+}
+
+// List returns a page of either the active or the archived rooms matching every query, ordered by name
+func (r Room) List(db orm.DB, qp []jobsity.ListQuery, archived bool, p jobsity.Pagination) ([]jobsity.Room, error) {
+	var rooms []jobsity.Room
+	q := db.Model(&rooms).Where("archived = ?", archived).Where("deleted_at is null").
+		Order("name", "company_id").Limit(p.Limit).Offset(p.Offset)
+	for _, lq := range qp {
+		q.Where(lq.Query, lq.ID)
+	}
+	err := q.Select()
+	return rooms, err
+}
+
+// Update updates room's metadata, visibility and archived state
+func (r Room) Update(db orm.DB, room jobsity.Room) error {
+	_, err := db.Model(&room).Column("topic", "description", "private", "archived", "slow_mode", "filters", "updated_at").WherePK().Update()
+	return err
+}
+
+// Delete sets deleted_at for a room and its messages
+func (r Room) Delete(db orm.DB, room jobsity.Room) error {
+	if err := db.Delete(&room); err != nil {
+		return err
+	}
+	_, err := db.Model((*jobsity.Message)(nil)).Where("room = ?", room.Key()).Delete()
+	return err
+}
@@ -0,0 +1,225 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestRoomCreate(t *testing.T) {
+	cases := []struct {
+		name     string
+		wantErr  error
+		req      jobsity.Room
+		wantData jobsity.Room
+	}{
+		{
+			name:    "Fail on existing name",
+			wantErr: pgsql.ErrRoomAlreadyExists,
+			req:     jobsity.Room{Name: "General"},
+		},
+		{
+			name:     "Success",
+			req:      jobsity.Room{Name: "random", Topic: "anything", CreatedBy: 1},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "random", Topic: "anything", CreatedBy: 1},
+		},
+		{
+			name:     "Success on existing name in another company",
+			req:      jobsity.Room{Name: "general", CompanyID: 1},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 3}, Name: "general", CompanyID: 1},
+		},
+		{
+			name:    "Fail on existing name in the same company",
+			wantErr: pgsql.ErrRoomAlreadyExists,
+			req:     jobsity.Room{Name: "General", CompanyID: 1, LocationID: 2},
+		},
+	}
+
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Room{})
+	if _, err := db.Exec(pgsql.RoomNameIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.InsertMultiple(db, &jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general"}); err != nil {
+		t.Error(err)
+	}
+
+	rdb := pgsql.Room{}
+
+	// The index rejects duplicates missed by the existence check, like concurrent creations
+	_, err := db.Model(&jobsity.Room{Name: "GENERAL"}).Insert()
+	assert.NotNil(t, err)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := rdb.Create(db, tt.req)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantData.ID != 0 {
+				tt.wantData.CreatedAt = resp.CreatedAt
+				tt.wantData.UpdatedAt = resp.UpdatedAt
+				assert.Equal(t, tt.wantData, resp)
+			}
+		})
+	}
+}
+
+func TestRoomList(t *testing.T) {
+	cases := []struct {
+		name     string
+		qp       []jobsity.ListQuery
+		archived bool
+		pg       jobsity.Pagination
+		wantData []jobsity.Room
+	}{
+		{
+			name: "Success on active rooms",
+			pg:   jobsity.Pagination{Limit: 10},
+			wantData: []jobsity.Room{
+				{Base: jobsity.Base{ID: 1}, Name: "general"},
+				{Base: jobsity.Base{ID: 6}, Name: "general", CompanyID: 1},
+				{Base: jobsity.Base{ID: 8}, Name: "general", CompanyID: 2},
+				{Base: jobsity.Base{ID: 7}, Name: "general-paris", CompanyID: 1, LocationID: 1},
+				{Base: jobsity.Base{ID: 9}, Name: "general-rome", CompanyID: 1, LocationID: 2},
+				{Base: jobsity.Base{ID: 4}, Name: "hr", Private: true},
+				{Base: jobsity.Base{ID: 5}, Name: "leadership", Private: true},
+				{Base: jobsity.Base{ID: 3}, Name: "random"},
+			},
+		},
+		{
+			name: "Success on rooms visible to a user",
+			qp: []jobsity.ListQuery{{
+				Query: "private = false or id in (select room_id from room_members where user_id = ? and deleted_at is null)",
+				ID:    1,
+			}},
+			wantData: []jobsity.Room{
+				{Base: jobsity.Base{ID: 1}, Name: "general"},
+				{Base: jobsity.Base{ID: 6}, Name: "general", CompanyID: 1},
+				{Base: jobsity.Base{ID: 8}, Name: "general", CompanyID: 2},
+				{Base: jobsity.Base{ID: 7}, Name: "general-paris", CompanyID: 1, LocationID: 1},
+				{Base: jobsity.Base{ID: 9}, Name: "general-rome", CompanyID: 1, LocationID: 2},
+				{Base: jobsity.Base{ID: 4}, Name: "hr", Private: true},
+				{Base: jobsity.Base{ID: 3}, Name: "random"},
+			},
+		},
+		{
+			name: "Success on rooms of a location",
+			qp: []jobsity.ListQuery{
+				{Query: "company_id in (0, ?)", ID: 1},
+				{Query: "location_id in (0, ?)", ID: 1},
+			},
+			wantData: []jobsity.Room{
+				{Base: jobsity.Base{ID: 1}, Name: "general"},
+				{Base: jobsity.Base{ID: 6}, Name: "general", CompanyID: 1},
+				{Base: jobsity.Base{ID: 7}, Name: "general-paris", CompanyID: 1, LocationID: 1},
+				{Base: jobsity.Base{ID: 4}, Name: "hr", Private: true},
+				{Base: jobsity.Base{ID: 5}, Name: "leadership", Private: true},
+				{Base: jobsity.Base{ID: 3}, Name: "random"},
+			},
+		},
+		{
+			name:     "Success on archived rooms",
+			archived: true,
+			wantData: []jobsity.Room{
+				{Base: jobsity.Base{ID: 2}, Name: "old", Archived: true},
+			},
+		},
+	}
+
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Role{}, &jobsity.User{}, &jobsity.Room{}, &jobsity.RoomMember{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Role{ID: jobsity.UserRole, AccessLevel: jobsity.UserRole, Name: "USER"},
+		&jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe", RoleID: jobsity.UserRole},
+		&jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general"},
+		&jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "old", Archived: true},
+		&jobsity.Room{Base: jobsity.Base{ID: 3}, Name: "random"},
+		&jobsity.Room{Base: jobsity.Base{ID: 4}, Name: "hr", Private: true},
+		&jobsity.Room{Base: jobsity.Base{ID: 5}, Name: "leadership", Private: true},
+		&jobsity.Room{Base: jobsity.Base{ID: 6}, Name: "general", CompanyID: 1},
+		&jobsity.Room{Base: jobsity.Base{ID: 7}, Name: "general-paris", CompanyID: 1, LocationID: 1},
+		&jobsity.Room{Base: jobsity.Base{ID: 8}, Name: "general", CompanyID: 2},
+		&jobsity.Room{Base: jobsity.Base{ID: 9}, Name: "general-rome", CompanyID: 1, LocationID: 2},
+		&jobsity.RoomMember{Base: jobsity.Base{ID: 1}, RoomID: 4, UserID: 1, Role: jobsity.RoomMemberRole},
+	); err != nil {
+		t.Error(err)
+	}
+
+	rdb := pgsql.Room{}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rooms, err := rdb.List(db, tt.qp, tt.archived, tt.pg)
+			assert.Nil(t, err)
+			for i, v := range rooms {
+				tt.wantData[i].CreatedAt = v.CreatedAt
+				tt.wantData[i].UpdatedAt = v.UpdatedAt
+			}
+			assert.Equal(t, tt.wantData, rooms)
+		})
+	}
+}
+
+func TestRoomUpdateDelete(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Room{}, &jobsity.Message{}, &jobsity.Reaction{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general", Topic: "old topic"},
+		&jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "general", CompanyID: 1, Topic: "company topic"},
+		&jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "general", Body: "hello"},
+		&jobsity.Message{Base: jobsity.Base{ID: 2}, Room: "1:general", Body: "hello company"},
+	); err != nil {
+		t.Error(err)
+	}
+
+	rdb := pgsql.Room{}
+
+	_, err := rdb.View(db, "random", 0)
+	assert.Equal(t, pgsql.ErrRoomNotFound, err)
+
+	// Names are looked up regardless of case, like they are unique
+	room, err := rdb.View(db, "General", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "general", room.Name)
+
+	company, err := rdb.View(db, "general", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "company topic", company.Topic)
+
+	// Companies without their own room use the global one
+	room, err = rdb.View(db, "general", 2)
+	assert.Nil(t, err)
+	room.Topic = ""
+	room.Archived = true
+	room.SlowMode = 30
+	room.Filters = []string{"strip_links"}
+	assert.Nil(t, rdb.Update(db, room))
+	updated, err := rdb.View(db, "general", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "", updated.Topic)
+	assert.True(t, updated.Archived)
+	assert.Equal(t, 30, updated.SlowMode)
+	assert.Equal(t, []string{"strip_links"}, updated.Filters)
+
+	assert.Nil(t, rdb.Delete(db, updated))
+	_, err = rdb.View(db, "general", 0)
+	assert.Equal(t, pgsql.ErrRoomNotFound, err)
+	msgs, err := pgsql.Message{}.List(db, "general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Empty(t, msgs)
+	msgs, err = pgsql.Message{}.List(db, "1:general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, msgs, 1)
+}
@@ -0,0 +1,57 @@
+package pgsql
+
+import (
+	"net/http"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Custom errors
+var (
+	ErrCompanyNotFound  = echo.NewHTTPError(http.StatusNotFound, "company not found")
+	ErrLocationNotFound = echo.NewHTTPError(http.StatusNotFound, "location not found")
+)
+
+// Tenant represents the client for company and location tables
+type Tenant struct{}
+
+// Companies returns the active companies
+func (t Tenant) Companies(db orm.DB) ([]jobsity.Company, error) {
+	var companies []jobsity.Company
+	err := db.Model(&companies).Column("id", "name", "active").Where("active = true").Order("id").Select()
+	return companies, err
+}
+
+// Company returns the company if active
+func (t Tenant) Company(db orm.DB, id int) (jobsity.Company, error) {
+	company := jobsity.Company{Base: jobsity.Base{ID: id}}
+	err := db.Model(&company).Column("id", "name", "active").WherePK().Where("active = true").Select()
+	if err == pg.ErrNoRows {
+		return company, ErrCompanyNotFound
+	}
+	return company, err
+}
+
+// Location returns the location if active, and its company too
+func (t Tenant) Location(db orm.DB, id int) (jobsity.Location, error) {
+	location := jobsity.Location{Base: jobsity.Base{ID: id}}
+	err := db.Model(&location).WherePK().Where("active = true").
+		Where("company_id in (select id from companies where active = true and deleted_at is null)").Select()
+	if err == pg.ErrNoRows {
+		return location, ErrLocationNotFound
+	}
+	return location, err
+}
+
+// Locations returns the active locations of the active companies
+func (t Tenant) Locations(db orm.DB) ([]jobsity.Location, error) {
+	var locations []jobsity.Location
+	err := db.Model(&locations).Where("active = true").
+		Where("company_id in (select id from companies where active = true and deleted_at is null)").
+		Order("id").Select()
+	return locations, err
+}
@@ -0,0 +1,57 @@
+package pgsql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestTenant(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Company{}, &jobsity.Location{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Company{Base: jobsity.Base{ID: 1}, Name: "acme", Active: true},
+		&jobsity.Company{Base: jobsity.Base{ID: 2}, Name: "closed"},
+		&jobsity.Location{Base: jobsity.Base{ID: 1}, Name: "Paris", Active: true, CompanyID: 1},
+		&jobsity.Location{Base: jobsity.Base{ID: 2}, Name: "Rome", CompanyID: 1},
+		&jobsity.Location{Base: jobsity.Base{ID: 3}, Name: "Berlin", Active: true, CompanyID: 2},
+	); err != nil {
+		t.Error(err)
+	}
+
+	tdb := pgsql.Tenant{}
+
+	companies, err := tdb.Companies(db)
+	assert.Nil(t, err)
+	if assert.Len(t, companies, 1) {
+		assert.Equal(t, "acme", companies[0].Name)
+	}
+
+	locations, err := tdb.Locations(db)
+	assert.Nil(t, err)
+	if assert.Len(t, locations, 1) {
+		assert.Equal(t, "Paris", locations[0].Name)
+		assert.Equal(t, 1, locations[0].CompanyID)
+	}
+
+	company, err := tdb.Company(db, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "acme", company.Name)
+	_, err = tdb.Company(db, 2)
+	assert.Equal(t, pgsql.ErrCompanyNotFound, err)
+
+	location, err := tdb.Location(db, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Paris", location.Name)
+	for _, id := range []int{2, 3} {
+		_, err = tdb.Location(db, id)
+		assert.Equal(t, pgsql.ErrLocationNotFound, err)
+	}
+}
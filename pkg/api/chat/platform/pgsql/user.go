@@ -0,0 +1,48 @@
+package pgsql
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// User represents the client for user table
+type User struct{}
+
+// Custom errors
+var (
+	ErrUserNotFound = echo.NewHTTPError(http.StatusNotFound, "user not found")
+)
+
+// View returns single user by ID
+func (u User) View(db orm.DB, id int) (jobsity.User, error) {
+	var user jobsity.User
+	err := db.Model(&user).Column("id", "username", "company_id", "location_id", "role_id").
+		Where("id = ?", id).Where("deleted_at is null").Select()
+	if err == pg.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// FindByUsername returns single user by username
+func (u User) FindByUsername(db orm.DB, uname string) (jobsity.User, error) {
+	var user jobsity.User
+	err := db.Model(&user).Column("id", "username", "company_id", "location_id", "role_id").
+		Where("username = ?", uname).Where("deleted_at is null").Select()
+	if err == pg.ErrNoRows {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+// UpdateLastSeen sets the last time the user was connected to the chat
+func (u User) UpdateLastSeen(db orm.DB, id int, t time.Time) error {
+	_, err := db.Model((*jobsity.User)(nil)).Set("last_seen = ?", t).Where("id = ?", id).Update()
+	return err
+}
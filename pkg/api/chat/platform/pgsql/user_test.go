@@ -0,0 +1,40 @@
+package pgsql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestUser(t *testing.T) {
+	dbCon := mock.NewPGContainer(t)
+	defer dbCon.Shutdown()
+
+	db := mock.NewDB(t, dbCon, &jobsity.Role{}, &jobsity.User{})
+
+	if err := mock.InsertMultiple(db,
+		&jobsity.Role{ID: jobsity.UserRole, AccessLevel: jobsity.UserRole, Name: "USER"},
+		&jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe", RoleID: jobsity.UserRole, CompanyID: 2},
+	); err != nil {
+		t.Error(err)
+	}
+
+	udb := pgsql.User{}
+
+	_, err := udb.FindByUsername(db, "janedoe")
+	assert.Equal(t, pgsql.ErrUserNotFound, err)
+	user, err := udb.FindByUsername(db, "johndoe")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, user.CompanyID)
+
+	seen := time.Now().Round(time.Millisecond)
+	assert.Nil(t, udb.UpdateLastSeen(db, 1, seen))
+	var got jobsity.User
+	assert.Nil(t, db.Model(&got).Where("id = ?", 1).Select())
+	assert.True(t, seen.Equal(got.LastSeen))
+}
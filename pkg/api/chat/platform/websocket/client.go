@@ -0,0 +1,236 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Outbound queue defaults
+const (
+	defaultQueueSize    = 64
+	defaultWriteTimeout = 10 * time.Second
+)
+
+// Close reasons sent to evicted clients
+const (
+	ReasonSlowConsumer = "slow consumer"
+	ReasonWriteTimeout = "write timeout"
+)
+
+// closePolicyViolation is the WebSocket close status used when evicting clients
+const closePolicyViolation = 1008
+
+// Config represents client outbound queue configuration
+type Config struct {
+	// QueueSize is the capacity of each client's outbound queue
+	QueueSize int
+	// HighWaterMark is the number of queued messages at which a client is evicted.
+	// It defaults to, and cannot exceed, QueueSize.
+	HighWaterMark int
+	// WriteTimeout is the time a single write may take before the client is evicted
+	WriteTimeout time.Duration
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.HighWaterMark < 1 || cfg.HighWaterMark > cfg.QueueSize {
+		cfg.HighWaterMark = cfg.QueueSize
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+	return cfg
+}
+
+// Stats holds outbound queue counters
+type Stats struct {
+	Dropped uint64 `json:"dropped"`
+	Evicted uint64 `json:"evicted"`
+}
+
+type counters struct {
+	dropped uint64
+	evicted uint64
+}
+
+func (s *counters) stats() Stats {
+	return Stats{
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Evicted: atomic.LoadUint64(&s.evicted),
+	}
+}
+
+// Client represents a WebSocket connection of an authenticated user.
+// Messages are queued in a bounded buffer drained by the client's writer goroutine,
+// so messages coming from different rooms never interleave on the socket and a
+// stalled connection never blocks its rooms; it gets evicted instead.
+type Client struct {
+	User jobsity.AuthUser
+
+	protocol string
+	conn     *websocket.Conn
+	cfg      Config
+	counters *counters
+	send     chan []byte
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+	reason   string
+}
+
+func newClient(conn *websocket.Conn, user jobsity.AuthUser, cfg Config, c *counters) *Client {
+	cl := &Client{
+		User:     user,
+		protocol: Protocol(conn),
+		conn:     conn,
+		cfg:      cfg,
+		counters: c,
+		send:     make(chan []byte, cfg.QueueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go cl.write()
+	return cl
+}
+
+// Protocol returns the subprotocol negotiated for the client's connection
+func (c *Client) Protocol() string {
+	return c.protocol
+}
+
+// Send encodes the frame for the client's subprotocol and queues it for the connection.
+// It returns false if the frame was dropped, because the client is closed or got evicted
+// for reaching the high-water mark. Frames the subprotocol does not carry are skipped.
+func (c *Client) Send(f jobsity.Frame) bool {
+	data, err := Encode(c.protocol, f)
+	if err != nil {
+		atomic.AddUint64(&c.counters.dropped, 1)
+		return false
+	}
+	if data == nil {
+		return true
+	}
+	return c.enqueue(data)
+}
+
+func (c *Client) enqueue(msg []byte) bool {
+	select {
+	case <-c.done:
+		atomic.AddUint64(&c.counters.dropped, 1)
+		return false
+	default:
+	}
+	if len(c.send) < c.cfg.HighWaterMark {
+		select {
+		case c.send <- msg:
+			return true
+		default:
+		}
+	}
+	c.Evict(ReasonSlowConsumer)
+	atomic.AddUint64(&c.counters.dropped, 1)
+	return false
+}
+
+// Close stops the client's writer once it wrote the queued messages, and waits for it, so the last
+// messages, like the error which ended the session, reach the connection before it is closed. The rest
+// of the queue is dropped once a write fails.
+func (c *Client) Close() {
+	c.close("")
+	<-c.stopped
+}
+
+// Evict stops the client's writer and closes the connection with the given reason
+func (c *Client) Evict(reason string) {
+	c.close(reason)
+}
+
+func (c *Client) close(reason string) {
+	c.once.Do(func() {
+		if reason != "" {
+			c.reason = reason
+			atomic.AddUint64(&c.counters.evicted, 1)
+		}
+		close(c.done)
+	})
+}
+
+// Done returns a channel which is closed when the client is closed or evicted
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Reason returns the reason the client was evicted for, if any.
+// It must only be called after Done is closed.
+func (c *Client) Reason() string {
+	return c.reason
+}
+
+func (c *Client) write() {
+	defer close(c.stopped)
+	for {
+		select {
+		case <-c.done:
+			c.shutdown()
+			return
+		default:
+		}
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := websocket.Message.Send(c.conn, string(msg)); err != nil {
+				if ne, ok := err.(net.Error); ok && ne.Timeout() {
+					c.Evict(ReasonWriteTimeout)
+				} else {
+					c.close("")
+				}
+			}
+		case <-c.done:
+			c.shutdown()
+			return
+		}
+	}
+}
+
+// shutdown writes the queued messages of closed clients. Evicted clients drop them instead,
+// and get the connection closed with the eviction reason, which also stops the connection's reader.
+func (c *Client) shutdown() {
+	if c.reason == "" {
+		c.flush()
+		return
+	}
+	atomic.AddUint64(&c.counters.dropped, uint64(len(c.send)))
+	c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+	if w, err := c.conn.NewFrameWriter(websocket.CloseFrame); err == nil {
+		payload := make([]byte, 2, 2+len(c.reason))
+		binary.BigEndian.PutUint16(payload, closePolicyViolation)
+		w.Write(append(payload, c.reason...))
+		w.Close()
+	}
+	c.conn.Close()
+}
+
+// flush writes the queued messages, dropping the rest of them once a write fails
+func (c *Client) flush() {
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			if err := websocket.Message.Send(c.conn, string(msg)); err != nil {
+				atomic.AddUint64(&c.counters.dropped, uint64(len(c.send)+1))
+				return
+			}
+		default:
+			return
+		}
+	}
+}
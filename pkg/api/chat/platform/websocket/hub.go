@@ -0,0 +1,154 @@
+package websocket
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Custom errors
+var (
+	ErrRoomNotFound = echo.NewHTTPError(http.StatusNotFound, "room not found")
+	ErrRoomExists   = echo.NewHTTPError(http.StatusConflict, "room already exists")
+)
+
+// Hub represents the registry of running rooms and connected clients
+type Hub struct {
+	mu       sync.RWMutex
+	rooms    map[string]*room
+	cfg      Config
+	counters counters
+}
+
+// NewHub creates a new hub without rooms
+func NewHub(cfg Config) *Hub {
+	return &Hub{
+		rooms: make(map[string]*room),
+		cfg:   cfg.withDefaults(),
+	}
+}
+
+// Connect creates a new client for the connection and starts its writer
+func (h *Hub) Connect(conn *websocket.Conn, user jobsity.AuthUser) *Client {
+	return newClient(conn, user, h.cfg, &h.counters)
+}
+
+// Stats returns outbound queue counters of all clients connected through the hub
+func (h *Hub) Stats() Stats {
+	return h.counters.stats()
+}
+
+// Open starts a new room
+func (h *Hub) Open(name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.rooms[name]; ok {
+		return ErrRoomExists
+	}
+	h.rooms[name] = newRoom(name)
+	return nil
+}
+
+// Close stops a room and waits for its goroutine to exit
+func (h *Hub) Close(name string) error {
+	h.mu.Lock()
+	r, ok := h.rooms[name]
+	delete(h.rooms, name)
+	h.mu.Unlock()
+	if !ok {
+		return ErrRoomNotFound
+	}
+	close(r.quit)
+	<-r.done
+	return nil
+}
+
+// Has reports whether the room is running
+func (h *Hub) Has(name string) bool {
+	_, err := h.room(name)
+	return err == nil
+}
+
+// Rooms returns names of the running rooms in alphabetical order
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make([]string, 0, len(h.rooms))
+	for name := range h.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Join adds the client to a room
+func (h *Hub) Join(name string, c *Client) error {
+	r, err := h.room(name)
+	if err != nil {
+		return err
+	}
+	select {
+	case r.join <- c:
+		return nil
+	case <-r.done:
+		return ErrRoomNotFound
+	}
+}
+
+// Leave removes the client from a room
+func (h *Hub) Leave(name string, c *Client) error {
+	r, err := h.room(name)
+	if err != nil {
+		return err
+	}
+	select {
+	case r.leave <- c:
+		return nil
+	case <-r.done:
+		return ErrRoomNotFound
+	}
+}
+
+// Broadcast sends the frame to every client in a room, except the sender if provided
+func (h *Hub) Broadcast(name string, f jobsity.Frame, except *Client) error {
+	r, err := h.room(name)
+	if err != nil {
+		return err
+	}
+	select {
+	case r.broadcast <- message{frame: f, except: except}:
+		return nil
+	case <-r.done:
+		return ErrRoomNotFound
+	}
+}
+
+// Clients returns clients of a room in the order they joined it
+func (h *Hub) Clients(name string) ([]*Client, error) {
+	r, err := h.room(name)
+	if err != nil {
+		return nil, err
+	}
+	reply := make(chan []*Client, 1)
+	select {
+	case r.clients <- reply:
+		return <-reply, nil
+	case <-r.done:
+		return nil, ErrRoomNotFound
+	}
+}
+
+func (h *Hub) room(name string) (*room, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.rooms[name]
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return r, nil
+}
@@ -0,0 +1,177 @@
+package websocket_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestHub(t *testing.T) {
+	h := ws.NewHub(ws.Config{})
+	assert.Nil(t, h.Open("general"))
+	assert.Nil(t, h.Open("random"))
+	assert.Equal(t, ws.ErrRoomExists, h.Open("general"))
+	assert.Equal(t, []string{"general", "random"}, h.Rooms())
+
+	conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+	john := h.Connect(conn, jobsity.AuthUser{ID: 1, Username: "johndoe"})
+	conn, _ = mock.NewWSConn(t)
+	jane := h.Connect(conn, jobsity.AuthUser{ID: 2, Username: "janedoe"})
+	defer john.Close()
+	jane.Close()
+
+	assert.Equal(t, ws.ErrRoomNotFound, h.Join("notexists", john))
+	assert.Nil(t, h.Join("general", john))
+	assert.Nil(t, h.Join("general", john))
+	assert.Nil(t, h.Join("general", jane))
+
+	clients, err := h.Clients("general")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ws.Client{john, jane}, clients)
+
+	// Closed clients are dropped from the room on the next broadcast
+	assert.Nil(t, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameMessage, "general", "janedoe", "hello"), nil))
+	var got string
+	assert.Nil(t, websocket.Message.Receive(peer, &got))
+	assert.Equal(t, "janedoe: hello", got)
+	clients, err = h.Clients("general")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ws.Client{john}, clients)
+
+	assert.Nil(t, h.Leave("general", john))
+	clients, err = h.Clients("general")
+	assert.Nil(t, err)
+	assert.Empty(t, clients)
+
+	assert.Nil(t, h.Close("general"))
+	assert.Equal(t, ws.ErrRoomNotFound, h.Close("general"))
+	assert.Equal(t, ws.ErrRoomNotFound, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameSystem, "general", "", "hello"), nil))
+	assert.False(t, h.Has("general"))
+	assert.True(t, h.Has("random"))
+}
+
+func TestHubConcurrency(t *testing.T) {
+	const n = 200
+	h := ws.NewHub(ws.Config{QueueSize: n})
+	if err := h.Open("general"); err != nil {
+		t.Fatal(err)
+	}
+
+	clients := make([]*ws.Client, n)
+	peers := make([]*websocket.Conn, n)
+	for i := range clients {
+		conn, peer := mock.NewWSConn(t)
+		clients[i] = h.Connect(conn, jobsity.AuthUser{ID: i, Username: fmt.Sprintf("user%d", i)})
+		peers[i] = peer
+	}
+
+	run := func(fn func(i int)) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				fn(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	run(func(i int) {
+		assert.Nil(t, h.Join("general", clients[i]))
+	})
+
+	// Every client receives every message, including its own
+	received := make([]int, n)
+	var readers sync.WaitGroup
+	for i := range peers {
+		readers.Add(1)
+		go func(i int) {
+			defer readers.Done()
+			for received[i] < n {
+				var msg string
+				if err := websocket.Message.Receive(peers[i], &msg); err != nil {
+					return
+				}
+				received[i]++
+			}
+		}(i)
+	}
+	run(func(i int) {
+		assert.Nil(t, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameSystem, "general", "", fmt.Sprintf("message %d", i)), nil))
+		_, err := h.Clients("general")
+		assert.Nil(t, err)
+	})
+	readers.Wait()
+	for i := range received {
+		assert.Equal(t, n, received[i])
+	}
+
+	run(func(i int) {
+		assert.Nil(t, h.Leave("general", clients[i]))
+		clients[i].Close()
+	})
+	members, err := h.Clients("general")
+	assert.Nil(t, err)
+	assert.Empty(t, members)
+	assert.Nil(t, h.Close("general"))
+}
+
+func TestSlowConsumerEviction(t *testing.T) {
+	h := ws.NewHub(ws.Config{QueueSize: 8, HighWaterMark: 4, WriteTimeout: 5 * time.Second})
+	if err := h.Open("general"); err != nil {
+		t.Fatal(err)
+	}
+
+	fastConn, fastPeer := mock.NewWSConn(t, ws.ProtocolText)
+	slowConn, slowPeer := mock.NewWSConn(t, ws.ProtocolText)
+	fast := h.Connect(fastConn, jobsity.AuthUser{ID: 1, Username: "fast"})
+	slow := h.Connect(slowConn, jobsity.AuthUser{ID: 2, Username: "slow"})
+	defer fast.Close()
+	assert.Nil(t, h.Join("general", fast))
+	assert.Nil(t, h.Join("general", slow))
+
+	// The slow peer never reads, so its socket buffers fill up and its queue grows
+	// until the high-water mark, while the fast peer keeps receiving every message
+	payload := strings.Repeat("x", 1<<20)
+	for i := 0; i < 32; i++ {
+		assert.Nil(t, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameSystem, "general", "", payload), nil))
+		var msg string
+		if err := websocket.Message.Receive(fastPeer, &msg); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(payload), len(msg))
+	}
+
+	select {
+	case <-slow.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow client was not evicted")
+	}
+	assert.Equal(t, ws.ReasonSlowConsumer, slow.Reason())
+
+	clients, err := h.Clients("general")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ws.Client{fast}, clients)
+
+	stats := h.Stats()
+	assert.Equal(t, uint64(1), stats.Evicted)
+	assert.True(t, stats.Dropped > 0)
+
+	// The evicted connection gets closed
+	for {
+		var msg string
+		if err := websocket.Message.Receive(slowPeer, &msg); err != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Supported subprotocols. Connections which do not negotiate a subprotocol use ProtocolJSON.
+const (
+	ProtocolJSON = "chat.v1.json"
+	ProtocolText = "chat.v1.text"
+)
+
+// ErrUnsupportedVersion is returned when decoding frames of an unknown protocol version
+var ErrUnsupportedVersion = fmt.Errorf("unsupported protocol version")
+
+// Handshake checks the request origin and selects the first subprotocol requested
+// by the client which the server supports
+func Handshake(cfg *websocket.Config, req *http.Request) (err error) {
+	cfg.Origin, err = websocket.Origin(cfg, req)
+	if err == nil && cfg.Origin == nil {
+		return fmt.Errorf("null origin")
+	}
+	if err != nil {
+		return err
+	}
+	requested := cfg.Protocol
+	cfg.Protocol = nil
+	for _, p := range requested {
+		if p == ProtocolJSON || p == ProtocolText {
+			cfg.Protocol = []string{p}
+			break
+		}
+	}
+	return nil
+}
+
+// Protocol returns the subprotocol negotiated for the connection
+func Protocol(conn *websocket.Conn) string {
+	if cfg := conn.Config(); cfg != nil && len(cfg.Protocol) == 1 && cfg.Protocol[0] == ProtocolText {
+		return ProtocolText
+	}
+	return ProtocolJSON
+}
+
+// Encode formats the frame for the given subprotocol. Frames the subprotocol does not carry are encoded as nil.
+func Encode(protocol string, f jobsity.Frame) ([]byte, error) {
+	if protocol == ProtocolText {
+		// Plain text clients could not expire typing indicators, nor tell which message a read receipt
+		// or a reaction is for
+		switch f.Type {
+		case jobsity.FrameTyping, jobsity.FrameStopTyping, jobsity.FrameRead,
+			jobsity.FrameReactionAdded, jobsity.FrameReactionRemoved:
+			return nil, nil
+		}
+		return []byte(f.String()), nil
+	}
+	return json.Marshal(f)
+}
+
+// Decode parses a client frame of the given subprotocol. Plain text frames are
+// addressed to room, and are treated as commands when they start with a slash.
+func Decode(protocol string, data []byte, room string) (jobsity.Frame, error) {
+	if protocol == ProtocolText {
+		return decodeText(string(data), room), nil
+	}
+	var f jobsity.Frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, err
+	}
+	if f.Version != jobsity.ProtocolVersion {
+		return f, ErrUnsupportedVersion
+	}
+	if f.Room == "" {
+		f.Room = room
+	}
+	return f, nil
+}
+
+func decodeText(text, room string) jobsity.Frame {
+	if !strings.HasPrefix(text, "/") {
+		return jobsity.NewFrame(jobsity.FrameMessage, room, "", text)
+	}
+	parts := strings.SplitN(text, " ", 2)
+	switch {
+	case parts[0] == "/join" && len(parts) == 2:
+		return jobsity.NewFrame(jobsity.FrameJoin, strings.TrimSpace(parts[1]), "", "")
+	case parts[0] == "/leave" && len(parts) == 2:
+		return jobsity.NewFrame(jobsity.FrameLeave, strings.TrimSpace(parts[1]), "", "")
+	case parts[0] == "/leave":
+		return jobsity.NewFrame(jobsity.FrameLeave, room, "", "")
+	default:
+		return jobsity.NewFrame(jobsity.FrameCommand, room, "", text)
+	}
+}
@@ -0,0 +1,129 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestDecode(t *testing.T) {
+	cases := map[string]struct {
+		protocol string
+		data     string
+		wantType string
+		wantRoom string
+		wantText string
+		wantErr  bool
+	}{
+		"Invalid JSON": {
+			protocol: ws.ProtocolJSON,
+			data:     "hello",
+			wantErr:  true,
+		},
+		"Unsupported version": {
+			protocol: ws.ProtocolJSON,
+			data:     `{"version":2,"type":"message","payload":{"text":"hello"}}`,
+			wantErr:  true,
+		},
+		"JSON message to the current room": {
+			protocol: ws.ProtocolJSON,
+			data:     `{"version":1,"type":"message","payload":{"text":"hello"}}`,
+			wantType: jobsity.FrameMessage,
+			wantRoom: "general",
+			wantText: "hello",
+		},
+		"JSON join": {
+			protocol: ws.ProtocolJSON,
+			data:     `{"version":1,"type":"join","room":"random"}`,
+			wantType: jobsity.FrameJoin,
+			wantRoom: "random",
+		},
+		"Text message": {
+			protocol: ws.ProtocolText,
+			data:     "hello",
+			wantType: jobsity.FrameMessage,
+			wantRoom: "general",
+			wantText: "hello",
+		},
+		"Text join": {
+			protocol: ws.ProtocolText,
+			data:     "/join random",
+			wantType: jobsity.FrameJoin,
+			wantRoom: "random",
+		},
+		"Text leave of the current room": {
+			protocol: ws.ProtocolText,
+			data:     "/leave",
+			wantType: jobsity.FrameLeave,
+			wantRoom: "general",
+		},
+		"Text command": {
+			protocol: ws.ProtocolText,
+			data:     "/users",
+			wantType: jobsity.FrameCommand,
+			wantRoom: "general",
+			wantText: "/users",
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := ws.Decode(tt.protocol, []byte(tt.data), "general")
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.wantType, f.Type)
+			assert.Equal(t, tt.wantRoom, f.Room)
+			assert.Equal(t, tt.wantText, f.Payload.Text)
+		})
+	}
+}
+
+func TestBroadcastProtocols(t *testing.T) {
+	h := ws.NewHub(ws.Config{})
+	if err := h.Open("general"); err != nil {
+		t.Fatal(err)
+	}
+	jsonConn, jsonPeer := mock.NewWSConn(t)
+	textConn, textPeer := mock.NewWSConn(t, ws.ProtocolText)
+	jsonClient := h.Connect(jsonConn, jobsity.AuthUser{ID: 1, Username: "johndoe"})
+	textClient := h.Connect(textConn, jobsity.AuthUser{ID: 2, Username: "janedoe"})
+	defer jsonClient.Close()
+	defer textClient.Close()
+	assert.Equal(t, ws.ProtocolJSON, jsonClient.Protocol())
+	assert.Equal(t, ws.ProtocolText, textClient.Protocol())
+	assert.Nil(t, h.Join("general", jsonClient))
+	assert.Nil(t, h.Join("general", textClient))
+
+	sent := jobsity.MessageFrame(jobsity.FrameMessage, jobsity.Message{
+		Base:     jobsity.Base{ID: 7, CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Room:     "general",
+		Username: "johndoe",
+		Body:     "hello",
+	})
+	assert.Nil(t, h.Broadcast("general", sent, nil))
+
+	var data string
+	assert.Nil(t, websocket.Message.Receive(jsonPeer, &data))
+	var got jobsity.Frame
+	assert.Nil(t, json.Unmarshal([]byte(data), &got))
+	assert.Equal(t, sent, got)
+
+	assert.Nil(t, websocket.Message.Receive(textPeer, &data))
+	assert.Equal(t, "johndoe: hello", data)
+
+	// Typing indicators are not sent to plain text clients
+	assert.Nil(t, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameTyping, "general", "johndoe", ""), nil))
+	assert.Nil(t, h.Broadcast("general", jobsity.NewFrame(jobsity.FrameSystem, "general", "", "bye"), nil))
+	assert.Nil(t, websocket.JSON.Receive(jsonPeer, &got))
+	assert.Equal(t, jobsity.FrameTyping, got.Type)
+	assert.Nil(t, websocket.Message.Receive(textPeer, &data))
+	assert.Equal(t, "bye", data)
+}
@@ -1,47 +1,91 @@
 package websocket
 
 import (
-	"fmt"
-	"golang.org/x/net/websocket"
-	jobsity "my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge"
 )
 
-// Room represents the client for room websocket
-type Room struct{}
+// message is a broadcast request, delivered to every member except the sender
+type message struct {
+	frame  jobsity.Frame
+	except *Client
+}
+
+// room owns its membership. All membership changes and broadcasts are
+// processed sequentially by the room's goroutine.
+type room struct {
+	name      string
+	join      chan *Client
+	leave     chan *Client
+	broadcast chan message
+	clients   chan chan []*Client
+	quit      chan struct{}
+	done      chan struct{}
+}
+
+func newRoom(name string) *room {
+	r := &room{
+		name:      name,
+		join:      make(chan *Client),
+		leave:     make(chan *Client),
+		broadcast: make(chan message),
+		clients:   make(chan chan []*Client),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
 
-func (r *Room) Run(room *jobsity.Room) {
+func (r *room) run() {
+	defer close(r.done)
+	var members []*Client
 	for {
 		select {
-		case message := <-room.Broadcast:
-			for client := range room.Clients {
-				err := websocket.Message.Send(client, string(message))
-				if err != nil {
-					fmt.Printf("error sending message to client: %v", err)
-					r.RemoveClient(client, room)
+		case c := <-r.join:
+			if indexOf(members, c) < 0 {
+				members = append(members, c)
+			}
+		case c := <-r.leave:
+			if i := indexOf(members, c); i >= 0 {
+				members = append(members[:i], members[i+1:]...)
+			}
+		case m := <-r.broadcast:
+			// frames are encoded once per subprotocol rather than once per member
+			encoded := make(map[string][]byte, 2)
+			active := members[:0]
+			for _, c := range members {
+				if c == m.except {
+					active = append(active, c)
+					continue
+				}
+				data, ok := encoded[c.protocol]
+				if !ok {
+					data, _ = Encode(c.protocol, m.frame)
+					encoded[c.protocol] = data
+				}
+				if data == nil || c.enqueue(data) {
+					active = append(active, c)
 				}
 			}
-		case <-room.Quit:
+			for i := len(active); i < len(members); i++ {
+				members[i] = nil
+			}
+			members = active
+		case reply := <-r.clients:
+			list := make([]*Client, len(members))
+			copy(list, members)
+			reply <- list
+		case <-r.quit:
 			return
 		}
 	}
 }
 
-func (r *Room) AddClient(conn *websocket.Conn, room *jobsity.Room) {
-	room.Clients[conn] = true
-}
-
-func (r *Room) RemoveClient(conn *websocket.Conn, room *jobsity.Room) {
-	delete(room.Clients, conn)
-}
-
-func (r *Room) BroadcastMessage(message []byte, sender *websocket.Conn, room *jobsity.Room) {
-	for client := range room.Clients {
-		if client != sender {
-			err := websocket.Message.Send(client, string(message))
-			if err != nil {
-				fmt.Printf("error sending message to client: %v", err)
-				r.RemoveClient(client, room)
-			}
+func indexOf(clients []*Client, c *Client) int {
+	for i, v := range clients {
+		if v == c {
+			return i
 		}
 	}
+	return -1
 }
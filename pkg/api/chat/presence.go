@@ -0,0 +1,340 @@
+package chat
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	websocket2 "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+)
+
+// DefaultHeartbeatInterval is how often chat instances tell the others they are alive
+const DefaultHeartbeatInterval = 10 * time.Second
+
+// heartbeatMisses is the number of heartbeats an instance misses before the presence of its users is dropped
+const heartbeatMisses = 3
+
+// SetAway marks the connection away or back. Users are away while every connection of theirs is,
+// and the changes of their status are announced to their rooms.
+func (s *Chat) SetAway(c echo.Context, conn *websocket.Conn, away bool) error {
+	cl := s.session(c, conn)
+	cl.mu.Lock()
+	cl.away = away
+	cl.mu.Unlock()
+	return s.updatePresence(cl.User, nil)
+}
+
+// ListPresence returns the online users, ordered by username. Users see the users of their company,
+// and admins see everyone. When a room is named, the users present in the room are returned instead.
+func (s *Chat) ListPresence(c echo.Context, roomName string) ([]jobsity.Presence, error) {
+	if roomName == "" {
+		user := s.rbac.User(c)
+		admin := s.isAdmin(c)
+		return s.presence.list(func(p presenceState) bool {
+			return admin || p.CompanyID == user.CompanyID
+		}), nil
+	}
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return nil, err
+	}
+	return s.presence.list(inRoom(room.Key())), nil
+}
+
+// updatePresence records the presence of the user's clients connected to this instance, shares it with
+// the other instances and announces the changes to the user's rooms. Joins are not announced to except.
+func (s *Chat) updatePresence(user jobsity.AuthUser, except *websocket2.Client) error {
+	// Updates are announced in the order they happen
+	s.presence.update.Lock()
+	defer s.presence.update.Unlock()
+
+	p := s.localPresence(user)
+	before, after := s.presence.set(s.instance, p)
+	if err := s.publish(usersTopic, event{Kind: eventPresence, Presence: &p}); err != nil {
+		return err
+	}
+	return s.announcePresence(user, before, after, except)
+}
+
+// SetHeartbeat sets how often the chat tells the other instances it is alive, and checks they are. The
+// presence of the users of an instance missing several heartbeats, like a crashed one, is dropped.
+// Heartbeats are off with 0, the default.
+func (s *Chat) SetHeartbeat(interval time.Duration) {
+	s.heartbeatMu.Lock()
+	defer s.heartbeatMu.Unlock()
+	if s.stopHeartbeat != nil {
+		close(s.stopHeartbeat)
+		s.stopHeartbeat = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	s.stopHeartbeat = make(chan struct{})
+	go s.heartbeat(interval, s.stopHeartbeat)
+}
+
+// heartbeat publishes a heartbeat every interval and expires the presence of the silent instances, until stopped
+func (s *Chat) heartbeat(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			// Failed heartbeats are made up for by the next ones
+			s.publish(usersTopic, event{Kind: eventHeartbeat})
+			s.expirePresence(now.Add(-heartbeatMisses * interval))
+		}
+	}
+}
+
+// expirePresence drops the presence of the instances not heard from since the deadline. Every instance
+// announces the changes to its own clients, since the expired instances cannot.
+func (s *Chat) expirePresence(deadline time.Time) {
+	s.presence.update.Lock()
+	defer s.presence.update.Unlock()
+	for _, change := range s.presence.expire(deadline) {
+		user := jobsity.AuthUser{ID: change.before.UserID, Username: change.before.Username, CompanyID: change.before.CompanyID}
+		// The other users are still announced if one fails
+		s.announceChanges(user, change.before, change.after, nil, s.hub.Broadcast)
+	}
+}
+
+// announcePresence lets the user's rooms know the user joined or left them, or went away or came back.
+// Rooms stopped meanwhile are skipped. Users going offline have their last seen time persisted.
+func (s *Chat) announcePresence(user jobsity.AuthUser, before, after presenceState, except *websocket2.Client) error {
+	return s.announceChanges(user, before, after, except, s.broadcast)
+}
+
+// announceChanges announces the changes of the user's presence with the broadcast function
+func (s *Chat) announceChanges(user jobsity.AuthUser, before, after presenceState, except *websocket2.Client,
+	broadcast func(string, jobsity.Frame, *websocket2.Client) error) error {
+	if before.online() && !after.online() {
+		if err := s.udb.UpdateLastSeen(s.db, user.ID, time.Now()); err != nil {
+			return err
+		}
+	}
+	announce := func(typ, key, roomName string, except *websocket2.Client) error {
+		if !s.hub.Has(key) {
+			return nil
+		}
+		return broadcast(key, jobsity.NewFrame(typ, roomName, user.Username, ""), except)
+	}
+	for key, roomName := range before.Rooms {
+		if _, ok := after.Rooms[key]; !ok {
+			if err := announce(jobsity.FrameLeave, key, roomName, nil); err != nil {
+				return err
+			}
+		}
+	}
+	for key, roomName := range after.Rooms {
+		if _, ok := before.Rooms[key]; !ok {
+			// Users joining a room were already welcomed
+			if err := announce(jobsity.FrameJoin, key, roomName, except); err != nil {
+				return err
+			}
+		}
+	}
+	if !before.online() || !after.online() || before.status() == after.status() {
+		return nil
+	}
+	typ := jobsity.FrameBack
+	if after.status() == jobsity.StatusAway {
+		typ = jobsity.FrameAway
+	}
+	for key, roomName := range after.Rooms {
+		if _, ok := before.Rooms[key]; ok {
+			if err := announce(typ, key, roomName, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// localPresence returns the presence of the user's clients connected to this instance
+func (s *Chat) localPresence(user jobsity.AuthUser) presenceState {
+	p := presenceState{UserID: user.ID, Username: user.Username, CompanyID: user.CompanyID, Rooms: make(map[string]string)}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, cl := range s.sessions {
+		if cl.User.ID != user.ID {
+			continue
+		}
+		cl.mu.Lock()
+		p.Sessions++
+		if cl.away {
+			p.Away++
+		}
+		for _, room := range cl.rooms {
+			p.Rooms[room.Key()] = room.Name
+		}
+		cl.mu.Unlock()
+	}
+	return p
+}
+
+// syncPresence shares the presence of the users connected to this instance with the other instances
+func (s *Chat) syncPresence() {
+	for _, p := range s.presence.local(s.instance) {
+		p := p
+		s.publish(usersTopic, event{Kind: eventPresence, Presence: &p})
+	}
+}
+
+// presenceState represents a user's presence on one or every chat instance
+type presenceState struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	CompanyID int    `json:"company_id"`
+	Sessions  int    `json:"sessions"`
+	// Away is the number of sessions away
+	Away int `json:"away"`
+	// Rooms holds the names of the rooms joined by any session, by key
+	Rooms map[string]string `json:"rooms,omitempty"`
+}
+
+func (p presenceState) online() bool {
+	return p.Sessions > 0
+}
+
+func (p presenceState) status() string {
+	if p.Away == p.Sessions {
+		return jobsity.StatusAway
+	}
+	return jobsity.StatusOnline
+}
+
+// inRoom matches the users present in the room with the key
+func inRoom(key string) func(presenceState) bool {
+	return func(p presenceState) bool {
+		_, ok := p.Rooms[key]
+		return ok
+	}
+}
+
+// presence holds the presence of the online users on every chat instance
+type presence struct {
+	// update serializes the updates of this instance
+	update sync.Mutex
+	mu     sync.RWMutex
+	// users holds the presence of each user by instance
+	users map[int]map[string]presenceState
+	// heard holds when each other instance was last heard from
+	heard map[string]time.Time
+}
+
+// presenceChange represents a user's presence on every instance before and after a change
+type presenceChange struct {
+	before, after presenceState
+}
+
+func newPresence() *presence {
+	return &presence{users: make(map[int]map[string]presenceState), heard: make(map[string]time.Time)}
+}
+
+// hear records that another instance was heard from, reporting whether it was unknown
+func (pr *presence) hear(instance string, at time.Time) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	_, ok := pr.heard[instance]
+	pr.heard[instance] = at
+	return !ok
+}
+
+// expire forgets the instances not heard from since the deadline, dropping the presence of their users,
+// and returns the changes of the users' presence
+func (pr *presence) expire(deadline time.Time) []presenceChange {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	var changes []presenceChange
+	for instance, at := range pr.heard {
+		if at.After(deadline) {
+			continue
+		}
+		delete(pr.heard, instance)
+		for userID, instances := range pr.users {
+			if _, ok := instances[instance]; !ok {
+				continue
+			}
+			before := pr.merged(userID)
+			delete(instances, instance)
+			if len(instances) == 0 {
+				delete(pr.users, userID)
+			}
+			changes = append(changes, presenceChange{before, pr.merged(userID)})
+		}
+	}
+	return changes
+}
+
+// set replaces the user's presence on an instance, returning the user's presence on every instance before and after
+func (pr *presence) set(instance string, p presenceState) (before, after presenceState) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	before = pr.merged(p.UserID)
+	instances, ok := pr.users[p.UserID]
+	switch {
+	case p.online() && !ok:
+		pr.users[p.UserID] = map[string]presenceState{instance: p}
+	case p.online():
+		instances[instance] = p
+	default:
+		delete(instances, instance)
+		if len(instances) == 0 {
+			delete(pr.users, p.UserID)
+		}
+	}
+	return before, pr.merged(p.UserID)
+}
+
+// merged returns the user's presence on every instance
+func (pr *presence) merged(userID int) presenceState {
+	m := presenceState{UserID: userID, Rooms: make(map[string]string)}
+	for _, p := range pr.users[userID] {
+		m.Username, m.CompanyID = p.Username, p.CompanyID
+		m.Sessions += p.Sessions
+		m.Away += p.Away
+		for key, roomName := range p.Rooms {
+			m.Rooms[key] = roomName
+		}
+	}
+	return m
+}
+
+// local returns the presence of the users connected to an instance
+func (pr *presence) local(instance string) []presenceState {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	var local []presenceState
+	for _, instances := range pr.users {
+		if p, ok := instances[instance]; ok {
+			local = append(local, p)
+		}
+	}
+	return local
+}
+
+// list returns the presence of the online users matching the filter, ordered by username
+func (pr *presence) list(match func(presenceState) bool) []jobsity.Presence {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	list := []jobsity.Presence{}
+	for userID := range pr.users {
+		if p := pr.merged(userID); match(p) {
+			list = append(list, jobsity.Presence{UserID: p.UserID, Username: p.Username, Status: p.status()})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Username < list[j].Username
+	})
+	return list
+}
@@ -0,0 +1,151 @@
+package chat_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+func TestPresence(t *testing.T) {
+	var mu sync.Mutex
+	var lastSeen []int
+	udb := mockdb.NewUser()
+	udb.UpdateLastSeenFn = func(db orm.DB, id int, t time.Time) error {
+		mu.Lock()
+		defer mu.Unlock()
+		lastSeen = append(lastSeen, id)
+		return nil
+	}
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: udb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(user string) (*websocket.Conn, *websocket.Conn) {
+		conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+		if err := s.JoinRoom(userCtx(user), conn, "general"); err != nil {
+			t.Fatal(err)
+		}
+		receive(t, peer, "Welcome to the general chat room!")
+		return conn, peer
+	}
+
+	// Users with several sessions join the room once
+	member, memberPeer := join("member")
+	otherMember, otherMemberPeer := join("member")
+	_, ownerPeer := join("owner")
+	receive(t, memberPeer, "owner joined the room")
+	receive(t, otherMemberPeer, "owner joined the room")
+
+	users, err := s.GetUsersInRoom(userCtx("owner"), "general")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"member", "owner"}, users)
+	online, err := s.ListPresence(userCtx("owner"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Presence{
+		{UserID: 3, Username: "member", Status: jobsity.StatusOnline},
+		{UserID: 1, Username: "owner", Status: jobsity.StatusOnline},
+	}, online)
+
+	// Users are away once every session is
+	assert.Nil(t, s.SetAway(userCtx("member"), member, true))
+	_, err = s.HandleFrame(userCtx("member"), otherMember, "general", []byte("/away"))
+	assert.Nil(t, err)
+	receive(t, ownerPeer, "member is away")
+	online, err = s.ListPresence(userCtx("owner"), "general")
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.StatusAway, online[0].Status)
+	assert.Nil(t, s.SetAway(userCtx("member"), member, false))
+	receive(t, ownerPeer, "member is back")
+
+	// Users leave the room and go offline with their last session
+	assert.Nil(t, s.Disconnect(userCtx("member"), member))
+	receive(t, ownerPeer, "member is away")
+	assert.Nil(t, s.LeaveRoom(userCtx("member"), otherMember, "general"))
+	receive(t, ownerPeer, "member left the room")
+	mu.Lock()
+	assert.Empty(t, lastSeen)
+	mu.Unlock()
+	assert.Nil(t, s.Disconnect(userCtx("member"), otherMember))
+	mu.Lock()
+	assert.Equal(t, []int{3}, lastSeen)
+	mu.Unlock()
+
+	online, err = s.ListPresence(userCtx("owner"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Presence{{UserID: 1, Username: "owner", Status: jobsity.StatusOnline}}, online)
+}
+
+func TestListPresence(t *testing.T) {
+	users := map[string]jobsity.AuthUser{
+		"johndoe": {ID: 1, Username: "johndoe", Role: jobsity.UserRole, CompanyID: 1},
+		"janedoe": {ID: 2, Username: "janedoe", Role: jobsity.UserRole, CompanyID: 2},
+		"admin":   {ID: 3, Username: "admin", Role: jobsity.AdminRole},
+	}
+	rbac := userRBAC(func(c echo.Context) jobsity.AuthUser {
+		return users[c.Get("username").(string)]
+	})
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"johndoe", "janedoe"} {
+		conn, _ := mock.NewWSConn(t, ws.ProtocolText)
+		if err := s.JoinRoom(userCtx(user), conn, "general"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		user      string
+		room      string
+		wantErr   bool
+		wantUsers []string
+	}{
+		{
+			name:      "Company users",
+			user:      "johndoe",
+			wantUsers: []string{"johndoe"},
+		},
+		{
+			name:      "Every user for admins",
+			user:      "admin",
+			wantUsers: []string{"janedoe", "johndoe"},
+		},
+		{
+			name:      "Room users",
+			user:      "johndoe",
+			room:      "general",
+			wantUsers: []string{"janedoe", "johndoe"},
+		},
+		{
+			name:    "Fail on unknown room",
+			user:    "johndoe",
+			room:    "random",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			online, err := s.ListPresence(userCtx(tt.user), tt.room)
+			assert.Equal(t, tt.wantErr, err != nil)
+			var got []string
+			for _, p := range online {
+				got = append(got, p.Username)
+			}
+			assert.Equal(t, tt.wantUsers, got)
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Custom errors
+var (
+	ErrRateLimited = echo.NewHTTPError(http.StatusTooManyRequests, "you are sending messages too fast, slow down")
+	ErrRoomBusy    = echo.NewHTTPError(http.StatusTooManyRequests, "this room is receiving too many messages, try again in a moment")
+
+	ErrInvalidSlowMode = echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("slow mode must be between 0 and %d seconds", MaxSlowMode))
+)
+
+// MaxSlowMode is the longest wait between messages a room's slow mode may impose, in seconds
+const MaxSlowMode = 6 * 60 * 60
+
+// floodReason is the reason of the mutes of users who keep sending messages too fast
+const floodReason = "flooding"
+
+// pruneInterval is how often the rate limiter forgets idle users and rooms
+const pruneInterval = time.Minute
+
+// RateLimit holds the message rate limits of the chat. Limits without messages are off, and so is
+// muting when Violations is 0. Limits are enforced per chat instance.
+type RateLimit struct {
+	// UserMessages is the number of messages a user may send at once, refilled over UserInterval
+	UserMessages int
+	UserInterval time.Duration
+	// RoomMessages is the number of messages a room may receive at once, refilled over RoomInterval
+	RoomMessages int
+	RoomInterval time.Duration
+	// Violations is the number of throttled messages within ViolationWindow after which
+	// the user is muted in the room for MuteDuration
+	Violations      int
+	ViolationWindow time.Duration
+	MuteDuration    time.Duration
+}
+
+// SetRateLimit replaces the message rate limits of the chat
+func (s *Chat) SetRateLimit(cfg RateLimit) {
+	s.limiter.configure(cfg)
+}
+
+// throttle checks whether the current user may send another message to a room, under the room's
+// slow mode and the rate limits. Users who keep sending messages too fast are muted in the room, but
+// not for messages throttled because the room as a whole is busy.
+func (s *Chat) throttle(c echo.Context, room jobsity.Room) error {
+	// Room owners and moderators are not slowed down
+	exempt := false
+	if room.SlowMode > 0 {
+		_, err := s.enforceRoomRole(c, room, jobsity.RoomModeratorRole)
+		exempt = err == nil
+	}
+
+	user := s.rbac.User(c)
+	now := time.Now()
+	err := s.limiter.allow(room, user.ID, exempt, now)
+	if err == nil || err == ErrRoomBusy || !s.limiter.violate(room.Key(), user.ID, now) {
+		return err
+	}
+	return s.muteFlooder(room, user)
+}
+
+// muteFlooder mutes a user who kept sending messages too fast to a room and lets the room know
+func (s *Chat) muteFlooder(room jobsity.Room, user jobsity.AuthUser) error {
+	req := Moderation{UserID: user.ID, Reason: floodReason, Duration: s.limiter.muteDuration()}
+	if _, err := s.sanction(room, jobsity.SanctionMute, req, 0); err != nil {
+		return err
+	}
+	if err := s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+		moderationNotice(user.Username+" was muted", req)), nil); err != nil {
+		return err
+	}
+	return echo.NewHTTPError(http.StatusTooManyRequests, moderationNotice("you were muted", req))
+}
+
+// slowModeError tells a user how long to wait before sending another message to a room in slow mode
+func slowModeError(wait time.Duration) error {
+	return echo.NewHTTPError(http.StatusTooManyRequests,
+		fmt.Sprintf("slow mode is on, wait %s before sending another message", formatDuration(wait.Round(time.Second))))
+}
+
+// slowModeNotice announces the slow mode of a room
+func slowModeNotice(seconds int) string {
+	if seconds == 0 {
+		return "Slow mode is off"
+	}
+	return fmt.Sprintf("Slow mode is on: one message every %s", formatDuration(time.Duration(seconds)*time.Second))
+}
+
+// sender identifies a user in a room
+type sender struct {
+	room   string
+	userID int
+}
+
+// limiter enforces the message rate limits with token buckets per user and per room,
+// and the slow mode of the rooms
+type limiter struct {
+	mu         sync.Mutex
+	cfg        RateLimit
+	users      *buckets
+	rooms      *buckets
+	sent       map[sender]time.Time
+	violations map[sender][]time.Time
+	pruned     time.Time
+}
+
+func newLimiter(cfg RateLimit) *limiter {
+	l := &limiter{}
+	l.configure(cfg)
+	return l
+}
+
+func (l *limiter) configure(cfg RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	l.users = newBuckets(cfg.UserMessages, cfg.UserInterval)
+	l.rooms = newBuckets(cfg.RoomMessages, cfg.RoomInterval)
+	l.sent = make(map[sender]time.Time)
+	l.violations = make(map[sender][]time.Time)
+}
+
+func (l *limiter) muteDuration() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg.MuteDuration
+}
+
+// allow takes a message of the user to the room from the buckets, unless the user or the room
+// ran out of messages, or the user sent a message to the room within its slow mode
+func (l *limiter) allow(room jobsity.Room, userID int, exempt bool, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	key := sender{room.Key(), userID}
+	if slow := time.Duration(room.SlowMode) * time.Second; slow > 0 && !exempt {
+		if wait := l.sent[key].Add(slow).Sub(now); wait > 0 {
+			return slowModeError(wait)
+		}
+	}
+	user := l.users.get(strconv.Itoa(userID), now)
+	roomBucket := l.rooms.get(key.room, now)
+	if !user.available() {
+		return ErrRateLimited
+	}
+	if !roomBucket.available() {
+		return ErrRoomBusy
+	}
+	user.take()
+	roomBucket.take()
+	l.sent[key] = now
+	return nil
+}
+
+// violate records a throttled message of the user to the room, reporting whether the user
+// reached the violations limit. Violations are forgotten once reported.
+func (l *limiter) violate(room string, userID int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.Violations < 1 || l.cfg.MuteDuration <= 0 {
+		return false
+	}
+	key := sender{room, userID}
+	recent := l.violations[key][:0]
+	for _, t := range l.violations[key] {
+		if now.Sub(t) < l.cfg.ViolationWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) < l.cfg.Violations {
+		l.violations[key] = recent
+		return false
+	}
+	delete(l.violations, key)
+	return true
+}
+
+// prune forgets the full buckets, the messages older than any slow mode and the expired violations
+func (l *limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < pruneInterval {
+		return
+	}
+	l.pruned = now
+	l.users.prune(now)
+	l.rooms.prune(now)
+	for key, t := range l.sent {
+		if now.Sub(t) >= MaxSlowMode*time.Second {
+			delete(l.sent, key)
+		}
+	}
+	for key, ts := range l.violations {
+		if now.Sub(ts[len(ts)-1]) >= l.cfg.ViolationWindow {
+			delete(l.violations, key)
+		}
+	}
+}
+
+// buckets holds the token buckets of a limit, by key
+type buckets struct {
+	size     int
+	interval time.Duration
+	m        map[string]*bucket
+}
+
+func newBuckets(size int, interval time.Duration) *buckets {
+	return &buckets{size: size, interval: interval, m: make(map[string]*bucket)}
+}
+
+// get returns the refilled bucket of the key, or nil when the limit is off
+func (bs *buckets) get(key string, now time.Time) *bucket {
+	if bs.size < 1 || bs.interval <= 0 {
+		return nil
+	}
+	b, ok := bs.m[key]
+	if !ok {
+		b = &bucket{tokens: float64(bs.size), last: now}
+		bs.m[key] = b
+	}
+	b.refill(bs.size, bs.interval, now)
+	return b
+}
+
+// prune forgets the buckets that refilled completely
+func (bs *buckets) prune(now time.Time) {
+	for key, b := range bs.m {
+		if b.refill(bs.size, bs.interval, now); b.tokens >= float64(bs.size) {
+			delete(bs.m, key)
+		}
+	}
+}
+
+// bucket holds the messages left to send, refilled at a steady rate up to the bucket's size
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(size int, interval time.Duration, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(size) * elapsed.Seconds() / interval.Seconds()
+		if b.tokens > float64(size) {
+			b.tokens = float64(size)
+		}
+		b.last = now
+	}
+}
+
+// available reports whether a message is left. Nil buckets, of limits that are off, never run out.
+func (b *bucket) available() bool {
+	return b == nil || b.tokens >= 1
+}
+
+func (b *bucket) take() {
+	if b != nil {
+		b.tokens--
+	}
+}
@@ -0,0 +1,115 @@
+package chat_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+// receiveFrame receives the next frame of a JSON connection, checking its type and text
+func receiveFrame(t *testing.T, conn *websocket.Conn, typ, text string) {
+	var f jobsity.Frame
+	if err := websocket.JSON.Receive(conn, &f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, typ, f.Type)
+	assert.Equal(t, text, f.Payload.Text)
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	s.SetRateLimit(chat.RateLimit{
+		UserMessages:    2,
+		UserInterval:    time.Hour,
+		Violations:      2,
+		ViolationWindow: time.Minute,
+		MuteDuration:    time.Minute,
+	})
+	_, moderatorPeer := joinPrivateRoom(t, s, "moderator")
+	member, memberPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	if _, err := s.HandleFrame(userCtx("member"), member, "", []byte(`{"version":1,"type":"join","room":"hr"}`)); err != nil {
+		t.Fatal(err)
+	}
+	receiveFrame(t, memberPeer, jobsity.FrameSystem, "Welcome to the hr chat room!")
+	receive(t, moderatorPeer, "member joined the room")
+
+	for _, text := range []string{"one", "two"} {
+		assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", text))
+		receiveFrame(t, memberPeer, jobsity.FrameMessage, text)
+	}
+
+	// Throttled messages are answered with a warning
+	_, err := s.HandleFrame(userCtx("member"), member, "hr", []byte(`{"version":1,"type":"message","room":"hr","payload":{"text":"three"}}`))
+	assert.Equal(t, chat.ErrRateLimited, err)
+	receiveFrame(t, memberPeer, jobsity.FrameWarning, "you are sending messages too fast, slow down")
+
+	// Repeated violations mute the user
+	err = s.SendMessage(userCtx("member"), member, "hr", "four")
+	assert.Equal(t, echo.NewHTTPError(http.StatusTooManyRequests, "you were muted for 1m: flooding"), err)
+	receiveFrame(t, memberPeer, jobsity.FrameSystem, "member was muted for 1m: flooding")
+	assert.Equal(t, chat.ErrMuted, s.SendMessage(userCtx("member"), member, "hr", "five"))
+	receive(t, moderatorPeer, "member: one", "member: two", "member was muted for 1m: flooding")
+
+	// Other users have their own limits
+	owner, _ := joinPrivateRoom(t, s, "owner")
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "hr", "hello"))
+}
+
+func TestRoomRateLimit(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	s.SetRateLimit(chat.RateLimit{
+		RoomMessages:    1,
+		RoomInterval:    time.Hour,
+		Violations:      1,
+		ViolationWindow: time.Minute,
+		MuteDuration:    time.Minute,
+	})
+	owner, _ := joinPrivateRoom(t, s, "owner")
+	member, _ := joinPrivateRoom(t, s, "member")
+
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "hr", "hello"))
+	// A busy room is not the member's fault, so it does not count as a violation
+	assert.Equal(t, chat.ErrRoomBusy, s.SendMessage(userCtx("member"), member, "hr", "hi"))
+	assert.Equal(t, chat.ErrRoomBusy, s.SendMessage(userCtx("member"), member, "hr", "hi"))
+}
+
+func TestSlowMode(t *testing.T) {
+	s := newPrivateRoom(t, ws.NewHub(ws.Config{}))
+	moderator, moderatorPeer := joinPrivateRoom(t, s, "moderator")
+	member, _ := joinPrivateRoom(t, s, "member")
+	receive(t, moderatorPeer, "member joined the room")
+
+	slowMode := 90
+	_, err := s.UpdateRoom(userCtx("moderator"), chat.UpdateRoom{Name: "hr", SlowMode: &slowMode})
+	assert.Equal(t, echo.ErrForbidden, err)
+	invalid := chat.MaxSlowMode + 1
+	_, err = s.UpdateRoom(userCtx("owner"), chat.UpdateRoom{Name: "hr", SlowMode: &invalid})
+	assert.Equal(t, chat.ErrInvalidSlowMode, err)
+	room, err := s.UpdateRoom(userCtx("owner"), chat.UpdateRoom{Name: "hr", SlowMode: &slowMode})
+	assert.Nil(t, err)
+	assert.Equal(t, 90, room.SlowMode)
+	receive(t, moderatorPeer, "Slow mode is on: one message every 1m30s")
+
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", "hello"))
+	err = s.SendMessage(userCtx("member"), member, "hr", "hello again")
+	assert.Equal(t, echo.NewHTTPError(http.StatusTooManyRequests, "slow mode is on, wait 1m30s before sending another message"), err)
+
+	// Room moderators are not slowed down
+	assert.Nil(t, s.SendMessage(userCtx("moderator"), moderator, "hr", "one"))
+	assert.Nil(t, s.SendMessage(userCtx("moderator"), moderator, "hr", "two"))
+
+	off := 0
+	_, err = s.UpdateRoom(userCtx("owner"), chat.UpdateRoom{Name: "hr", SlowMode: &off})
+	assert.Nil(t, err)
+	receive(t, moderatorPeer, "member: hello", "moderator: one", "moderator: two", "Slow mode is off")
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "hr", "hello again"))
+}
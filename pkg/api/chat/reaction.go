@@ -0,0 +1,173 @@
+package chat
+
+import (
+	"net/http"
+	"unicode"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// maxEmojiLength is the longest emoji accepted as a reaction, in bytes. It leaves room for sequences
+// joining several emojis, like family emojis or flags.
+const maxEmojiLength = 32
+
+// Code points joining, modifying and completing emojis into sequences
+const (
+	zeroWidthJoiner   = '\u200d'
+	textVariation     = '\ufe0e'
+	emojiVariation    = '\ufe0f'
+	combiningKeycap   = '\u20e3'
+	firstSkinTone     = '\U0001f3fb'
+	lastSkinTone      = '\U0001f3ff'
+	firstRegionalFlag = '\U0001f1e6'
+	lastRegionalFlag  = '\U0001f1ff'
+	firstTag          = '\U000e0020'
+	lastTag           = '\U000e007f'
+)
+
+// pictographs are the code points that are emojis by themselves
+var pictographs = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21a9, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23cf, Hi: 0x23cf, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23f3, Stride: 1},
+		{Lo: 0x23f8, Hi: 0x23fa, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25ab, Stride: 1},
+		{Lo: 0x25b6, Hi: 0x25b6, Stride: 1},
+		{Lo: 0x25c0, Hi: 0x25c0, Stride: 1},
+		{Lo: 0x25fb, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b07, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1},
+		{Lo: 0x2b55, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+	},
+}
+
+// Custom errors
+var (
+	ErrInvalidEmoji = echo.NewHTTPError(http.StatusBadRequest, "reaction must be an emoji")
+)
+
+// ToggleReaction adds the current user's reaction with the emoji to a room's message, or removes it if the
+// user already reacted with the same emoji, and lets the room know. It returns the message with its reactions.
+func (s *Chat) ToggleReaction(c echo.Context, roomName string, id int, emoji string) (jobsity.Message, error) {
+	if !validEmoji(emoji) {
+		return jobsity.Message{}, ErrInvalidEmoji
+	}
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return jobsity.Message{}, err
+	}
+	if room.Archived {
+		return jobsity.Message{}, ErrRoomArchived
+	}
+	if _, err := s.roomMessage(room, id); err != nil {
+		return jobsity.Message{}, err
+	}
+	user := s.rbac.User(c)
+	if err := s.enforceNotSanctioned(room, user.ID, jobsity.SanctionMute); err != nil {
+		return jobsity.Message{}, err
+	}
+
+	added, err := s.mdb.ToggleReaction(s.db, jobsity.Reaction{MessageID: id, UserID: user.ID, Username: user.Username, Emoji: emoji})
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	msg, err := s.mdb.View(s.db, id)
+	if err != nil {
+		return jobsity.Message{}, err
+	}
+	msg.Room = room.Name
+
+	typ := jobsity.FrameReactionRemoved
+	if added {
+		typ = jobsity.FrameReactionAdded
+	}
+	f := jobsity.NewFrame(typ, room.Name, user.Username, emoji)
+	f.ID = msg.ID
+	return msg, s.broadcastRunning(room, f)
+}
+
+// validEmoji reports whether the text is a single emoji, which may be a sequence of emojis joined by
+// zero width joiners, like family emojis
+func validEmoji(text string) bool {
+	if text == "" || len(text) > maxEmojiLength {
+		return false
+	}
+	runes := []rune(text)
+	for i := 0; ; i++ {
+		if i = emojiElement(runes, i); i < 0 {
+			return false
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return false
+		}
+	}
+}
+
+// emojiElement reads the emoji starting at runes[i], with its modifiers, returning where it ends,
+// or -1 when there is none. Emojis are pictographs, pairs of regional indicators making flags,
+// and keycaps.
+func emojiElement(runes []rune, i int) int {
+	if i >= len(runes) {
+		return -1
+	}
+	r := runes[i]
+	switch {
+	case r >= firstRegionalFlag && r <= lastRegionalFlag:
+		if i+1 < len(runes) && runes[i+1] >= firstRegionalFlag && runes[i+1] <= lastRegionalFlag {
+			return i + 2
+		}
+		return -1
+	case r == '#' || r == '*' || r >= '0' && r <= '9':
+		i++
+		if i < len(runes) && runes[i] == emojiVariation {
+			i++
+		}
+		if i < len(runes) && runes[i] == combiningKeycap {
+			return i + 1
+		}
+		return -1
+	case !unicode.Is(pictographs, r):
+		return -1
+	}
+	i++
+	if i < len(runes) && (runes[i] == textVariation || runes[i] == emojiVariation) {
+		i++
+	}
+	if i < len(runes) && runes[i] >= firstSkinTone && runes[i] <= lastSkinTone {
+		i++
+	}
+	// Tags spell subdivision flags, like Scotland's
+	for i < len(runes) && runes[i] >= firstTag && runes[i] <= lastTag {
+		i++
+	}
+	return i
+}
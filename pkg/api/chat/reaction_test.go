@@ -0,0 +1,170 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveReaction receives the next frame of a JSON connection, checking it is the user's reaction to a message
+func receiveReaction(t *testing.T, conn *websocket.Conn, typ, sender string, messageID int, emoji string) {
+	var f jobsity.Frame
+	if err := websocket.JSON.Receive(conn, &f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, typ, f.Type)
+	assert.Equal(t, sender, f.Sender)
+	assert.Equal(t, messageID, f.ID)
+	assert.Equal(t, emoji, f.Payload.Text)
+}
+
+func TestToggleReaction(t *testing.T) {
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 3}, Username: "member"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moderator, moderatorPeer := mock.NewWSConn(t, ws.ProtocolText)
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, _ := mock.NewWSConn(t, ws.ProtocolJSON)
+	assert.Nil(t, s.JoinRoom(userCtx("moderator"), moderator, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	receive(t, moderatorPeer, "Welcome to the general chat room!", "owner joined the room", "member joined the room")
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "lunch?"))
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "lunch?")
+
+	cases := []struct {
+		name    string
+		user    string
+		room    string
+		id      int
+		emoji   string
+		wantErr error
+	}{
+		{
+			name:    "Fail on empty emoji",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on text",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			emoji:   ":thumbsup:",
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on digit",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			emoji:   "1",
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on punctuation",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			emoji:   "!!!",
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on several emojis",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			emoji:   "👍👎",
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on dangling joiner",
+			user:    "member",
+			room:    "general",
+			id:      1,
+			emoji:   "👨\u200d",
+			wantErr: chat.ErrInvalidEmoji,
+		},
+		{
+			name:    "Fail on unknown room",
+			user:    "member",
+			room:    "random",
+			id:      1,
+			emoji:   "👍",
+			wantErr: pgsql.ErrRoomNotFound,
+		},
+		{
+			name:    "Fail on unknown message",
+			user:    "member",
+			room:    "general",
+			id:      2,
+			emoji:   "👍",
+			wantErr: pgsql.ErrMessageNotFound,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ToggleReaction(userCtx(tt.user), tt.room, tt.id, tt.emoji)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+
+	// Reactions are added, counted by emoji, and pushed to the room
+	_, err = s.HandleFrame(userCtx("member"), member, "general", []byte(`{"version":1,"type":"react","id":1,"payload":{"text":"👍"}}`))
+	assert.Nil(t, err)
+	receiveReaction(t, ownerPeer, jobsity.FrameReactionAdded, "member", 1, "👍")
+	msg, err := s.ToggleReaction(userCtx("owner"), "general", 1, "👍")
+	assert.Nil(t, err)
+	receiveReaction(t, ownerPeer, jobsity.FrameReactionAdded, "owner", 1, "👍")
+	assert.Equal(t, "general", msg.Room)
+	assert.Equal(t, []jobsity.ReactionCount{{Emoji: "👍", Count: 2, Users: []string{"member", "owner"}}}, msg.Reactions)
+
+	// Reacting again with the same emoji removes the reaction
+	msg, err = s.ToggleReaction(userCtx("member"), "general", 1, "👍")
+	assert.Nil(t, err)
+	receiveReaction(t, ownerPeer, jobsity.FrameReactionRemoved, "member", 1, "👍")
+	assert.Equal(t, []jobsity.ReactionCount{{Emoji: "👍", Count: 1, Users: []string{"owner"}}}, msg.Reactions)
+
+	// Emojis may be sequences, like variations, skin tones, families, flags and keycaps
+	for _, emoji := range []string{"❤️", "👍🏽", "👨\u200d👩\u200d👧", "🇦🇷", "🏴\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f", "1️⃣"} {
+		_, err = s.ToggleReaction(userCtx("owner"), "general", 1, emoji)
+		assert.Nil(t, err)
+		receiveReaction(t, ownerPeer, jobsity.FrameReactionAdded, "owner", 1, emoji)
+		_, err = s.ToggleReaction(userCtx("owner"), "general", 1, emoji)
+		assert.Nil(t, err)
+		receiveReaction(t, ownerPeer, jobsity.FrameReactionRemoved, "owner", 1, emoji)
+	}
+
+	// Histories hold the reactions
+	msgs, err := s.ListMessages(userCtx("member"), "general", jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, msg.Reactions, msgs[0].Reactions)
+	}
+
+	// Muted users cannot react
+	_, err = s.MuteUser(userCtx("admin"), "general", chat.Moderation{UserID: 3, Duration: time.Hour})
+	assert.Nil(t, err)
+	_, err = s.ToggleReaction(userCtx("member"), "general", 1, "🍕")
+	assert.Equal(t, chat.ErrMuted, err)
+
+	// Plain text clients are not sent reactions
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "bye"))
+	receive(t, moderatorPeer, "owner: lunch?", "member was muted by admin for 1h", "owner: bye")
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Custom errors
+var (
+	ErrInvalidMessageID = echo.NewHTTPError(http.StatusBadRequest, "message id must be positive")
+)
+
+// SetReadReceipts sets the largest number of users present in a room for read receipts to be sent to it.
+// Read receipts are off with 0, the default.
+func (s *Chat) SetReadReceipts(maxUsers int) {
+	s.receipts.Store(int32(maxUsers))
+}
+
+// MarkRead moves the current user's read cursor of a room forward to a message of the room, and returns
+// the cursor. When the cursor moves, rooms with few enough users present are sent a read receipt.
+func (s *Chat) MarkRead(c echo.Context, roomName string, messageID int) (jobsity.ReadCursor, error) {
+	if messageID <= 0 {
+		return jobsity.ReadCursor{}, ErrInvalidMessageID
+	}
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.ReadCursor{}, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return jobsity.ReadCursor{}, err
+	}
+
+	user := s.rbac.User(c)
+	key := room.Key()
+	moved, err := s.rcdb.MarkRead(s.db, user.ID, key, messageID)
+	if err != nil {
+		return jobsity.ReadCursor{}, err
+	}
+	cursors, err := s.rcdb.List(s.db, user.ID, []string{key})
+	if err != nil || len(cursors) == 0 {
+		return jobsity.ReadCursor{}, err
+	}
+	cursor := cursors[0]
+	cursor.Room = room.Name
+	if !moved || !s.sendsReceipts(key) {
+		return cursor, nil
+	}
+	f := jobsity.NewFrame(jobsity.FrameRead, room.Name, user.Username, "")
+	f.ID = cursor.LastReadID
+	return cursor, s.broadcast(key, f, nil)
+}
+
+// sendsReceipts reports whether read receipts are sent to the running room with the key
+func (s *Chat) sendsReceipts(key string) bool {
+	max := int(s.receipts.Load())
+	if max <= 0 || !s.hub.Has(key) {
+		return false
+	}
+	return len(s.presence.list(inRoom(key))) <= max
+}
+
+// setReadCursors sets the current user's read cursor of each room
+func (s *Chat) setReadCursors(c echo.Context, rooms []jobsity.Room) error {
+	keys := make([]string, len(rooms))
+	for i, room := range rooms {
+		keys[i] = room.Key()
+	}
+	cursors, err := s.rcdb.List(s.db, s.rbac.User(c).ID, keys)
+	if err != nil {
+		return err
+	}
+	for i := range cursors {
+		rooms[i].LastReadID = cursors[i].LastReadID
+		rooms[i].Unread = cursors[i].Unread
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveReceipt receives the next frame of a JSON connection, checking it is the user's read receipt of a message
+func receiveReceipt(t *testing.T, conn *websocket.Conn, sender string, messageID int) {
+	var f jobsity.Frame
+	if err := websocket.JSON.Receive(conn, &f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, jobsity.FrameRead, f.Type)
+	assert.Equal(t, sender, f.Sender)
+	assert.Equal(t, messageID, f.ID)
+}
+
+func TestMarkRead(t *testing.T) {
+	mdb := mockdb.NewMessage()
+	rcdb := mockdb.NewRead()
+	cursors := rcdb.ListFn
+	rcdb.ListFn = func(db orm.DB, userID int, rooms []string) ([]jobsity.ReadCursor, error) {
+		list, err := cursors(db, userID, rooms)
+		for i, cursor := range list {
+			msgs, _ := mdb.List(db, cursor.Room, jobsity.Pagination{})
+			for _, msg := range msgs {
+				if msg.ID > cursor.LastReadID && msg.UserID != userID {
+					list[i].Unread++
+				}
+			}
+		}
+		return list, err
+	}
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Reads: rcdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetReadReceipts(2)
+
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, memberPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	receiveFrame(t, memberPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	for _, text := range []string{"one", "two"} {
+		assert.Nil(t, s.SendMessage(userCtx("member"), member, "general", text))
+		receiveFrame(t, ownerPeer, jobsity.FrameMessage, text)
+		receiveFrame(t, memberPeer, jobsity.FrameMessage, text)
+	}
+
+	// Rooms are listed with their unread messages
+	rooms, err := s.ListRooms(userCtx("owner"), false, jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, rooms, 1) {
+		assert.Equal(t, 0, rooms[0].LastReadID)
+		assert.Equal(t, 2, rooms[0].Unread)
+	}
+	rooms, err = s.ListRooms(userCtx("member"), false, jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, rooms, 1) {
+		assert.Equal(t, 0, rooms[0].Unread)
+	}
+
+	_, err = s.MarkRead(userCtx("owner"), "general", 0)
+	assert.Equal(t, chat.ErrInvalidMessageID, err)
+	_, err = s.MarkRead(userCtx("owner"), "random", 1)
+	assert.NotNil(t, err)
+
+	// Small rooms are sent read receipts as cursors move forward
+	_, err = s.HandleFrame(userCtx("owner"), owner, "general", []byte(`{"version":1,"type":"read","id":1}`))
+	assert.Nil(t, err)
+	receiveReceipt(t, memberPeer, "owner", 1)
+	receiveReceipt(t, ownerPeer, "owner", 1)
+	cursor, err := s.MarkRead(userCtx("owner"), "general", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.ReadCursor{UserID: 1, Room: "general", LastReadID: 2}, cursor)
+	receiveReceipt(t, memberPeer, "owner", 2)
+	cursor, err = s.MarkRead(userCtx("owner"), "general", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cursor.LastReadID)
+
+	rooms, err = s.ListRooms(userCtx("owner"), false, jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, rooms, 1) {
+		assert.Equal(t, 2, rooms[0].LastReadID)
+		assert.Equal(t, 0, rooms[0].Unread)
+	}
+
+	// Larger rooms are not sent read receipts
+	moderator, _ := mock.NewWSConn(t, ws.ProtocolText)
+	assert.Nil(t, s.JoinRoom(userCtx("moderator"), moderator, "general"))
+	receiveFrom(t, memberPeer, jobsity.FrameJoin, "moderator")
+	_, err = s.MarkRead(userCtx("member"), "general", 2)
+	assert.Nil(t, err)
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "three"))
+	receiveFrame(t, memberPeer, jobsity.FrameMessage, "three")
+}
@@ -0,0 +1,328 @@
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	"my-chat-jobsity-challenge/pkg/utl/query"
+)
+
+// Custom errors
+var (
+	ErrInvalidRoomName = echo.NewHTTPError(http.StatusBadRequest,
+		"room name must have up to 64 letters, digits, dashes or underscores")
+	ErrRoomArchived = echo.NewHTTPError(http.StatusBadRequest, "room is archived")
+)
+
+var roomNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// defaultRoom is the name of the room provisioned for every company, and the prefix of the rooms
+// provisioned for every location
+const defaultRoom = "general"
+
+// provisionRooms persists the initial rooms and the default rooms of the active companies and
+// locations missing from the database, then starts every active room
+func (s *Chat) provisionRooms(rooms []string) error {
+	for _, name := range rooms {
+		if err := s.provisionRoom(jobsity.Room{Name: name}); err != nil {
+			return err
+		}
+	}
+
+	companies, err := s.tdb.Companies(s.db)
+	if err != nil {
+		return err
+	}
+	for _, company := range companies {
+		if err := s.provisionCompanyRoom(company); err != nil {
+			return err
+		}
+	}
+	locations, err := s.tdb.Locations(s.db)
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		if err := s.provisionLocationRoom(location); err != nil {
+			return err
+		}
+	}
+
+	active, err := s.rdb.List(s.db, nil, false, jobsity.Pagination{})
+	if err != nil {
+		return err
+	}
+	for _, room := range active {
+		if err := s.openRoom(room.Key(), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provisionTenantRooms persists the default rooms of the user's company and location when missing, like
+// for the companies and locations created after startup. Inactive ones are skipped.
+func (s *Chat) provisionTenantRooms(user jobsity.AuthUser) error {
+	if _, ok := s.provisioned.Load(companyKey(user.CompanyID)); user.CompanyID != 0 && !ok {
+		company, err := s.tdb.Company(s.db, user.CompanyID)
+		if err != nil && err != pgsql.ErrCompanyNotFound {
+			return err
+		}
+		if err == nil {
+			if err := s.provisionCompanyRoom(company); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := s.provisioned.Load(locationKey(user.LocationID)); user.LocationID != 0 && !ok {
+		location, err := s.tdb.Location(s.db, user.LocationID)
+		if err != nil && err != pgsql.ErrLocationNotFound {
+			return err
+		}
+		if err == nil {
+			return s.provisionLocationRoom(location)
+		}
+	}
+	return nil
+}
+
+// provisionCompanyRoom persists the default room of the company when missing
+func (s *Chat) provisionCompanyRoom(company jobsity.Company) error {
+	if err := s.provisionRoom(jobsity.Room{Name: defaultRoom, CompanyID: company.ID}); err != nil {
+		return err
+	}
+	s.provisioned.Store(companyKey(company.ID), true)
+	return nil
+}
+
+// provisionLocationRoom persists the default room of the location when missing
+func (s *Chat) provisionLocationRoom(location jobsity.Location) error {
+	if err := s.provisionRoom(jobsity.Room{
+		Name:       locationRoomName(location),
+		CompanyID:  location.CompanyID,
+		LocationID: location.ID,
+	}); err != nil {
+		return err
+	}
+	s.provisioned.Store(locationKey(location.ID), true)
+	return nil
+}
+
+func companyKey(id int) string {
+	return "company:" + strconv.Itoa(id)
+}
+
+func locationKey(id int) string {
+	return "location:" + strconv.Itoa(id)
+}
+
+// provisionRoom persists the room unless its company already has a room with the same name
+func (s *Chat) provisionRoom(room jobsity.Room) error {
+	existing, err := s.rdb.View(s.db, room.Name, room.CompanyID)
+	if err == nil && existing.CompanyID == room.CompanyID {
+		return nil
+	}
+	if err != nil && err != pgsql.ErrRoomNotFound {
+		return err
+	}
+	_, err = s.rdb.Create(s.db, room)
+	return err
+}
+
+var roomNameInvalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// locationRoomName returns the name of the default room of a location, like general-new-york
+func locationRoomName(location jobsity.Location) string {
+	slug := strings.Trim(roomNameInvalidChars.ReplaceAllString(strings.ToLower(location.Name), "-"), "-")
+	if slug == "" {
+		slug = strconv.Itoa(location.ID)
+	}
+	name := defaultRoom + "-" + slug
+	if len(name) > 64 {
+		name = strings.TrimRight(name[:64], "-")
+	}
+	return name
+}
+
+// CreateRoom persists a new room, owned by its creator, and starts it. Rooms are created in the
+// creator's company unless another one is given, while admins create global rooms by default.
+// Company admins create rooms in their company, and location admins in their location.
+func (s *Chat) CreateRoom(c echo.Context, req jobsity.Room) (jobsity.Room, error) {
+	user := s.rbac.User(c)
+	if req.CompanyID == 0 && !s.isAdmin(c) {
+		req.CompanyID = user.CompanyID
+	}
+	if !s.isRoomAdmin(c, req) {
+		return jobsity.Room{}, echo.ErrForbidden
+	}
+	if !roomNameRegexp.MatchString(req.Name) {
+		return jobsity.Room{}, ErrInvalidRoomName
+	}
+	if err := s.filters.validate(req.Filters); err != nil {
+		return jobsity.Room{}, err
+	}
+
+	req.CreatedBy = user.ID
+	room, err := s.rdb.Create(s.db, req)
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	if _, err := s.rmdb.Create(s.db, jobsity.RoomMember{
+		RoomID: room.ID,
+		UserID: room.CreatedBy,
+		Role:   jobsity.RoomOwnerRole,
+	}); err != nil {
+		return jobsity.Room{}, err
+	}
+	return room, s.openRoom(room.Key(), true)
+}
+
+// ListRooms returns a page of either the active or the archived rooms, with the current user's read cursor
+// and unread message count. Users are listed the global rooms, their company's rooms and their location's
+// rooms, while private rooms are only listed to their members and admins.
+func (s *Chat) ListRooms(c echo.Context, archived bool, p jobsity.Pagination) ([]jobsity.Room, error) {
+	user := s.rbac.User(c)
+	if err := s.provisionTenantRooms(user); err != nil {
+		return nil, err
+	}
+	q := query.Rooms(user)
+	if !s.isAdmin(c) {
+		q = append(q, jobsity.ListQuery{
+			Query: "private = false or id in (select room_id from room_members where user_id = ? and deleted_at is null)",
+			ID:    user.ID,
+		})
+	}
+	rooms, err := s.rdb.List(s.db, q, archived, p)
+	if err != nil {
+		return nil, err
+	}
+	return rooms, s.setReadCursors(c, rooms)
+}
+
+// ViewRoom returns single room
+func (s *Chat) ViewRoom(c echo.Context, roomName string) (jobsity.Room, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	return room, s.enforceRoomAccess(c, room)
+}
+
+// UpdateRoom contains room's information used for updating. Nil fields are left unchanged.
+type UpdateRoom struct {
+	Name        string
+	Topic       *string
+	Description *string
+	Private     *bool
+	SlowMode    *int
+	Filters     *[]string
+}
+
+// UpdateRoom updates room's topic, description, visibility, slow mode and message filters. Only room owners
+// and admins can update a room. Topic and slow mode changes are announced to the room.
+func (s *Chat) UpdateRoom(c echo.Context, r UpdateRoom) (jobsity.Room, error) {
+	room, err := s.room(c, r.Name)
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	if _, err := s.enforceRoomRole(c, room, jobsity.RoomOwnerRole); err != nil {
+		return jobsity.Room{}, err
+	}
+
+	topicChanged := r.Topic != nil && *r.Topic != room.Topic
+	if r.Topic != nil {
+		room.Topic = *r.Topic
+	}
+	if r.Description != nil {
+		room.Description = *r.Description
+	}
+	if r.Private != nil {
+		room.Private = *r.Private
+	}
+	slowModeChanged := r.SlowMode != nil && *r.SlowMode != room.SlowMode
+	if r.SlowMode != nil {
+		if *r.SlowMode < 0 || *r.SlowMode > MaxSlowMode {
+			return jobsity.Room{}, ErrInvalidSlowMode
+		}
+		room.SlowMode = *r.SlowMode
+	}
+	if r.Filters != nil {
+		if err := s.filters.validate(*r.Filters); err != nil {
+			return jobsity.Room{}, err
+		}
+		room.Filters = *r.Filters
+	}
+	if err := s.rdb.Update(s.db, room); err != nil {
+		return jobsity.Room{}, err
+	}
+
+	if topicChanged && !room.Archived {
+		if err := s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+			fmt.Sprintf("The topic is now: %s", room.Topic)), nil); err != nil {
+			return jobsity.Room{}, err
+		}
+	}
+	if slowModeChanged && !room.Archived {
+		if err := s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "",
+			slowModeNotice(room.SlowMode)), nil); err != nil {
+			return jobsity.Room{}, err
+		}
+	}
+	return room, nil
+}
+
+// ArchiveRoom stops a room, keeping its history. Archived rooms cannot be joined. Only room owners
+// and admins can archive a room.
+func (s *Chat) ArchiveRoom(c echo.Context, roomName string) (jobsity.Room, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Room{}, err
+	}
+	if _, err := s.enforceRoomRole(c, room, jobsity.RoomOwnerRole); err != nil {
+		return jobsity.Room{}, err
+	}
+	if room.Archived {
+		return jobsity.Room{}, ErrRoomArchived
+	}
+
+	room.Archived = true
+	if err := s.rdb.Update(s.db, room); err != nil {
+		return jobsity.Room{}, err
+	}
+	return room, s.stopRoom(room, fmt.Sprintf("Room %s was archived", room.Name))
+}
+
+// DeleteRoom deletes a room and its history, notifying the room's clients and stopping it. Global
+// rooms are deleted by admins, and the rooms of a company or location by their admins.
+func (s *Chat) DeleteRoom(c echo.Context, roomName string) error {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return err
+	}
+	if !s.isRoomAdmin(c, room) {
+		return echo.ErrForbidden
+	}
+
+	if err := s.rdb.Delete(s.db, room); err != nil {
+		return err
+	}
+	if room.Archived {
+		return nil
+	}
+	return s.stopRoom(room, fmt.Sprintf("Room %s was deleted", room.Name))
+}
+
+// stopRoom notifies the room's clients and stops it on every instance
+func (s *Chat) stopRoom(room jobsity.Room, notice string) error {
+	if err := s.broadcast(room.Key(), jobsity.NewFrame(jobsity.FrameSystem, room.Name, "", notice), nil); err != nil {
+		return err
+	}
+	return s.closeRoom(room.Key(), true)
+}
@@ -0,0 +1,468 @@
+package chat_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// roleRBAC returns a RBAC mock authenticating a user with the given role and without company
+func roleRBAC(role jobsity.AccessRole) *mock.RBAC {
+	return tenantRBAC(role, 0, 0)
+}
+
+// tenantRBAC returns a RBAC mock authenticating a user with the given role, company and location
+func tenantRBAC(role jobsity.AccessRole, companyID, locationID int) *mock.RBAC {
+	return userRBAC(func(echo.Context) jobsity.AuthUser {
+		return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: role, CompanyID: companyID, LocationID: locationID}
+	})
+}
+
+// userRBAC returns a RBAC mock authenticating the user returned by user, enforcing roles,
+// companies and locations like the RBAC service does
+func userRBAC(user func(echo.Context) jobsity.AuthUser) *mock.RBAC {
+	enforceRole := func(c echo.Context, r jobsity.AccessRole) error {
+		if user(c).Role > r {
+			return echo.ErrForbidden
+		}
+		return nil
+	}
+	return &mock.RBAC{
+		UserFn:        user,
+		EnforceRoleFn: enforceRole,
+		EnforceCompanyFn: func(c echo.Context, id int) error {
+			if enforceRole(c, jobsity.AdminRole) == nil {
+				return nil
+			}
+			if enforceRole(c, jobsity.CompanyAdminRole) != nil || user(c).CompanyID != id {
+				return echo.ErrForbidden
+			}
+			return nil
+		},
+		EnforceLocationFn: func(c echo.Context, id int) error {
+			if enforceRole(c, jobsity.CompanyAdminRole) == nil {
+				return nil
+			}
+			if enforceRole(c, jobsity.LocationAdminRole) != nil || user(c).LocationID != id {
+				return echo.ErrForbidden
+			}
+			return nil
+		},
+	}
+}
+
+func TestProvisionRooms(t *testing.T) {
+	rdb := mockdb.NewRoom()
+	if _, err := rdb.Create(nil, jobsity.Room{Name: "random", Topic: "anything"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rdb.Create(nil, jobsity.Room{Name: "old", Archived: true}); err != nil {
+		t.Fatal(err)
+	}
+	hub := ws.NewHub(ws.Config{})
+	if _, err := chat.New([]string{"general", "random"}, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb}), broker.NewMemory(), nil, hub, nil); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"general", "random"}, hub.Rooms())
+	room, err := rdb.View(nil, "random", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "anything", room.Topic)
+}
+
+func TestProvisionTenantRooms(t *testing.T) {
+	rdb := mockdb.NewRoom()
+	tdb := &mockdb.Tenant{
+		CompaniesFn: func(orm.DB) ([]jobsity.Company, error) {
+			return []jobsity.Company{{Base: jobsity.Base{ID: 1}}, {Base: jobsity.Base{ID: 2}}}, nil
+		},
+		LocationsFn: func(orm.DB) ([]jobsity.Location, error) {
+			return []jobsity.Location{
+				{Base: jobsity.Base{ID: 1}, Name: "New York", CompanyID: 1},
+				{Base: jobsity.Base{ID: 2}, Name: "Zürich!", CompanyID: 2},
+				{Base: jobsity.Base{ID: 3}, Name: "東京", CompanyID: 2},
+			}, nil
+		},
+	}
+	for i := 0; i < 2; i++ {
+		// Provisioning again keeps the existing rooms
+		hub := ws.NewHub(ws.Config{})
+		if _, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb, Tenants: tdb}), broker.NewMemory(), nil, hub, nil); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"1:general", "1:general-new-york", "2:general", "2:general-3", "2:general-z-rich", "general"}, hub.Rooms())
+	}
+
+	tdb.CompaniesFn = func(orm.DB) ([]jobsity.Company, error) {
+		return nil, jobsity.ErrGeneric
+	}
+	_, err := chat.New(nil, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb, Tenants: tdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), nil)
+	assert.Equal(t, jobsity.ErrGeneric, err)
+}
+
+func TestCompanyRooms(t *testing.T) {
+	users := map[string]jobsity.AuthUser{
+		"alice": {ID: 1, Username: "alice", Role: jobsity.UserRole, CompanyID: 1, LocationID: 1},
+		"carol": {ID: 2, Username: "carol", Role: jobsity.UserRole, CompanyID: 1, LocationID: 2},
+		"boss":  {ID: 3, Username: "boss", Role: jobsity.CompanyAdminRole, CompanyID: 1, LocationID: 2},
+		"bob":   {ID: 4, Username: "bob", Role: jobsity.UserRole, CompanyID: 2, LocationID: 3},
+		"dave":  {ID: 5, Username: "dave", Role: jobsity.UserRole, CompanyID: 3, LocationID: 4},
+	}
+	rbac := userRBAC(func(c echo.Context) jobsity.AuthUser {
+		return users[c.Get("username").(string)]
+	})
+	var mu sync.Mutex
+	history := map[string][]jobsity.Message{}
+	mdb := &mockdb.Message{
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			history[msg.Room] = append(history[msg.Room], msg)
+			return msg, nil
+		},
+		ListFn: func(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return history[room], nil
+		},
+	}
+	tdb := &mockdb.Tenant{
+		CompaniesFn: func(orm.DB) ([]jobsity.Company, error) {
+			return []jobsity.Company{{Base: jobsity.Base{ID: 1}}, {Base: jobsity.Base{ID: 2}}}, nil
+		},
+		LocationsFn: func(orm.DB) ([]jobsity.Location, error) {
+			return []jobsity.Location{{Base: jobsity.Base{ID: 1}, Name: "Paris", CompanyID: 1}}, nil
+		},
+		CompanyFn: func(db orm.DB, id int) (jobsity.Company, error) {
+			if id != 3 {
+				return jobsity.Company{}, pgsql.ErrCompanyNotFound
+			}
+			return jobsity.Company{Base: jobsity.Base{ID: 3}}, nil
+		},
+		LocationFn: func(db orm.DB, id int) (jobsity.Location, error) {
+			if id != 4 {
+				return jobsity.Location{}, pgsql.ErrLocationNotFound
+			}
+			return jobsity.Location{Base: jobsity.Base{ID: 4}, Name: "Lima", CompanyID: 3}, nil
+		},
+	}
+	hub := ws.NewHub(ws.Config{})
+	s, err := chat.New([]string{"lobby"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Tenants: tdb}), broker.NewMemory(), nil, hub, rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, alicePeer := mock.NewWSConn(t, ws.ProtocolText)
+	bob, bobPeer := mock.NewWSConn(t, ws.ProtocolText)
+	carol, _ := mock.NewWSConn(t, ws.ProtocolText)
+
+	// Users join the general room of their own company
+	assert.Nil(t, s.JoinRoom(userCtx("alice"), alice, "general"))
+	receive(t, alicePeer, "Welcome to the general chat room!")
+	assert.Nil(t, s.JoinRoom(userCtx("bob"), bob, "general"))
+	receive(t, bobPeer, "Welcome to the general chat room!")
+	assert.Nil(t, s.SendMessage(userCtx("alice"), alice, "general", "hi"))
+	assert.Nil(t, s.SendMessage(userCtx("bob"), bob, "general", "hello"))
+	receive(t, alicePeer, "alice: hi")
+	receive(t, bobPeer, "bob: hello")
+	msgs, err := s.ListMessages(userCtx("alice"), "general", jobsity.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Message{{Room: "general", UserID: 1, Username: "alice", Body: "hi"}}, msgs)
+
+	// Global rooms are shared by every company
+	assert.Nil(t, s.JoinRoom(userCtx("alice"), alice, "lobby"))
+	receive(t, alicePeer, "Welcome to the lobby chat room!")
+	assert.Nil(t, s.JoinRoom(userCtx("bob"), bob, "lobby"))
+	receive(t, bobPeer, "Welcome to the lobby chat room!")
+	receive(t, alicePeer, "bob joined the room")
+
+	// Location rooms are only open to their location and the company admins
+	assert.Nil(t, s.JoinRoom(userCtx("alice"), alice, "general-paris"))
+	receive(t, alicePeer, "Welcome to the general-paris chat room!")
+	assert.Equal(t, chat.ErrOtherLocation, s.JoinRoom(userCtx("carol"), carol, "general-paris"))
+	_, err = s.ViewRoom(userCtx("boss"), "general-paris")
+	assert.Nil(t, err)
+	_, err = s.ViewRoom(userCtx("bob"), "general-paris")
+	assert.Equal(t, pgsql.ErrRoomNotFound, err)
+
+	// Company admins manage the rooms of their company only
+	room, err := s.CreateRoom(userCtx("boss"), jobsity.Room{Name: "sales"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, room.CompanyID)
+	assert.True(t, hub.Has("1:sales"))
+	_, err = s.CreateRoom(userCtx("boss"), jobsity.Room{Name: "sales", CompanyID: 2})
+	assert.Equal(t, echo.ErrForbidden, err)
+	_, err = s.CreateRoom(userCtx("bob"), jobsity.Room{Name: "sales"})
+	assert.Equal(t, echo.ErrForbidden, err)
+	assert.Equal(t, echo.ErrForbidden, s.DeleteRoom(userCtx("boss"), "lobby"))
+	assert.Nil(t, s.DeleteRoom(userCtx("boss"), "general-paris"))
+	receive(t, alicePeer, "Room general-paris was deleted")
+
+	// Companies and locations created after startup get their default rooms on first use
+	_, err = s.ListRooms(userCtx("dave"), false, jobsity.Pagination{})
+	assert.Nil(t, err)
+	room, err = s.ViewRoom(userCtx("dave"), "general")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, room.CompanyID)
+	dave, davePeer := mock.NewWSConn(t, ws.ProtocolText)
+	assert.Nil(t, s.JoinRoom(userCtx("dave"), dave, "general-lima"))
+	receive(t, davePeer, "Welcome to the general-lima chat room!")
+	assert.True(t, hub.Has("3:general-lima"))
+}
+
+func TestCreateRoom(t *testing.T) {
+	cases := []struct {
+		name     string
+		role     jobsity.AccessRole
+		req      jobsity.Room
+		wantErr  error
+		wantData jobsity.Room
+		wantOpen bool
+	}{
+		{
+			name:    "Fail on RBAC",
+			role:    jobsity.UserRole,
+			req:     jobsity.Room{Name: "lobby"},
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on invalid name",
+			role:    jobsity.AdminRole,
+			req:     jobsity.Room{Name: "the lobby"},
+			wantErr: chat.ErrInvalidRoomName,
+		},
+		{
+			name:     "Fail on existing room",
+			role:     jobsity.AdminRole,
+			req:      jobsity.Room{Name: "general"},
+			wantErr:  pgsql.ErrRoomAlreadyExists,
+			wantOpen: true,
+		},
+		{
+			name:     "Success",
+			role:     jobsity.AdminRole,
+			req:      jobsity.Room{Name: "lobby", Topic: "Say hi"},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "lobby", Topic: "Say hi", CreatedBy: 1},
+			wantOpen: true,
+		},
+		{
+			name:     "Success as super admin",
+			role:     jobsity.SuperAdminRole,
+			req:      jobsity.Room{Name: "lobby"},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "lobby", CreatedBy: 1},
+			wantOpen: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := ws.NewHub(ws.Config{})
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, hub, roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			room, err := s.CreateRoom(nil, tt.req)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantData, room)
+			assert.Equal(t, tt.wantOpen, hub.Has(tt.req.Name))
+		})
+	}
+}
+
+func TestUpdateRoom(t *testing.T) {
+	topic := "New topic"
+	description := "General talk"
+	cases := []struct {
+		name     string
+		role     jobsity.AccessRole
+		req      chat.UpdateRoom
+		wantErr  error
+		wantData jobsity.Room
+		wantRecv string
+	}{
+		{
+			name:    "Fail on RBAC",
+			role:    jobsity.UserRole,
+			req:     chat.UpdateRoom{Name: "general", Topic: &topic},
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on room not found",
+			role:    jobsity.AdminRole,
+			req:     chat.UpdateRoom{Name: "lobby", Topic: &topic},
+			wantErr: pgsql.ErrRoomNotFound,
+		},
+		{
+			name:     "Success on description",
+			role:     jobsity.AdminRole,
+			req:      chat.UpdateRoom{Name: "general", Description: &description},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general", Description: description},
+		},
+		{
+			name:     "Success on topic",
+			role:     jobsity.AdminRole,
+			req:      chat.UpdateRoom{Name: "general", Topic: &topic},
+			wantData: jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general", Topic: topic},
+			wantRecv: "The topic is now: New topic",
+		},
+	}
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+			if err := s.JoinRoom(nil, conn, "general"); err != nil {
+				t.Fatal(err)
+			}
+			receive(t, peer, "Welcome to the general chat room!")
+
+			room, err := s.UpdateRoom(nil, tt.req)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantData, room)
+			if tt.wantRecv != "" {
+				receive(t, peer, tt.wantRecv)
+			}
+		})
+	}
+}
+
+func TestArchiveRoom(t *testing.T) {
+	mdb := &mockdb.Message{
+		ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+			return nil, nil
+		},
+	}
+	hub := ws.NewHub(ws.Config{})
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, hub, roleRBAC(jobsity.AdminRole))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, peer := mock.NewWSConn(t, ws.ProtocolText)
+	if err := s.JoinRoom(nil, conn, "general"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, peer, "Welcome to the general chat room!")
+
+	room, err := s.ArchiveRoom(nil, "general")
+	assert.Nil(t, err)
+	assert.True(t, room.Archived)
+	receive(t, peer, "Room general was archived")
+	assert.False(t, hub.Has("general"))
+
+	_, err = s.ArchiveRoom(nil, "general")
+	assert.Equal(t, chat.ErrRoomArchived, err)
+	archived, err := s.ListRooms(nil, true, jobsity.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, []jobsity.Room{room}, archived)
+
+	// Archived rooms are deleted without being stopped again
+	assert.Nil(t, s.DeleteRoom(nil, "general"))
+	_, err = s.ViewRoom(nil, "general")
+	assert.Equal(t, pgsql.ErrRoomNotFound, err)
+}
+
+func TestDeleteRoom(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    jobsity.AccessRole
+		room    string
+		wantErr error
+	}{
+		{
+			name:    "Fail on RBAC",
+			role:    jobsity.CompanyAdminRole,
+			room:    "general",
+			wantErr: echo.ErrForbidden,
+		},
+		{
+			name:    "Fail on room not found",
+			role:    jobsity.AdminRole,
+			room:    "lobby",
+			wantErr: pgsql.ErrRoomNotFound,
+		},
+		{
+			name: "Success",
+			role: jobsity.SuperAdminRole,
+			room: "general",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := ws.NewHub(ws.Config{})
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, hub, roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantErr, s.DeleteRoom(nil, tt.room))
+			assert.Equal(t, tt.wantErr != nil, hub.Has("general"))
+		})
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	private := jobsity.ListQuery{
+		Query: "private = false or id in (select room_id from room_members where user_id = ? and deleted_at is null)",
+		ID:    1,
+	}
+	cases := []struct {
+		name      string
+		role      jobsity.AccessRole
+		wantQuery []jobsity.ListQuery
+	}{
+		{
+			name: "Success on admin",
+			role: jobsity.AdminRole,
+		},
+		{
+			name: "Success on company admin",
+			role: jobsity.CompanyAdminRole,
+			wantQuery: []jobsity.ListQuery{
+				{Query: "company_id in (0, ?)", ID: 2},
+				private,
+			},
+		},
+		{
+			name: "Success on user",
+			role: jobsity.UserRole,
+			wantQuery: []jobsity.ListQuery{
+				{Query: "company_id in (0, ?)", ID: 2},
+				{Query: "location_id in (0, ?)", ID: 3},
+				private,
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb := mockdb.NewRoom()
+			var query []jobsity.ListQuery
+			list := rdb.ListFn
+			s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), tenantRBAC(tt.role, 2, 3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rdb.ListFn = func(db orm.DB, qp []jobsity.ListQuery, archived bool, p jobsity.Pagination) ([]jobsity.Room, error) {
+				query = qp
+				return list(db, qp, archived, p)
+			}
+			rooms, err := s.ListRooms(nil, false, jobsity.Pagination{Limit: 10})
+			assert.Nil(t, err)
+			assert.Equal(t, []jobsity.Room{{Base: jobsity.Base{ID: 1}, Name: "general"}}, rooms)
+			assert.Equal(t, tt.wantQuery, query)
+		})
+	}
+}
@@ -2,64 +2,287 @@ package chat
 
 import (
 	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
 	"github.com/labstack/echo"
-	"github.com/streadway/amqp"
 	"golang.org/x/net/websocket"
 	jobsity "my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
 	websocket2 "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Service represents chat application interface
 type Service interface {
+	HandleFrame(c echo.Context, conn *websocket.Conn, roomName string, data []byte) (jobsity.Frame, error)
 	HandleCommand(c echo.Context, conn *websocket.Conn, roomName string, message string) error
-	JoinRoom(c echo.Context, conn *websocket.Conn, roomName string, user *jobsity.AuthUser) error
-	LeaveRoom(c echo.Context, roomName string, conn *websocket.Conn, user *jobsity.AuthUser) error
-	SendMessage(c echo.Context, roomName string, message []byte, user *jobsity.AuthUser) error
-	SendMessageOne(c echo.Context, roomName string, user *jobsity.AuthUser, message string) error
+	JoinRoom(c echo.Context, conn *websocket.Conn, roomName string) error
+	LeaveRoom(c echo.Context, conn *websocket.Conn, roomName string) error
+	Disconnect(c echo.Context, conn *websocket.Conn) error
+	SendMessage(c echo.Context, conn *websocket.Conn, roomName string, message string) error
+	SendReply(c echo.Context, conn *websocket.Conn, roomName string, parentID int, message string) error
 	GetUsersInRoom(c echo.Context, roomName string) ([]string, error)
-	FetchStockQuote(c echo.Context, stockCode string) (float64, error)
-	CreateRoom(c echo.Context, roomName string, user *jobsity.AuthUser) error
+	CreateRoom(c echo.Context, req jobsity.Room) (jobsity.Room, error)
+	ListRooms(c echo.Context, archived bool, p jobsity.Pagination) ([]jobsity.Room, error)
+	ViewRoom(c echo.Context, roomName string) (jobsity.Room, error)
+	UpdateRoom(c echo.Context, req UpdateRoom) (jobsity.Room, error)
+	ArchiveRoom(c echo.Context, roomName string) (jobsity.Room, error)
+	DeleteRoom(c echo.Context, roomName string) error
+	ListMembers(c echo.Context, roomName string) ([]jobsity.RoomMember, error)
+	InviteMember(c echo.Context, roomName string, req jobsity.RoomMember) (jobsity.RoomMember, error)
+	RemoveMember(c echo.Context, roomName string, userID int) error
+	KickUser(c echo.Context, roomName string, req Moderation) error
+	BanUser(c echo.Context, roomName string, req Moderation) (jobsity.Sanction, error)
+	UnbanUser(c echo.Context, roomName string, userID int) error
+	MuteUser(c echo.Context, roomName string, req Moderation) (jobsity.Sanction, error)
+	ListModerationLog(c echo.Context, roomName string, p jobsity.Pagination) ([]jobsity.ModerationLog, error)
+	ListMessages(c echo.Context, roomName string, p jobsity.Pagination) ([]jobsity.Message, error)
+	EditMessage(c echo.Context, roomName string, id int, text string) (jobsity.Message, error)
+	DeleteMessage(c echo.Context, roomName string, id int) error
+	ListMessageEdits(c echo.Context, roomName string, id int) ([]jobsity.MessageEdit, error)
+	ViewThread(c echo.Context, roomName string, id int, p jobsity.Pagination) (jobsity.Thread, error)
+	ToggleReaction(c echo.Context, roomName string, id int, emoji string) (jobsity.Message, error)
+	ListMentions(c echo.Context, p jobsity.Pagination) ([]jobsity.Mention, error)
+	SendDirect(c echo.Context, username string, text string) (jobsity.Message, error)
+	ListConversations(c echo.Context, p jobsity.Pagination) ([]jobsity.Conversation, error)
+	ListDirectMessages(c echo.Context, username string, p jobsity.Pagination) ([]jobsity.Message, error)
+	SetAway(c echo.Context, conn *websocket.Conn, away bool) error
+	Typing(c echo.Context, conn *websocket.Conn, roomName string, typing bool) error
+	ListPresence(c echo.Context, roomName string) ([]jobsity.Presence, error)
+	MarkRead(c echo.Context, roomName string, messageID int) (jobsity.ReadCursor, error)
+	Stats(c echo.Context) websocket2.Stats
 }
 
-// New creates new password application service
-func New(rooms []string /*map[string]*jobsity.Room,*/, db *pg.DB, rabbit *amqp.Connection, ws RWS) Chat {
-	return Chat{
-		rooms:  rooms,
-		db:     db,
-		rabbit: rabbit,
-		ws:     ws,
+// HistoryLimit is the number of latest messages sent to a client joining a room
+const HistoryLimit = 50
+
+// Repositories holds the repositories the chat persists its data with
+type Repositories struct {
+	Messages   MDB
+	Rooms      RDB
+	Members    RMDB
+	Tenants    TDB
+	Moderation MODB
+	Users      UDB
+	Directs    DDB
+	Reads      RCDB
+}
+
+// New creates new chat application service, persists the initial rooms and the default rooms of
+// the companies and locations, opens the active rooms and subscribes to the room and user events
+// of the other chat instances sharing the broker, asking them for the presence of their users
+func New(rooms []string, db *pg.DB, repos Repositories, b broker.Broker, bot StockBot, hub Hub, rbac RBAC) (*Chat, error) {
+	instance, err := newInstanceID()
+	if err != nil {
+		return nil, err
+	}
+	s := &Chat{
+		sessions: make(map[*websocket.Conn]*client),
+		hub:      hub,
+		db:       db,
+		mdb:      repos.Messages,
+		rdb:      repos.Rooms,
+		rmdb:     repos.Members,
+		tdb:      repos.Tenants,
+		modb:     repos.Moderation,
+		udb:      repos.Users,
+		ddb:      repos.Directs,
+		rcdb:     repos.Reads,
+		broker:   b,
+		bot:      bot,
+		rbac:     rbac,
+		instance: instance,
+		subs:     make(map[string]broker.Subscription),
+		seen:     newDedup(dedupSize),
+		commands: newCommands(),
+		limiter:  newLimiter(RateLimit{}),
+		filters:  newFilters(),
+		presence: newPresence(),
+		typing:   newTypingTracker(TypingConfig{Interval: DefaultTypingInterval, Timeout: DefaultTypingTimeout}),
+	}
+	s.editWindow.Store(int64(DefaultEditWindow))
+	if err := s.registerBuiltins(); err != nil {
+		return nil, err
+	}
+	if _, err := b.Subscribe(roomsTopic, s.handleEvent); err != nil {
+		return nil, err
+	}
+	if _, err := b.Subscribe(usersTopic, s.handleEvent); err != nil {
+		return nil, err
+	}
+	if err := s.publish(usersTopic, event{Kind: eventSync}); err != nil {
+		return nil, err
+	}
+	if err := s.provisionRooms(rooms); err != nil {
+		return nil, err
 	}
+	return s, nil
 }
 
-// Initialize initalizes password application service with defaults
-func Initialize(rooms []string /*map[string]*jobsity.Room,*/, db *pg.DB, rabbit *amqp.Connection) Chat {
-	return New(rooms, db, rabbit, &websocket2.Room{})
+// Initialize initalizes chat application service with defaults, the message rate limits and the message filters
+func Initialize(rooms []string, db *pg.DB, b broker.Broker, bot StockBot, hub Hub, rbac RBAC, limits RateLimit, fc FilterConfig) (*Chat, error) {
+	s, err := New(rooms, db, Repositories{
+		Messages:   pgsql.Message{},
+		Rooms:      pgsql.Room{},
+		Members:    pgsql.Member{},
+		Tenants:    pgsql.Tenant{},
+		Moderation: pgsql.Moderation{},
+		Users:      pgsql.User{},
+		Directs:    pgsql.Direct{},
+		Reads:      pgsql.Read{},
+	}, b, bot, hub, rbac)
+	if err != nil {
+		return nil, err
+	}
+	s.SetRateLimit(limits)
+	if err := s.SetFilters(fc); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
+// client represents an authenticated WebSocket connection and the rooms it joined, keyed by their lowercase names
 type client struct {
-	conn     *websocket.Conn
-	username string
-	lastMsg  time.Time
+	*websocket2.Client
+
+	mu    sync.Mutex
+	rooms map[string]jobsity.Room
+	away  bool
 }
 
 // Chat represents chat application service
 type Chat struct {
-	clients map[string][]*client
-	Rooms   map[string]*jobsity.Room
-	rooms   []string
-	db      *pg.DB
-	rabbit  *amqp.Connection
-	ws      RWS
-}
-
-// RWS represents room websocket interface
-type RWS interface {
-	Run(*jobsity.Room)
-	AddClient(*websocket.Conn, *jobsity.Room)
-	RemoveClient(*websocket.Conn, *jobsity.Room)
-	BroadcastMessage([]byte, *websocket.Conn, *jobsity.Room)
-	//BroadcastMessageInRoom([]byte, *websocket.Conn, string)
-	//handleStockCommand([]byte, *websocket.Conn, string)
-	//handleStockCommandInRoom([]byte, *websocket.Conn, *jobsity.Room)
+	mu       sync.RWMutex
+	sessions map[*websocket.Conn]*client
+	hub      Hub
+	db       *pg.DB
+	mdb      MDB
+	rdb      RDB
+	rmdb     RMDB
+	tdb      TDB
+	modb     MODB
+	udb      UDB
+	ddb      DDB
+	rcdb     RCDB
+	broker   broker.Broker
+	bot      StockBot
+	rbac     RBAC
+	commands *commands
+	limiter  *limiter
+	filters  *filters
+	presence *presence
+	typing   *typingTracker
+	// provisioned holds the companies and locations whose default rooms are persisted
+	provisioned sync.Map
+	// receipts is the largest number of users present in a room for read receipts to be sent to it
+	receipts atomic.Int32
+	// editWindow is how long senders may edit or delete their messages
+	editWindow atomic.Int64
+	// stopHeartbeat stops the heartbeats to the other instances, when running
+	heartbeatMu   sync.Mutex
+	stopHeartbeat chan struct{}
+
+	// instance identifies this chat instance in the room events it publishes
+	instance string
+	seq      uint64
+	subsMu   sync.Mutex
+	subs     map[string]broker.Subscription
+	seen     *dedup
+}
+
+// MDB represents message repository interface
+type MDB interface {
+	Create(orm.DB, jobsity.Message) (jobsity.Message, error)
+	View(orm.DB, int) (jobsity.Message, error)
+	List(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error)
+	ListReplies(orm.DB, int, jobsity.Pagination) ([]jobsity.Message, error)
+	Update(orm.DB, jobsity.Message) error
+	Delete(orm.DB, jobsity.Message) error
+	CreateEdit(orm.DB, jobsity.MessageEdit) (jobsity.MessageEdit, error)
+	ListEdits(orm.DB, int) ([]jobsity.MessageEdit, error)
+	ToggleReaction(orm.DB, jobsity.Reaction) (bool, error)
+	CreateMentions(orm.DB, []jobsity.Mention) error
+	ListMentions(orm.DB, int, jobsity.Pagination) ([]jobsity.Mention, error)
+}
+
+// RDB represents room repository interface
+type RDB interface {
+	Create(orm.DB, jobsity.Room) (jobsity.Room, error)
+	View(orm.DB, string, int) (jobsity.Room, error)
+	List(orm.DB, []jobsity.ListQuery, bool, jobsity.Pagination) ([]jobsity.Room, error)
+	Update(orm.DB, jobsity.Room) error
+	Delete(orm.DB, jobsity.Room) error
+}
+
+// RMDB represents room member repository interface
+type RMDB interface {
+	Create(orm.DB, jobsity.RoomMember) (jobsity.RoomMember, error)
+	View(orm.DB, int, int) (jobsity.RoomMember, error)
+	List(orm.DB, int) ([]jobsity.RoomMember, error)
+	Delete(orm.DB, jobsity.RoomMember) error
+}
+
+// TDB represents company and location repository interface
+type TDB interface {
+	Companies(orm.DB) ([]jobsity.Company, error)
+	Locations(orm.DB) ([]jobsity.Location, error)
+	Company(orm.DB, int) (jobsity.Company, error)
+	Location(orm.DB, int) (jobsity.Location, error)
+}
+
+// MODB represents room sanction and moderation log repository interface
+type MODB interface {
+	CreateSanction(orm.DB, jobsity.Sanction) (jobsity.Sanction, error)
+	ViewSanction(orm.DB, int, int, jobsity.SanctionKind) (jobsity.Sanction, error)
+	DeleteSanction(orm.DB, jobsity.Sanction) error
+	CreateLog(orm.DB, jobsity.ModerationLog) (jobsity.ModerationLog, error)
+	ListLog(orm.DB, int, jobsity.Pagination) ([]jobsity.ModerationLog, error)
+}
+
+// UDB represents user repository interface
+type UDB interface {
+	View(orm.DB, int) (jobsity.User, error)
+	FindByUsername(orm.DB, string) (jobsity.User, error)
+	UpdateLastSeen(orm.DB, int, time.Time) error
+}
+
+// DDB represents direct conversation repository interface
+type DDB interface {
+	Touch(orm.DB, int, int) error
+	List(orm.DB, int, jobsity.Pagination) ([]jobsity.Conversation, error)
+	MarkRead(orm.DB, int, int, int) error
+}
+
+// RCDB represents read cursor repository interface
+type RCDB interface {
+	MarkRead(orm.DB, int, string, int) (bool, error)
+	List(orm.DB, int, []string) ([]jobsity.ReadCursor, error)
+}
+
+// StockBot represents stock bot client interface
+type StockBot interface {
+	Quote(string) (jobsity.StockReply, error)
+}
+
+// RBAC represents role-based-access-control interface
+type RBAC interface {
+	User(echo.Context) jobsity.AuthUser
+	EnforceRole(echo.Context, jobsity.AccessRole) error
+	EnforceCompany(echo.Context, int) error
+	EnforceLocation(echo.Context, int) error
+}
+
+// Hub represents running rooms and connected clients registry interface
+type Hub interface {
+	Connect(*websocket.Conn, jobsity.AuthUser) *websocket2.Client
+	Stats() websocket2.Stats
+	Open(string) error
+	Close(string) error
+	Has(string) bool
+	Join(string, *websocket2.Client) error
+	Leave(string, *websocket2.Client) error
+	Broadcast(string, jobsity.Frame, *websocket2.Client) error
+	Clients(string) ([]*websocket2.Client, error)
 }
@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+)
+
+// SendReply persists a reply to a message of the room sent through the connection, and broadcasts it to the
+// room. Threads are one level deep, so replies to a reply join the thread of its parent.
+func (s *Chat) SendReply(c echo.Context, conn *websocket.Conn, roomName string, parentID int, message string) error {
+	if parentID <= 0 {
+		return ErrInvalidMessageID
+	}
+	return s.send(c, conn, roomName, parentID, message)
+}
+
+// ViewThread returns a message of a room with a page of its replies, ordered by timestamp. Replies
+// are shown in the thread of their parent.
+func (s *Chat) ViewThread(c echo.Context, roomName string, id int, p jobsity.Pagination) (jobsity.Thread, error) {
+	room, err := s.room(c, roomName)
+	if err != nil {
+		return jobsity.Thread{}, err
+	}
+	if err := s.enforceRoomAccess(c, room); err != nil {
+		return jobsity.Thread{}, err
+	}
+	parent, err := s.threadHead(room, id)
+	if err != nil {
+		return jobsity.Thread{}, err
+	}
+	replies, err := s.mdb.ListReplies(s.db, parent.ID, p)
+	if err != nil {
+		return jobsity.Thread{}, err
+	}
+
+	parent.Room = room.Name
+	for i := range replies {
+		replies[i].Room = room.Name
+	}
+	return jobsity.Thread{Parent: parent, Replies: replies}, nil
+}
+
+// threadHead returns the message heading the thread of a room's message
+func (s *Chat) threadHead(room jobsity.Room, id int) (jobsity.Message, error) {
+	msg, err := s.roomMessage(room, id)
+	if err != nil || msg.ParentID == 0 {
+		return msg, err
+	}
+	return s.roomMessage(room, msg.ParentID)
+}
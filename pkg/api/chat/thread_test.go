@@ -0,0 +1,80 @@
+package chat_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveReply receives the next frame of a JSON connection, checking it is a reply to the parent message
+func receiveReply(t *testing.T, conn *websocket.Conn, parentID int, text string) {
+	var f jobsity.Frame
+	if err := websocket.JSON.Receive(conn, &f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, jobsity.FrameReply, f.Type)
+	assert.Equal(t, parentID, f.ParentID)
+	assert.Equal(t, text, f.Payload.Text)
+}
+
+func TestThread(t *testing.T) {
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moderator, moderatorPeer := mock.NewWSConn(t, ws.ProtocolText)
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, _ := mock.NewWSConn(t, ws.ProtocolJSON)
+	assert.Nil(t, s.JoinRoom(userCtx("moderator"), moderator, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	receive(t, moderatorPeer, "Welcome to the general chat room!", "owner joined the room", "member joined the room")
+
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "lunch?"))
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "lunch?")
+
+	assert.Equal(t, chat.ErrInvalidMessageID, s.SendReply(userCtx("member"), member, "general", 0, "sure"))
+	assert.Equal(t, pgsql.ErrMessageNotFound, s.SendReply(userCtx("member"), member, "general", 5, "sure"))
+	assert.Equal(t, chat.ErrNotInRoom, s.SendReply(userCtx("member"), member, "random", 1, "sure"))
+
+	// Replies to a reply join the thread of its parent
+	_, err = s.HandleFrame(userCtx("member"), member, "general",
+		[]byte(`{"version":1,"type":"reply","parent_id":1,"payload":{"text":"sure"}}`))
+	assert.Nil(t, err)
+	receiveReply(t, ownerPeer, 1, "sure")
+	assert.Nil(t, s.SendReply(userCtx("owner"), owner, "general", 2, "great"))
+	receiveReply(t, ownerPeer, 1, "great")
+	receive(t, moderatorPeer, "owner: lunch?", "member (in thread): sure", "owner (in thread): great")
+
+	// Histories hold the parents with their reply counts, and threads their replies
+	msgs, err := s.ListMessages(userCtx("member"), "general", jobsity.Pagination{})
+	assert.Nil(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "lunch?", msgs[0].Body)
+		assert.Equal(t, 2, msgs[0].Replies)
+	}
+	thread, err := s.ViewThread(userCtx("member"), "general", 2, jobsity.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, thread.Parent.ID)
+	assert.Equal(t, "general", thread.Parent.Room)
+	assert.Equal(t, 2, thread.Parent.Replies)
+	var replies []string
+	for _, reply := range thread.Replies {
+		replies = append(replies, reply.Body)
+	}
+	assert.Equal(t, []string{"sure", "great"}, replies)
+	_, err = s.ViewThread(userCtx("member"), "general", 5, jobsity.Pagination{})
+	assert.Equal(t, pgsql.ErrMessageNotFound, err)
+}
@@ -4,8 +4,13 @@ import (
 	"github.com/labstack/echo"
 	"golang.org/x/net/websocket"
 	"log"
+	jobsity "my-chat-jobsity-challenge"
 	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // HTTP represents chat http service
@@ -13,70 +18,1329 @@ type HTTP struct {
 	svc chat.Service
 }
 
-// NewHTTP creates new user http service
-func NewHTTP(svc chat.Service, r *echo.Group, mw echo.MiddlewareFunc) {
+// NewHTTP creates new chat http service
+func NewHTTP(svc chat.Service, r *echo.Group) {
 	h := HTTP{svc}
 	ur := r.Group("/chat")
 
-	// swagger:route GET /ws auth meReq
-	// Gets user's info from session.
+	// swagger:route GET /v1/chat/ws chat chatWS
+	// Upgrades the connection to a chat WebSocket.
 	// responses:
-	//  200: chatResp
+	//  101: ok
+	//  401: err
 	//  500: err
-	ur.GET("/ws", h.handleWebSocket, mw)
+	ur.GET("/ws", h.handleWebSocket)
 
+	// swagger:route POST /v1/chat/rooms chat roomCreate
+	// Creates and starts a new room.
+	// responses:
+	//  200: roomResp
+	//  400: errMsg
+	//  401: err
+	//  403: err
+	//  409: errMsg
+	//  500: err
+	ur.POST("/rooms", h.createRoom)
+
+	// swagger:operation GET /v1/chat/rooms chat listRooms
+	// ---
+	// summary: Returns list of rooms.
+	// description: Returns a page of the active rooms, or the archived ones if requested, ordered by name, with the current user's last read message and unread message count.
+	// parameters:
+	// - name: archived
+	//   in: query
+	//   description: list archived rooms instead of active ones
+	//   type: bool
+	//   required: false
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/roomListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms", h.listRooms)
+
+	// swagger:operation GET /v1/chat/rooms/{room} chat getRoom
+	// ---
+	// summary: Returns a single room.
+	// description: Returns a single room by its name.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/roomResp"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room", h.viewRoom)
+
+	// swagger:operation PATCH /v1/chat/rooms/{room} chat roomUpdate
+	// ---
+	// summary: Updates room's information
+	// description: Updates room's topic, description, visibility, slow mode, the number of seconds users wait between their messages, and message filters, applied after the chat's default ones. Topic and slow mode changes are announced to the room.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/roomUpdate"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/roomResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.PATCH("/rooms/:room", h.updateRoom)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/archive chat roomArchive
+	// ---
+	// summary: Archives a room
+	// description: Stops a room, keeping its history. Archived rooms cannot be joined.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/roomResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/archive", h.archiveRoom)
+
+	// swagger:operation DELETE /v1/chat/rooms/{room} chat roomDelete
+	// ---
+	// summary: Deletes a room
+	// description: Deletes a room and its history, stopping it if active.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ok"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.DELETE("/rooms/:room", h.deleteRoom)
+
+	// swagger:operation GET /v1/chat/rooms/{room}/messages chat listMessages
+	// ---
+	// summary: Returns room's message history.
+	// description: Returns a page of room's latest messages, ordered by timestamp from oldest to newest, with their reply counts. Replies are listed in their thread.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room/messages", h.listMessages)
+
+	// swagger:operation PATCH /v1/chat/rooms/{room}/messages/{id} chat messageEdit
+	// ---
+	// summary: Edits a message
+	// description: Replaces the body of a room's message, keeping the previous one in the message's edit history, and sends the room a message.edited frame. Senders edit their messages for a while after sending them, and room moderators and admins edit any message at any time.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the message
+	//   type: int
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/messageEdit"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.PATCH("/rooms/:room/messages/:id", h.editMessage)
+
+	// swagger:operation DELETE /v1/chat/rooms/{room}/messages/{id} chat messageDelete
+	// ---
+	// summary: Deletes a message
+	// description: Deletes a room's message and sends the room a message.deleted frame. Senders delete their messages for a while after sending them, and room moderators and admins delete any message at any time.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the message
+	//   type: int
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ok"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.DELETE("/rooms/:room/messages/:id", h.deleteMessage)
+
+	// swagger:operation GET /v1/chat/rooms/{room}/messages/{id}/edits chat listMessageEdits
+	// ---
+	// summary: Returns message's edit history.
+	// description: Returns the previous versions of an edited message, from the first one. Only admins of the room's company or location can list them.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the message
+	//   type: int
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageEditListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room/messages/:id/edits", h.listMessageEdits)
+
+	// swagger:operation GET /v1/chat/rooms/{room}/messages/{id}/thread chat viewThread
+	// ---
+	// summary: Returns a message thread.
+	// description: Returns a message with a page of its replies, ordered by timestamp from oldest to newest. Replies are shown in the thread of their parent.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the message
+	//   type: int
+	//   required: true
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/threadResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room/messages/:id/thread", h.viewThread)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/messages/{id}/reactions chat reactionToggle
+	// ---
+	// summary: Toggles a reaction to a message
+	// description: Adds the current user's reaction with an emoji to a room's message, or removes it if the user already reacted with the same emoji, and sends the room a reaction.added or reaction.removed frame. Returns the message with its reactions.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the message
+	//   type: int
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/reactionToggle"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/messages/:id/reactions", h.toggleReaction)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/read chat markRead
+	// ---
+	// summary: Marks a room read
+	// description: Moves the current user's read cursor of a room forward to a message of the room, and returns it with the number of messages left unread. Rooms with few users present are sent a read receipt.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/markRead"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/readCursorResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/read", h.markRead)
+
+	// swagger:operation GET /v1/chat/rooms/{room}/members chat listMembers
+	// ---
+	// summary: Returns room's members.
+	// description: Returns members of a room with their room roles. Members of private rooms are only listed to the other members.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/memberListResp"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room/members", h.listMembers)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/members chat memberInvite
+	// ---
+	// summary: Invites a user to a room
+	// description: Adds a user of the room's company and location to a room with a room role. Only room owners, moderators and admins can invite, granting up to their own role.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/memberInvite"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/memberResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "409":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/members", h.inviteMember)
+
+	// swagger:operation DELETE /v1/chat/rooms/{room}/members/{id} chat memberRemove
+	// ---
+	// summary: Removes a user from a room
+	// description: Removes a user from a room, evicting the user from private rooms. Users can remove themselves, while room owners, moderators and admins can remove members up to their own role.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of user
+	//   type: int
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ok"
+	//   "400":
+	//     "$ref": "#/responses/err"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.DELETE("/rooms/:room/members/:id", h.removeMember)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/kicks chat userKick
+	// ---
+	// summary: Kicks a user from a room
+	// description: Removes a user from a room on every chat instance. Only room owners, moderators and admins can kick, and only users up to their own role.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/moderation"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ok"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/kicks", h.kickUser)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/bans chat userBan
+	// ---
+	// summary: Bans a user from a room
+	// description: Keeps a user from joining a room, permanently unless a duration is given, and removes the user from it. Only room owners, moderators and admins can ban, and only users up to their own role.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/moderation"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/sanctionResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/bans", h.banUser)
+
+	// swagger:operation DELETE /v1/chat/rooms/{room}/bans/{id} chat userUnban
+	// ---
+	// summary: Lifts the ban of a user from a room
+	// description: Lets a banned user join the room again. Only room owners, moderators and admins can unban.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of user
+	//   type: int
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ok"
+	//   "400":
+	//     "$ref": "#/responses/err"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.DELETE("/rooms/:room/bans/:id", h.unbanUser)
+
+	// swagger:operation POST /v1/chat/rooms/{room}/mutes chat userMute
+	// ---
+	// summary: Mutes a user in a room
+	// description: Keeps a user from sending messages to a room for a duration. Only room owners, moderators and admins can mute, and only users up to their own role.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/moderation"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/sanctionResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/rooms/:room/mutes", h.muteUser)
+
+	// swagger:operation GET /v1/chat/rooms/{room}/moderation chat listModerationLog
+	// ---
+	// summary: Returns room's moderation log.
+	// description: Returns a page of the moderation actions taken in a room, latest first. Only admins of the room's company or location can list them.
+	// parameters:
+	// - name: room
+	//   in: path
+	//   description: name of the room
+	//   type: string
+	//   required: true
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/moderationLogResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/rooms/:room/moderation", h.listModerationLog)
+
+	// swagger:operation GET /v1/chat/conversations chat listConversations
+	// ---
+	// summary: Returns user's direct conversations.
+	// description: Returns a page of the current user's direct conversations with their latest message and unread count, latest first.
+	// parameters:
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/conversationListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/conversations", h.listConversations)
+
+	// swagger:operation POST /v1/chat/conversations/{username}/messages chat directMessage
+	// ---
+	// summary: Sends a direct message to a user.
+	// description: Sends a direct message to a user of the current user's company, delivered to every session of the recipient.
+	// parameters:
+	// - name: username
+	//   in: path
+	//   description: username of the recipient
+	//   type: string
+	//   required: true
+	// - name: request
+	//   in: body
+	//   description: Request body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/directMessage"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "422":
+	//     "$ref": "#/responses/errMsg"
+	//   "429":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.POST("/conversations/:username/messages", h.sendDirect)
+
+	// swagger:operation GET /v1/chat/conversations/{username}/messages chat listDirectMessages
+	// ---
+	// summary: Returns the direct messages with a user.
+	// description: Returns a page of the latest direct messages between the current user and another one, ordered by timestamp from oldest to newest, and marks them read.
+	// parameters:
+	// - name: username
+	//   in: path
+	//   description: username of the other user
+	//   type: string
+	//   required: true
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/messageListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/errMsg"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/conversations/:username/messages", h.listDirectMessages)
+
+	// swagger:operation GET /v1/chat/mentions chat listMentions
+	// ---
+	// summary: Returns user's mentions.
+	// description: Returns a page of the room messages mentioning the current user, latest first. Mentions of messages past the user's read cursor of their room are unread.
+	// parameters:
+	// - name: limit
+	//   in: query
+	//   description: number of results
+	//   type: int
+	//   required: false
+	// - name: page
+	//   in: query
+	//   description: page number
+	//   type: int
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/mentionListResp"
+	//   "400":
+	//     "$ref": "#/responses/errMsg"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/mentions", h.listMentions)
+
+	// swagger:operation GET /v1/chat/presence chat listPresence
+	// ---
+	// summary: Returns the online users.
+	// description: Returns the online users of the current user's company, or every online user for admins, ordered by username. Users whose every chat connection is away have the away status.
+	// parameters:
+	// - name: room
+	//   in: query
+	//   description: name of a room to list the users present in instead
+	//   type: string
+	//   required: false
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/presenceListResp"
+	//   "401":
+	//     "$ref": "#/responses/err"
+	//   "403":
+	//     "$ref": "#/responses/err"
+	//   "404":
+	//     "$ref": "#/responses/errMsg"
+	//   "500":
+	//     "$ref": "#/responses/err"
+	ur.GET("/presence", h.listPresence)
 }
 
-func (h *HTTP) handleWebSocket(c echo.Context) error {
-	// Upgrade the HTTP request to a WebSocket connection
-	wsHandler := websocket.Handler(func(ws *websocket.Conn) {
-		// Read the initial message from the WebSocket
-		var msg string
-		err := websocket.Message.Receive(ws, &msg)
+// Room create request
+// swagger:model roomCreate
+type createRoomReq struct {
+	Name        string `json:"name" validate:"required"`
+	Topic       string `json:"topic" validate:"max=256"`
+	Description string `json:"description" validate:"max=1024"`
+	Private     bool   `json:"private"`
+	CompanyID   int    `json:"company_id" validate:"min=0"`
+	LocationID  int    `json:"location_id" validate:"min=0"`
+
+	Filters []string `json:"filters"`
+}
+
+func (h *HTTP) createRoom(c echo.Context) error {
+	r := new(createRoomReq)
+	if err := c.Bind(r); err != nil {
+		return err
+	}
+
+	room, err := h.svc.CreateRoom(c, jobsity.Room{
+		Name:        r.Name,
+		Topic:       r.Topic,
+		Description: r.Description,
+		Private:     r.Private,
+		CompanyID:   r.CompanyID,
+		LocationID:  r.LocationID,
+		Filters:     r.Filters,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, room)
+}
+
+type listRoomsReq struct {
+	jobsity.PaginationReq
+	Archived bool `query:"archived"`
+}
+
+type roomListResponse struct {
+	Rooms []jobsity.Room `json:"rooms"`
+	Page  int            `json:"page"`
+}
+
+func (h *HTTP) listRooms(c echo.Context) error {
+	var req listRoomsReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListRooms(c, req.Archived, req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, roomListResponse{result, req.Page})
+}
+
+func (h *HTTP) viewRoom(c echo.Context) error {
+	result, err := h.svc.ViewRoom(c, c.Param("room"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// Room update request
+// swagger:model roomUpdate
+type updateRoomReq struct {
+	Topic       *string `json:"topic,omitempty" validate:"omitempty,max=256"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=1024"`
+	Private     *bool   `json:"private,omitempty"`
+	SlowMode    *int    `json:"slow_mode,omitempty" validate:"omitempty,min=0,max=21600"`
+
+	Filters *[]string `json:"filters,omitempty"`
+}
+
+func (h *HTTP) updateRoom(c echo.Context) error {
+	req := new(updateRoomReq)
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	room, err := h.svc.UpdateRoom(c, chat.UpdateRoom{
+		Name:        c.Param("room"),
+		Topic:       req.Topic,
+		Description: req.Description,
+		Private:     req.Private,
+		SlowMode:    req.SlowMode,
+		Filters:     req.Filters,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, room)
+}
+
+func (h *HTTP) archiveRoom(c echo.Context) error {
+	room, err := h.svc.ArchiveRoom(c, c.Param("room"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, room)
+}
+
+func (h *HTTP) deleteRoom(c echo.Context) error {
+	if err := h.svc.DeleteRoom(c, c.Param("room")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+type memberListResponse struct {
+	Members []jobsity.RoomMember `json:"members"`
+}
+
+func (h *HTTP) listMembers(c echo.Context) error {
+	result, err := h.svc.ListMembers(c, c.Param("room"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, memberListResponse{result})
+}
+
+// Member invite request
+// swagger:model memberInvite
+type inviteMemberReq struct {
+	UserID int              `json:"user_id" validate:"required"`
+	Role   jobsity.RoomRole `json:"role"`
+}
+
+func (h *HTTP) inviteMember(c echo.Context) error {
+	r := new(inviteMemberReq)
+	if err := c.Bind(r); err != nil {
+		return err
+	}
+
+	member, err := h.svc.InviteMember(c, c.Param("room"), jobsity.RoomMember{
+		UserID: r.UserID,
+		Role:   r.Role,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, member)
+}
+
+func (h *HTTP) removeMember(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+
+	if err := h.svc.RemoveMember(c, c.Param("room"), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// Moderation request
+// swagger:model moderation
+type moderationReq struct {
+	UserID int    `json:"user_id" validate:"required"`
+	Reason string `json:"reason" validate:"max=256"`
+	// Duration of the sanction, like 30s, 10m or 2h. Bans without duration are permanent.
+	Duration string `json:"duration"`
+}
+
+// moderation binds a moderation request
+func moderation(c echo.Context) (chat.Moderation, error) {
+	r := new(moderationReq)
+	if err := c.Bind(r); err != nil {
+		return chat.Moderation{}, err
+	}
+
+	req := chat.Moderation{UserID: r.UserID, Reason: r.Reason}
+	if r.Duration != "" {
+		d, err := time.ParseDuration(r.Duration)
 		if err != nil {
-			log.Println("Error receiving initial message:", err)
-			return
+			return chat.Moderation{}, chat.ErrInvalidDuration
 		}
+		req.Duration = d
+	}
+	return req, nil
+}
 
-		// Parse the message as a join command
-		if strings.HasPrefix(msg, "/join ") {
-			// Extract the room name from the command
-			room := strings.TrimPrefix(msg, "/join ")
+func (h *HTTP) kickUser(c echo.Context) error {
+	req, err := moderation(c)
+	if err != nil {
+		return err
+	}
 
-			// Join the room
-			err := h.svc.JoinRoom(c, ws, room, nil)
-			if err != nil {
-				log.Println("Error joining room:", err)
+	if err := h.svc.KickUser(c, c.Param("room"), req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *HTTP) banUser(c echo.Context) error {
+	req, err := moderation(c)
+	if err != nil {
+		return err
+	}
+
+	ban, err := h.svc.BanUser(c, c.Param("room"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, ban)
+}
+
+func (h *HTTP) unbanUser(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+
+	if err := h.svc.UnbanUser(c, c.Param("room"), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *HTTP) muteUser(c echo.Context) error {
+	req, err := moderation(c)
+	if err != nil {
+		return err
+	}
+
+	mute, err := h.svc.MuteUser(c, c.Param("room"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mute)
+}
+
+type moderationLogResponse struct {
+	Log  []jobsity.ModerationLog `json:"log"`
+	Page int                     `json:"page"`
+}
+
+func (h *HTTP) listModerationLog(c echo.Context) error {
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListModerationLog(c, c.Param("room"), req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, moderationLogResponse{result, req.Page})
+}
+
+type messageListResponse struct {
+	Messages []jobsity.Message `json:"messages"`
+	Page     int               `json:"page"`
+}
+
+func (h *HTTP) listMessages(c echo.Context) error {
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListMessages(c, c.Param("room"), req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, messageListResponse{result, req.Page})
+}
+
+// Message edit request
+// swagger:model messageEdit
+type messageEditReq struct {
+	Text string `json:"text" validate:"required"`
+}
+
+func (h *HTTP) editMessage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+	req := new(messageEditReq)
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	msg, err := h.svc.EditMessage(c, c.Param("room"), id, req.Text)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, msg)
+}
+
+func (h *HTTP) deleteMessage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+
+	if err := h.svc.DeleteMessage(c, c.Param("room"), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+type messageEditListResponse struct {
+	Edits []jobsity.MessageEdit `json:"edits"`
+}
+
+func (h *HTTP) listMessageEdits(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+
+	result, err := h.svc.ListMessageEdits(c, c.Param("room"), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, messageEditListResponse{result})
+}
+
+type threadResponse struct {
+	Parent  jobsity.Message   `json:"parent"`
+	Replies []jobsity.Message `json:"replies"`
+	Page    int               `json:"page"`
+}
+
+func (h *HTTP) viewThread(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ViewThread(c, c.Param("room"), id, req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, threadResponse{result.Parent, result.Replies, req.Page})
+}
+
+// Reaction toggle request
+// swagger:model reactionToggle
+type reactionToggleReq struct {
+	Emoji string `json:"emoji" validate:"required"`
+}
+
+func (h *HTTP) toggleReaction(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return jobsity.ErrBadRequest
+	}
+	req := new(reactionToggleReq)
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	msg, err := h.svc.ToggleReaction(c, c.Param("room"), id, req.Emoji)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, msg)
+}
+
+// Mark read request
+// swagger:model markRead
+type markReadReq struct {
+	MessageID int `json:"message_id" validate:"required"`
+}
+
+func (h *HTTP) markRead(c echo.Context) error {
+	req := new(markReadReq)
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	cursor, err := h.svc.MarkRead(c, c.Param("room"), req.MessageID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, cursor)
+}
+
+type conversationListResponse struct {
+	Conversations []jobsity.Conversation `json:"conversations"`
+	Page          int                    `json:"page"`
+}
+
+func (h *HTTP) listConversations(c echo.Context) error {
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListConversations(c, req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, conversationListResponse{result, req.Page})
+}
+
+// Direct message request
+// swagger:model directMessage
+type directMessageReq struct {
+	Text string `json:"text" validate:"required"`
+}
+
+func (h *HTTP) sendDirect(c echo.Context) error {
+	req := new(directMessageReq)
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	msg, err := h.svc.SendDirect(c, c.Param("username"), req.Text)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, msg)
+}
+
+func (h *HTTP) listDirectMessages(c echo.Context) error {
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListDirectMessages(c, c.Param("username"), req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, messageListResponse{result, req.Page})
+}
+
+type mentionListResponse struct {
+	Mentions []jobsity.Mention `json:"mentions"`
+	Page     int               `json:"page"`
+}
+
+func (h *HTTP) listMentions(c echo.Context) error {
+	var req jobsity.PaginationReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	result, err := h.svc.ListMentions(c, req.Transform())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mentionListResponse{result, req.Page})
+}
+
+type presenceListResponse struct {
+	Users []jobsity.Presence `json:"users"`
+}
+
+func (h *HTTP) listPresence(c echo.Context) error {
+	result, err := h.svc.ListPresence(c, c.QueryParam("room"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, presenceListResponse{result})
+}
+
+func (h *HTTP) handleWebSocket(c echo.Context) error {
+	// Upgrade the HTTP request to a WebSocket connection. The request already went
+	// through the JWT middleware, so the session belongs to the authenticated user.
+	wsServer := websocket.Server{
+		Handshake: ws.Handshake,
+		Handler: func(conn *websocket.Conn) {
+			// The initial frame must join a room
+			var data []byte
+			if err := websocket.Message.Receive(conn, &data); err != nil {
+				log.Println("Error receiving initial frame:", err)
+				return
+			}
+			f, err := ws.Decode(ws.Protocol(conn), data, "")
+			if err != nil || f.Type != jobsity.FrameJoin {
+				log.Println("Initial frame is not a join frame")
 				return
 			}
 
-			// Send a welcome message to the client
-			welcomeMsg := "Welcome to the " + room + " chat room!"
-			err = websocket.Message.Send(ws, welcomeMsg)
-			if err != nil {
-				log.Println("Error sending welcome message:", err)
+			// Join the room, and leave every joined room when the WebSocket connection is closed.
+			// Disconnecting writes the frames still queued, like the error of a failed join.
+			defer h.svc.Disconnect(c, conn)
+			if f, err = h.svc.HandleFrame(c, conn, "", data); err != nil {
+				log.Println("Error joining room:", err)
 				return
 			}
+			room := f.Room
 
-			// Handle WebSocket events
+			// Handle WebSocket frames
 			for {
-				// Read a message from the WebSocket
-				var msg string
-				err = websocket.Message.Receive(ws, &msg)
-				if err != nil {
-					log.Println("Error receiving message:", err)
+				if err := websocket.Message.Receive(conn, &data); err != nil {
+					log.Println("Error receiving frame:", err)
 					break
 				}
 
-				// Handle the message
-				h.svc.HandleCommand(c, ws, room, msg)
+				// Frames without a room, like plain text messages, go to the last joined room
+				f, err := h.svc.HandleFrame(c, conn, room, data)
+				if err != nil {
+					log.Println("Error handling frame:", err)
+					continue
+				}
+				switch {
+				case f.Type == jobsity.FrameJoin:
+					room = f.Room
+				case f.Type == jobsity.FrameLeave && strings.EqualFold(f.Room, room):
+					room = ""
+				}
 			}
-
-			// Leave the room when the WebSocket connection is closed
-			h.svc.LeaveRoom(c, room, ws, nil)
-		}
-	})
-	wsHandler.ServeHTTP(c.Response().Writer, c.Request())
+		},
+	}
+	wsServer.ServeHTTP(c.Response().Writer, c.Request())
 
 	return nil
 }
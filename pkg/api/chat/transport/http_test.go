@@ -0,0 +1,1412 @@
+package transport_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/api/chat/transport"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+	"my-chat-jobsity-challenge/pkg/utl/server"
+)
+
+func TestListMessages(t *testing.T) {
+	type listResponse struct {
+		Messages []jobsity.Message `json:"messages"`
+		Page     int               `json:"page"`
+	}
+	cases := []struct {
+		name       string
+		req        string
+		wantStatus int
+		wantResp   *listResponse
+		mdb        *mockdb.Message
+	}{
+		{
+			name:       "Invalid request",
+			req:        `?limit=2222&page=-1`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Fail on query",
+			req:  `?limit=100&page=1`,
+			mdb: &mockdb.Message{
+				ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+					return nil, jobsity.ErrGeneric
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			req:  `?limit=100&page=1`,
+			mdb: &mockdb.Message{
+				ListFn: func(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+					if room != "general" || p.Limit != 100 || p.Offset != 100 {
+						return nil, jobsity.ErrGeneric
+					}
+					return []jobsity.Message{
+						{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "hi"},
+						{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "hello"},
+					}, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantResp: &listResponse{
+				Messages: []jobsity.Message{
+					{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "hi"},
+					{Base: jobsity.Base{ID: 2}, Room: "general", UserID: 2, Username: "janedoe", Body: "hello"},
+				},
+				Page: 1,
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			rg := r.Group("")
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: tt.mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, rg)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			path := ts.URL + "/chat/rooms/general/messages" + tt.req
+			res, err := http.Get(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(listResponse)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	cases := []struct {
+		name     string
+		protocol string
+		join     string
+		message  string
+		wantRecv []string
+		wantJSON []jobsity.Frame
+	}{
+		{
+			name:     "Plain text",
+			protocol: ws.ProtocolText,
+			join:     "/join general",
+			message:  "hello",
+			wantRecv: []string{"janedoe: earlier", "Welcome to the general chat room!", "johndoe: hello"},
+		},
+		{
+			name:    "JSON",
+			join:    `{"version":1,"type":"join","room":"general"}`,
+			message: `{"version":1,"type":"message","payload":{"text":"hello"}}`,
+			wantJSON: []jobsity.Frame{
+				{Version: 1, Type: jobsity.FrameMessage, Room: "general", ID: 2, Sender: "janedoe",
+					Timestamp: mock.TestTime(2000), Payload: jobsity.Payload{Text: "earlier"}},
+				{Version: 1, Type: jobsity.FrameSystem, Room: "general",
+					Payload: jobsity.Payload{Text: "Welcome to the general chat room!"}},
+				{Version: 1, Type: jobsity.FrameMessage, Room: "general", ID: 3, Sender: "johndoe",
+					Timestamp: mock.TestTime(2000), Payload: jobsity.Payload{Text: "hello"}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := make(chan jobsity.Message, 1)
+			mdb := &mockdb.Message{
+				ListFn: func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error) {
+					return []jobsity.Message{{
+						Base: jobsity.Base{ID: 2, CreatedAt: mock.TestTime(2000)},
+						Room: "general", UserID: 2, Username: "janedoe", Body: "earlier",
+					}}, nil
+				},
+				CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+					saved <- msg
+					msg.ID = 3
+					msg.CreatedAt = mock.TestTime(2000)
+					return msg, nil
+				},
+			}
+			rbac := &mock.RBAC{
+				UserFn: func(echo.Context) jobsity.AuthUser {
+					return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: jobsity.UserRole}
+				},
+			}
+
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), rbac)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			conn, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/chat/ws", tt.protocol, ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if err := websocket.Message.Send(conn, tt.join); err != nil {
+				t.Fatal(err)
+			}
+			if err := websocket.Message.Send(conn, tt.message); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, jobsity.Message{Room: "general", UserID: 1, Username: "johndoe", Body: "hello"}, <-saved)
+
+			for _, want := range tt.wantRecv {
+				var got string
+				if err := websocket.Message.Receive(conn, &got); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, want, got)
+			}
+			for _, want := range tt.wantJSON {
+				var got jobsity.Frame
+				if err := websocket.JSON.Receive(conn, &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Type == jobsity.FrameSystem {
+					got.Timestamp = time.Time{}
+				}
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestWebSocketRoom(t *testing.T) {
+	r := server.New()
+	svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.NewHTTP(svc, r.Group(""))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	dial := func(join string) *websocket.Conn {
+		conn, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/chat/ws", ws.ProtocolText, ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := websocket.Message.Send(conn, join); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+	receive := func(conn *websocket.Conn, want ...string) {
+		for _, w := range want {
+			var got string
+			if err := websocket.Message.Receive(conn, &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, w, got)
+		}
+	}
+
+	// Clients failing to join are told why before being disconnected
+	conn := dial("/join random")
+	defer conn.Close()
+	receive(conn, "room not found")
+	var got string
+	assert.NotNil(t, websocket.Message.Receive(conn, &got))
+
+	// Frames without a room no longer go to the room the client left
+	conn = dial("/join general")
+	defer conn.Close()
+	receive(conn, "Welcome to the general chat room!")
+	if err := websocket.Message.Send(conn, "/leave general"); err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.Message.Send(conn, "/users"); err != nil {
+		t.Fatal(err)
+	}
+	receive(conn, "room not found")
+}
+
+// roleRBAC returns a RBAC mock authenticating a user with the given role
+func roleRBAC(role jobsity.AccessRole) *mock.RBAC {
+	return &mock.RBAC{
+		UserFn: func(echo.Context) jobsity.AuthUser {
+			return jobsity.AuthUser{ID: 1, Username: "johndoe", Role: role}
+		},
+		EnforceRoleFn: func(c echo.Context, r jobsity.AccessRole) error {
+			if role > r {
+				return echo.ErrForbidden
+			}
+			return nil
+		},
+	}
+}
+
+func TestCreateRoom(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		req        string
+		wantStatus int
+		wantResp   *jobsity.Room
+	}{
+		{
+			name:       "Fail on validation",
+			role:       jobsity.AdminRole,
+			req:        `{"topic":"Say hi"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on invalid name",
+			role:       jobsity.AdminRole,
+			req:        `{"name":"the lobby"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on RBAC",
+			role:       jobsity.UserRole,
+			req:        `{"name":"lobby"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Fail on existing room",
+			role:       jobsity.AdminRole,
+			req:        `{"name":"general"}`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Fail on unknown filter",
+			role:       jobsity.AdminRole,
+			req:        `{"name":"lobby","filters":["whisper"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.SuperAdminRole,
+			req:        `{"name":"lobby","topic":"Say hi","description":"Newcomers","filters":["strip_links"]}`,
+			wantStatus: http.StatusOK,
+			wantResp: &jobsity.Room{Base: jobsity.Base{ID: 2}, Name: "lobby", Topic: "Say hi",
+				Description: "Newcomers", CreatedBy: 1, Filters: []string{"strip_links"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/rooms", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.Room)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	type listResponse struct {
+		Rooms []jobsity.Room `json:"rooms"`
+		Page  int            `json:"page"`
+	}
+	cases := []struct {
+		name       string
+		req        string
+		wantStatus int
+		wantResp   *listResponse
+	}{
+		{
+			name:       "Invalid request",
+			req:        `?page=-1`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Success on active rooms",
+			req:        `?limit=10`,
+			wantStatus: http.StatusOK,
+			wantResp: &listResponse{Rooms: []jobsity.Room{
+				{Base: jobsity.Base{ID: 2}, Name: "general"},
+				{Base: jobsity.Base{ID: 3}, Name: "random"},
+			}},
+		},
+		{
+			name:       "Success on archived rooms",
+			req:        `?archived=true`,
+			wantStatus: http.StatusOK,
+			wantResp: &listResponse{Rooms: []jobsity.Room{
+				{Base: jobsity.Base{ID: 1}, Name: "old", Archived: true},
+			}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb := mockdb.NewRoom()
+			if _, err := rdb.Create(nil, jobsity.Room{Name: "old", Archived: true}); err != nil {
+				t.Fatal(err)
+			}
+			r := server.New()
+			svc, err := chat.New([]string{"general", "random"}, nil, mockdb.ChatRepositories(chat.Repositories{Rooms: rdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.AdminRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Get(ts.URL + "/chat/rooms" + tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(listResponse)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestUpdateRoom(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		room       string
+		req        string
+		wantStatus int
+		wantResp   *jobsity.Room
+	}{
+		{
+			name:       "Fail on validation",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			req:        `{"topic":"` + strings.Repeat("a", 257) + `"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on RBAC",
+			role:       jobsity.UserRole,
+			room:       "general",
+			req:        `{"topic":"New topic"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Fail on room not found",
+			role:       jobsity.AdminRole,
+			room:       "lobby",
+			req:        `{"topic":"New topic"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			req:        `{"topic":"New topic"}`,
+			wantStatus: http.StatusOK,
+			wantResp:   &jobsity.Room{Base: jobsity.Base{ID: 1}, Name: "general", Topic: "New topic"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/chat/rooms/"+tt.room, bytes.NewBufferString(tt.req))
+			req.Header.Set("Content-Type", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.Room)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestDeleteRoom(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		room       string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on RBAC",
+			role:       jobsity.UserRole,
+			room:       "general",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Fail on room not found",
+			role:       jobsity.AdminRole,
+			room:       "lobby",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/chat/rooms/"+tt.room, nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestInviteMember(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		room       string
+		req        string
+		wantStatus int
+		wantResp   *jobsity.RoomMember
+	}{
+		{
+			name:       "Fail on validation",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			req:        `{"role":200}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on room not found",
+			role:       jobsity.AdminRole,
+			room:       "lobby",
+			req:        `{"user_id":2}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Fail on non-member",
+			role:       jobsity.UserRole,
+			room:       "general",
+			req:        `{"user_id":2}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Fail on user not found",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			req:        `{"user_id":3}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			room:       "general",
+			req:        `{"user_id":2,"role":110}`,
+			wantStatus: http.StatusOK,
+			wantResp:   &jobsity.RoomMember{Base: jobsity.Base{ID: 1}, RoomID: 1, UserID: 2, Role: jobsity.RoomModeratorRole},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 2}, Username: "jane"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/rooms/"+tt.room+"/members", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.RoomMember)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		id         string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on invalid id",
+			role:       jobsity.AdminRole,
+			id:         "a",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on non-member",
+			role:       jobsity.AdminRole,
+			id:         "3",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Fail on RBAC",
+			role:       jobsity.UserRole,
+			id:         "2",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			id:         "2",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rmdb := mockdb.NewMember()
+			if _, err := rmdb.Create(nil, jobsity.RoomMember{RoomID: 1, UserID: 2, Role: jobsity.RoomMemberRole}); err != nil {
+				t.Fatal(err)
+			}
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Members: rmdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/chat/rooms/general/members/"+tt.id, nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestBanUser(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		req        string
+		wantStatus int
+		wantResp   *jobsity.Sanction
+	}{
+		{
+			name:       "Fail on validation",
+			role:       jobsity.AdminRole,
+			req:        `{"reason":"spam"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on invalid duration",
+			role:       jobsity.AdminRole,
+			req:        `{"user_id":2,"duration":"soon"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on self moderation",
+			role:       jobsity.AdminRole,
+			req:        `{"user_id":1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on user not found",
+			role:       jobsity.AdminRole,
+			req:        `{"user_id":3}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Fail on non-member",
+			role:       jobsity.UserRole,
+			req:        `{"user_id":2}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			req:        `{"user_id":2,"reason":"spam"}`,
+			wantStatus: http.StatusOK,
+			wantResp:   &jobsity.Sanction{Base: jobsity.Base{ID: 1}, RoomID: 1, UserID: 2, Kind: jobsity.SanctionBan, Reason: "spam", CreatedBy: 1},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 2}, Username: "jane"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/rooms/general/bans", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.Sanction)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestMuteUser(t *testing.T) {
+	cases := []struct {
+		name       string
+		req        string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on missing duration",
+			req:        `{"user_id":2}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on negative duration",
+			req:        `{"user_id":2,"duration":"-10m"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Success",
+			req:        `{"user_id":2,"duration":"10m"}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 2}, Username: "jane"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.AdminRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/rooms/general/mutes", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestUnbanUser(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on invalid id",
+			id:         "a",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on user not banned",
+			id:         "3",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			id:         "2",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			modb := mockdb.NewModeration()
+			if _, err := modb.CreateSanction(nil, jobsity.Sanction{RoomID: 1, UserID: 2, Kind: jobsity.SanctionBan}); err != nil {
+				t.Fatal(err)
+			}
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Moderation: modb, Users: mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 2}, Username: "jane"}, jobsity.User{Base: jobsity.Base{ID: 3}, Username: "joe"})}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.AdminRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/chat/rooms/general/bans/"+tt.id, nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestSendDirect(t *testing.T) {
+	cases := []struct {
+		name       string
+		username   string
+		req        string
+		wantStatus int
+		wantResp   *jobsity.Message
+	}{
+		{
+			name:       "Fail on validation",
+			username:   "jane",
+			req:        `{"text":""}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown user",
+			username:   "joe",
+			req:        `{"text":"hi"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Fail on self message",
+			username:   "johndoe",
+			req:        `{"text":"hi"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Success",
+			username:   "jane",
+			req:        `{"text":"hi"}`,
+			wantStatus: http.StatusOK,
+			wantResp:   &jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "@1:2", UserID: 1, Username: "johndoe", Body: "hi"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			mdb := &mockdb.Message{
+				CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+					msg.ID = 1
+					return msg, nil
+				},
+			}
+			udb := mockdb.NewUser(jobsity.User{Base: jobsity.Base{ID: 1}, Username: "johndoe"}, jobsity.User{Base: jobsity.Base{ID: 2}, Username: "jane"})
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb, Users: udb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/conversations/"+tt.username+"/messages", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.Message)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestListConversations(t *testing.T) {
+	type listResponse struct {
+		Conversations []jobsity.Conversation `json:"conversations"`
+		Page          int                    `json:"page"`
+	}
+	ddb := mockdb.NewDirect()
+	for _, peerID := range []int{2, 3} {
+		if err := ddb.Touch(nil, 1, peerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := server.New()
+	svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Directs: ddb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.NewHTTP(svc, r.Group(""))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/chat/conversations?page=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Get(ts.URL + "/chat/conversations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	response := new(listResponse)
+	if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &listResponse{Conversations: []jobsity.Conversation{{UserID: 1, PeerID: 3}, {UserID: 1, PeerID: 2}}}, response)
+}
+
+func TestListMentions(t *testing.T) {
+	type listResponse struct {
+		Mentions []jobsity.Mention `json:"mentions"`
+		Page     int               `json:"page"`
+	}
+	mdb := &mockdb.Message{
+		ListMentionsFn: func(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Mention, error) {
+			msg := jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 2, Username: "janedoe", Body: "@johndoe hi", Mentions: []string{"johndoe"}}
+			return []jobsity.Mention{{MessageID: 1, UserID: userID, Room: "general", Message: &msg}}, nil
+		},
+	}
+	r := server.New()
+	svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.NewHTTP(svc, r.Group(""))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/chat/mentions?page=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Get(ts.URL + "/chat/mentions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	response := new(listResponse)
+	if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, response.Mentions, 1) {
+		assert.Equal(t, 1, response.Mentions[0].UserID)
+		assert.True(t, response.Mentions[0].Unread)
+		assert.Equal(t, []string{"johndoe"}, response.Mentions[0].Message.Mentions)
+	}
+}
+
+// newSentMessageDB returns a message repository mock holding johndoe's message 1 to the general room
+func newSentMessageDB() *mockdb.Message {
+	return &mockdb.Message{
+		ViewFn: func(db orm.DB, id int) (jobsity.Message, error) {
+			if id != 1 {
+				return jobsity.Message{}, pgsql.ErrMessageNotFound
+			}
+			return jobsity.Message{Base: jobsity.Base{ID: 1, CreatedAt: time.Now()}, Room: "general", UserID: 1, Username: "johndoe", Body: "helo"}, nil
+		},
+		UpdateFn: func(db orm.DB, msg jobsity.Message) error {
+			return nil
+		},
+		DeleteFn: func(db orm.DB, msg jobsity.Message) error {
+			return nil
+		},
+		CreateEditFn: func(db orm.DB, edit jobsity.MessageEdit) (jobsity.MessageEdit, error) {
+			return edit, nil
+		},
+		ListEditsFn: func(db orm.DB, messageID int) ([]jobsity.MessageEdit, error) {
+			return []jobsity.MessageEdit{{MessageID: messageID, Body: "helo", EditedBy: 1}}, nil
+		},
+		ToggleReactionFn: func(db orm.DB, r jobsity.Reaction) (bool, error) {
+			return true, nil
+		},
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		req        string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Fail on invalid id",
+			id:         "a",
+			req:        `{"text":"hello"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on validation",
+			id:         "1",
+			req:        `{"text":""}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown message",
+			id:         "2",
+			req:        `{"text":"hello"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			id:         "1",
+			req:        `{"text":"hello"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "hello",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: newSentMessageDB()}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/chat/rooms/general/messages/"+tt.id, bytes.NewBufferString(tt.req))
+			req.Header.Set("Content-Type", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantBody != "" {
+				response := new(jobsity.Message)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantBody, response.Body)
+				assert.NotNil(t, response.EditedAt)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on invalid id",
+			id:         "a",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown message",
+			id:         "2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			id:         "1",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: newSentMessageDB()}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/chat/rooms/general/messages/"+tt.id, nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestListMessageEdits(t *testing.T) {
+	type listResponse struct {
+		Edits []jobsity.MessageEdit `json:"edits"`
+	}
+	cases := []struct {
+		name       string
+		role       jobsity.AccessRole
+		wantStatus int
+		wantResp   *listResponse
+	}{
+		{
+			name:       "Fail on non-admin",
+			role:       jobsity.UserRole,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Success",
+			role:       jobsity.AdminRole,
+			wantStatus: http.StatusOK,
+			wantResp:   &listResponse{Edits: []jobsity.MessageEdit{{MessageID: 1, Body: "helo", EditedBy: 1}}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: newSentMessageDB()}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(tt.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Get(ts.URL + "/chat/rooms/general/messages/1/edits")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(listResponse)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestViewThread(t *testing.T) {
+	type threadResponse struct {
+		Parent  jobsity.Message   `json:"parent"`
+		Replies []jobsity.Message `json:"replies"`
+	}
+	cases := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantResp   *threadResponse
+	}{
+		{
+			name:       "Fail on invalid id",
+			id:         "a",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown message",
+			id:         "2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			id:         "1",
+			wantStatus: http.StatusOK,
+			wantResp: &threadResponse{
+				Parent:  jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "helo", Replies: 1},
+				Replies: []jobsity.Message{{Base: jobsity.Base{ID: 3}, Room: "general", UserID: 2, Username: "jane", Body: "hi", ParentID: 1}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			mdb := &mockdb.Message{
+				ViewFn: func(db orm.DB, id int) (jobsity.Message, error) {
+					if id != 1 {
+						return jobsity.Message{}, pgsql.ErrMessageNotFound
+					}
+					return jobsity.Message{Base: jobsity.Base{ID: 1}, Room: "general", UserID: 1, Username: "johndoe", Body: "helo", Replies: 1}, nil
+				},
+				ListRepliesFn: func(db orm.DB, parentID int, p jobsity.Pagination) ([]jobsity.Message, error) {
+					return []jobsity.Message{{Base: jobsity.Base{ID: 3}, Room: "general", UserID: 2, Username: "jane", Body: "hi", ParentID: parentID}}, nil
+				},
+			}
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: mdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Get(ts.URL + "/chat/rooms/general/messages/" + tt.id + "/thread")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(threadResponse)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestToggleReaction(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		req        string
+		wantStatus int
+	}{
+		{
+			name:       "Fail on invalid id",
+			id:         "a",
+			req:        `{"emoji":"👍"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on validation",
+			id:         "1",
+			req:        `{"emoji":""}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on invalid emoji",
+			id:         "1",
+			req:        `{"emoji":"yes"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown message",
+			id:         "2",
+			req:        `{"emoji":"👍"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			id:         "1",
+			req:        `{"emoji":"👍"}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Messages: newSentMessageDB()}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			path := ts.URL + "/chat/rooms/general/messages/" + tt.id + "/reactions"
+			res, err := http.Post(path, "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantStatus == http.StatusOK {
+				response := new(jobsity.Message)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, 1, response.ID)
+				assert.Equal(t, "general", response.Room)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	cases := []struct {
+		name       string
+		room       string
+		req        string
+		rcdb       *mockdb.Read
+		wantStatus int
+		wantResp   *jobsity.ReadCursor
+	}{
+		{
+			name:       "Fail on validation",
+			room:       "general",
+			req:        `{"message_id":0}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Fail on unknown room",
+			room:       "notexists",
+			req:        `{"message_id":3}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Fail on unknown message",
+			room: "general",
+			req:  `{"message_id":3}`,
+			rcdb: &mockdb.Read{
+				MarkReadFn: func(db orm.DB, userID int, room string, messageID int) (bool, error) {
+					return false, pgsql.ErrMessageNotFound
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			room:       "general",
+			req:        `{"message_id":3}`,
+			wantStatus: http.StatusOK,
+			wantResp:   &jobsity.ReadCursor{UserID: 1, Room: "general", LastReadID: 3},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rcdb := tt.rcdb
+			if rcdb == nil {
+				rcdb = mockdb.NewRead()
+			}
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{Reads: rcdb}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/chat/rooms/"+tt.room+"/read", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(jobsity.ReadCursor)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestListPresence(t *testing.T) {
+	type listResponse struct {
+		Users []jobsity.Presence `json:"users"`
+	}
+	cases := []struct {
+		name       string
+		req        string
+		wantStatus int
+		wantResp   *listResponse
+	}{
+		{
+			name:       "Fail on unknown room",
+			req:        "?room=notexists",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Success",
+			wantStatus: http.StatusOK,
+			wantResp:   &listResponse{Users: []jobsity.Presence{}},
+		},
+		{
+			name:       "Success on room",
+			req:        "?room=general",
+			wantStatus: http.StatusOK,
+			wantResp:   &listResponse{Users: []jobsity.Presence{}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := server.New()
+			svc, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), roleRBAC(jobsity.UserRole))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport.NewHTTP(svc, r.Group(""))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Get(ts.URL + "/chat/presence" + tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if tt.wantResp != nil {
+				response := new(listResponse)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantResp, response)
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
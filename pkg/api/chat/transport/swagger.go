@@ -0,0 +1,138 @@
+package transport
+
+import (
+	"my-chat-jobsity-challenge"
+)
+
+// Messages model response
+// swagger:response messageListResp
+type swaggMessageListResponse struct {
+	// in:body
+	Body struct {
+		Messages []jobsity.Message `json:"messages"`
+		Page     int               `json:"page"`
+	}
+}
+
+// Room model response
+// swagger:response roomResp
+type swaggRoomResponse struct {
+	// in:body
+	Body struct {
+		*jobsity.Room
+	}
+}
+
+// Rooms model response
+// swagger:response roomListResp
+type swaggRoomListResponse struct {
+	// in:body
+	Body struct {
+		Rooms []jobsity.Room `json:"rooms"`
+		Page  int            `json:"page"`
+	}
+}
+
+// Room member model response
+// swagger:response memberResp
+type swaggMemberResponse struct {
+	// in:body
+	Body struct {
+		*jobsity.RoomMember
+	}
+}
+
+// Room members model response
+// swagger:response memberListResp
+type swaggMemberListResponse struct {
+	// in:body
+	Body struct {
+		Members []jobsity.RoomMember `json:"members"`
+	}
+}
+
+// Room sanction model response
+// swagger:response sanctionResp
+type swaggSanctionResponse struct {
+	// in:body
+	Body struct {
+		*jobsity.Sanction
+	}
+}
+
+// Moderation log model response
+// swagger:response moderationLogResp
+type swaggModerationLogResponse struct {
+	// in:body
+	Body struct {
+		Log  []jobsity.ModerationLog `json:"log"`
+		Page int                     `json:"page"`
+	}
+}
+
+// Message model response
+// swagger:response messageResp
+type swaggMessageResponse struct {
+	// in:body
+	Body struct {
+		*jobsity.Message
+	}
+}
+
+// Thread response
+// swagger:response threadResp
+type swaggThreadResponse struct {
+	// in:body
+	Body struct {
+		Parent  jobsity.Message   `json:"parent"`
+		Replies []jobsity.Message `json:"replies"`
+		Page    int               `json:"page"`
+	}
+}
+
+// Message edit list response
+// swagger:response messageEditListResp
+type swaggMessageEditListResponse struct {
+	// in:body
+	Body struct {
+		Edits []jobsity.MessageEdit `json:"edits"`
+	}
+}
+
+// Read cursor model response
+// swagger:response readCursorResp
+type swaggReadCursorResponse struct {
+	// in:body
+	Body struct {
+		*jobsity.ReadCursor
+	}
+}
+
+// Presence model response
+// swagger:response presenceListResp
+type swaggPresenceListResponse struct {
+	// in:body
+	Body struct {
+		Users []jobsity.Presence `json:"users"`
+	}
+}
+
+// Conversations model response
+// swagger:response conversationListResp
+type swaggConversationListResponse struct {
+	// in:body
+	Body struct {
+		Conversations []jobsity.Conversation `json:"conversations"`
+		Page          int                    `json:"page"`
+	}
+}
+
+// Mentions model response
+// swagger:response mentionListResp
+type swaggMentionListResponse struct {
+	// in:body
+	Body struct {
+		Mentions []jobsity.Mention `json:"mentions"`
+		Page     int               `json:"page"`
+	}
+}
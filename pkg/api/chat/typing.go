@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Typing indicator defaults
+const (
+	DefaultTypingInterval = 3 * time.Second
+	DefaultTypingTimeout  = 6 * time.Second
+)
+
+// TypingConfig holds the typing indicator settings
+type TypingConfig struct {
+	// Interval is the shortest time between the typing frames of a user relayed to a room
+	Interval time.Duration
+	// Timeout is how long a user stays typing after their last typing frame
+	Timeout time.Duration
+}
+
+// SetTyping replaces the typing indicator settings of the chat
+func (s *Chat) SetTyping(cfg TypingConfig) {
+	s.typing.configure(cfg)
+}
+
+// Typing relays to the room's other clients that the user of the connection started or stopped
+// typing. Typing frames are throttled, never persisted, and expire unless another one follows in time.
+func (s *Chat) Typing(c echo.Context, conn *websocket.Conn, roomName string, typing bool) error {
+	cl := s.session(c, conn)
+	room, ok := cl.joinedRoom(roomName)
+	if !ok {
+		return ErrNotInRoom
+	}
+	if !typing {
+		return s.stopTyping(room, cl.User)
+	}
+
+	key := room.Key()
+	t := typist{room: key, userID: cl.User.ID}
+	if !s.typing.start(t, time.Now(), func() { s.relayStopTyping(room, cl.User) }) {
+		return nil
+	}
+	// Muted users are not shown typing
+	if err := s.enforceNotSanctioned(room, cl.User.ID, jobsity.SanctionMute); err != nil {
+		s.typing.stop(t)
+		return err
+	}
+	return s.broadcast(key, jobsity.NewFrame(jobsity.FrameTyping, room.Name, cl.User.Username, ""), cl.Client)
+}
+
+// stopTyping lets the room know the user stopped typing in it, if they were
+func (s *Chat) stopTyping(room jobsity.Room, user jobsity.AuthUser) error {
+	if !s.typing.stop(typist{room: room.Key(), userID: user.ID}) {
+		return nil
+	}
+	return s.relayStopTyping(room, user)
+}
+
+// relayStopTyping broadcasts that the user stopped typing, unless the room was stopped meanwhile
+func (s *Chat) relayStopTyping(room jobsity.Room, user jobsity.AuthUser) error {
+	key := room.Key()
+	if !s.hub.Has(key) {
+		return nil
+	}
+	return s.broadcast(key, jobsity.NewFrame(jobsity.FrameStopTyping, room.Name, user.Username, ""), nil)
+}
+
+// typist identifies a user typing in a room
+type typist struct {
+	room   string
+	userID int
+}
+
+// typingState holds when a typist was last relayed and when they stop typing
+type typingState struct {
+	relayed time.Time
+	expires time.Time
+	timer   *time.Timer
+}
+
+// typingTracker tracks the users typing in the rooms, throttling their typing frames and expiring them
+type typingTracker struct {
+	mu      sync.Mutex
+	cfg     TypingConfig
+	typists map[typist]*typingState
+}
+
+func newTypingTracker(cfg TypingConfig) *typingTracker {
+	return &typingTracker{cfg: cfg, typists: make(map[typist]*typingState)}
+}
+
+func (tt *typingTracker) configure(cfg TypingConfig) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.cfg = cfg
+}
+
+// start records a typing frame of the typist, reporting whether to relay it. Typists who send no
+// other typing frame before the timeout expire, calling expire.
+func (tt *typingTracker) start(t typist, now time.Time, expire func()) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	st, ok := tt.typists[t]
+	if !ok {
+		st = &typingState{}
+		st.timer = time.AfterFunc(tt.cfg.Timeout, func() {
+			if tt.expired(t, st) {
+				expire()
+			}
+		})
+		tt.typists[t] = st
+	} else {
+		st.timer.Reset(tt.cfg.Timeout)
+	}
+	st.expires = now.Add(tt.cfg.Timeout)
+	if ok && now.Sub(st.relayed) < tt.cfg.Interval {
+		return false
+	}
+	st.relayed = now
+	return true
+}
+
+// expired reports whether the typist's state timed out, in which case it is forgotten
+func (tt *typingTracker) expired(t typist, st *typingState) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if tt.typists[t] != st || time.Now().Before(st.expires) {
+		return false
+	}
+	delete(tt.typists, t)
+	return true
+}
+
+// stop forgets the typist, reporting whether they were typing
+func (tt *typingTracker) stop(t typist) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	st, ok := tt.typists[t]
+	if ok {
+		st.timer.Stop()
+		delete(tt.typists, t)
+	}
+	return ok
+}
@@ -0,0 +1,76 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat"
+	ws "my-chat-jobsity-challenge/pkg/api/chat/platform/websocket"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+	"my-chat-jobsity-challenge/pkg/utl/mock/mockdb"
+)
+
+// receiveFrom receives the next frame of a JSON connection, checking its type and sender
+func receiveFrom(t *testing.T, conn *websocket.Conn, typ, sender string) {
+	var f jobsity.Frame
+	if err := websocket.JSON.Receive(conn, &f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, typ, f.Type)
+	assert.Equal(t, sender, f.Sender)
+}
+
+func TestTyping(t *testing.T) {
+	s, err := chat.New([]string{"general"}, nil, mockdb.ChatRepositories(chat.Repositories{}), broker.NewMemory(), nil, ws.NewHub(ws.Config{}), membersRBAC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTyping(chat.TypingConfig{Interval: time.Hour, Timeout: 50 * time.Millisecond})
+
+	moderator, moderatorPeer := mock.NewWSConn(t, ws.ProtocolText)
+	owner, ownerPeer := mock.NewWSConn(t, ws.ProtocolJSON)
+	member, _ := mock.NewWSConn(t, ws.ProtocolJSON)
+	assert.Nil(t, s.JoinRoom(userCtx("moderator"), moderator, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("owner"), owner, "general"))
+	assert.Nil(t, s.JoinRoom(userCtx("member"), member, "general"))
+	receiveFrame(t, ownerPeer, jobsity.FrameSystem, "Welcome to the general chat room!")
+	receiveFrom(t, ownerPeer, jobsity.FrameJoin, "member")
+	receive(t, moderatorPeer, "Welcome to the general chat room!", "owner joined the room", "member joined the room")
+
+	typing := []byte(`{"version":1,"type":"typing"}`)
+	_, err = s.HandleFrame(userCtx("member"), member, "random", typing)
+	assert.Equal(t, chat.ErrNotInRoom, err)
+
+	// Typing frames are throttled, and users stop typing with their message
+	for i := 0; i < 2; i++ {
+		_, err = s.HandleFrame(userCtx("member"), member, "general", typing)
+		assert.Nil(t, err)
+	}
+	receiveFrom(t, ownerPeer, jobsity.FrameTyping, "member")
+	assert.Nil(t, s.SendMessage(userCtx("member"), member, "general", "hello"))
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "hello")
+	receiveFrom(t, ownerPeer, jobsity.FrameStopTyping, "member")
+
+	// Typing expires unless another typing frame follows in time
+	_, err = s.HandleFrame(userCtx("member"), member, "general", typing)
+	assert.Nil(t, err)
+	receiveFrom(t, ownerPeer, jobsity.FrameTyping, "member")
+	receiveFrom(t, ownerPeer, jobsity.FrameStopTyping, "member")
+
+	// Typing is stopped explicitly only once
+	assert.Nil(t, s.Typing(userCtx("member"), member, "general", true))
+	receiveFrom(t, ownerPeer, jobsity.FrameTyping, "member")
+	assert.Nil(t, s.Typing(userCtx("member"), member, "general", false))
+	assert.Nil(t, s.Typing(userCtx("member"), member, "general", false))
+	receiveFrom(t, ownerPeer, jobsity.FrameStopTyping, "member")
+
+	// Plain text clients do not receive typing indicators
+	assert.Nil(t, s.SendMessage(userCtx("owner"), owner, "general", "bye"))
+	receive(t, moderatorPeer, "member: hello", "owner: bye")
+	receiveFrame(t, ownerPeer, jobsity.FrameMessage, "bye")
+}
@@ -0,0 +1,62 @@
+package stockbot
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is the time quotes are cached for unless configured otherwise
+const defaultCacheTTL = time.Minute
+
+// Cache is a quote provider caching the quotes of another provider for a TTL per symbol.
+// Failed lookups are not cached.
+type Cache struct {
+	provider QuoteProvider
+	ttl      time.Duration
+
+	mu     sync.Mutex
+	quotes map[string]cachedQuote
+}
+
+type cachedQuote struct {
+	price   float64
+	expires time.Time
+}
+
+// NewCache creates a new quote cache
+func NewCache(p QuoteProvider, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &Cache{
+		provider: p,
+		ttl:      ttl,
+		quotes:   make(map[string]cachedQuote),
+	}
+}
+
+// Quote returns the cached quote of a stock, fetching it if missing or expired
+func (c *Cache) Quote(stockCode string) (float64, error) {
+	symbol := strings.ToUpper(stockCode)
+	now := time.Now()
+	c.mu.Lock()
+	q, ok := c.quotes[symbol]
+	if ok && now.After(q.expires) {
+		delete(c.quotes, symbol)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return q.price, nil
+	}
+
+	price, err := c.provider.Quote(stockCode)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.quotes[symbol] = cachedQuote{price: price, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return price, nil
+}
@@ -0,0 +1,46 @@
+package stockbot_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/stockbot"
+)
+
+func TestCache(t *testing.T) {
+	calls := map[string]int{}
+	prices := map[string]float64{"AAPL.US": 165.02}
+	c := stockbot.NewCache(stockbot.QuoteFunc(func(stockCode string) (float64, error) {
+		calls[stockCode]++
+		price, ok := prices[stockCode]
+		if !ok {
+			return 0, jobsity.ErrGeneric
+		}
+		return price, nil
+	}), 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		price, err := c.Quote("AAPL.US")
+		assert.Nil(t, err)
+		assert.Equal(t, 165.02, price)
+	}
+	assert.Equal(t, 1, calls["AAPL.US"])
+
+	// Failed lookups are not cached
+	for i := 0; i < 2; i++ {
+		_, err := c.Quote("APPL.US")
+		assert.Equal(t, jobsity.ErrGeneric, err)
+	}
+	assert.Equal(t, 2, calls["APPL.US"])
+
+	// Expired quotes are fetched again
+	prices["AAPL.US"] = 166.50
+	time.Sleep(60 * time.Millisecond)
+	price, err := c.Quote("AAPL.US")
+	assert.Nil(t, err)
+	assert.Equal(t, 166.50, price)
+	assert.Equal(t, 2, calls["AAPL.US"])
+}
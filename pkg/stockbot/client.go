@@ -0,0 +1,42 @@
+package stockbot
+
+import (
+	"encoding/json"
+	"time"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+)
+
+// defaultTimeout is the time to wait for the stock bot's reply unless configured otherwise
+const defaultTimeout = 10 * time.Second
+
+// Client requests stock quotes from the stock bot through the broker
+type Client struct {
+	broker  broker.Broker
+	topic   string
+	timeout time.Duration
+}
+
+// NewClient creates a new stock bot client
+func NewClient(b broker.Broker, topic string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{broker: b, topic: topic, timeout: timeout}
+}
+
+// Quote requests the stock quote and waits for the stock bot's reply
+func (c *Client) Quote(stockCode string) (jobsity.StockReply, error) {
+	var reply jobsity.StockReply
+	body, err := json.Marshal(jobsity.StockRequest{Code: stockCode})
+	if err != nil {
+		return reply, err
+	}
+	data, err := c.broker.Request(c.topic, body, c.timeout)
+	if err != nil {
+		return reply, err
+	}
+	err = json.Unmarshal(data, &reply)
+	return reply, err
+}
@@ -0,0 +1,64 @@
+package stockbot_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/stockbot"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestQuote(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+	client := stockbot.NewClient(b, "stock_requests", 100*time.Millisecond)
+
+	_, err := client.Quote("AAPL.US")
+	assert.Equal(t, broker.ErrNoResponder, err)
+
+	bot := stockbot.New(stockbot.QuoteFunc(func(stockCode string) (float64, error) {
+		switch stockCode {
+		case "AAPL.US":
+			return 93.42, nil
+		case "SLOW.US":
+			time.Sleep(time.Second)
+		}
+		return 0, jobsity.ErrGeneric
+	}))
+	assert.Nil(t, bot.Serve(b, "stock_requests"))
+
+	reply, err := client.Quote("AAPL.US")
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.StockReply{Code: "AAPL.US", Price: 93.42}, reply)
+
+	reply, err = client.Quote("APPL.US")
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.StockReply{Code: "APPL.US", Error: "stock quote is unavailable, please try again later"}, reply)
+
+	_, err = client.Quote("SLOW.US")
+	assert.Equal(t, broker.ErrTimeout, err)
+}
+
+func TestBotWithStooq(t *testing.T) {
+	ts := httptest.NewServer(mock.Stooq(map[string]float64{"AAPL.US": 165.02}))
+	defer ts.Close()
+	b := broker.NewMemory()
+	defer b.Close()
+
+	bot := stockbot.New(stockbot.NewCache(stockbot.NewStooq(ts.URL, time.Second), time.Minute))
+	assert.Nil(t, bot.Serve(b, "stock_requests"))
+	client := stockbot.NewClient(b, "stock_requests", time.Second)
+
+	reply, err := client.Quote("AAPL.US")
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.StockReply{Code: "AAPL.US", Price: 165.02}, reply)
+
+	reply, err = client.Quote("APPL.US")
+	assert.Nil(t, err)
+	assert.Equal(t, jobsity.StockReply{Code: "APPL.US", Error: "APPL.US quote not found"}, reply)
+}
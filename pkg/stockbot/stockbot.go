@@ -0,0 +1,102 @@
+// Package stockbot implements the stock bot, which answers stock quote
+// requests of the chat service through the message broker
+package stockbot
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+	"my-chat-jobsity-challenge/pkg/utl/config"
+)
+
+// quoteUnavailable is the error replied for quotes failing other than for unknown symbols, whose details
+// are only logged, since replies are posted to chat rooms
+const quoteUnavailable = "stock quote is unavailable, please try again later"
+
+// QuoteProvider represents stock quote provider interface
+type QuoteProvider interface {
+	Quote(stockCode string) (float64, error)
+}
+
+// QuoteFunc is an adapter to use a function as a QuoteProvider
+type QuoteFunc func(stockCode string) (float64, error)
+
+// Quote calls f(stockCode)
+func (f QuoteFunc) Quote(stockCode string) (float64, error) {
+	return f(stockCode)
+}
+
+// Bot represents the stock bot
+type Bot struct {
+	quotes QuoteProvider
+}
+
+// Start connects to the broker and answers stock quote requests until interrupted
+func Start(cfg *config.Configuration) error {
+	b, err := broker.New(cfg.Broker.Driver, brokerURL(cfg), cfg.Broker.Exchange)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if err := New(NewProvider(cfg.StockBot)).Serve(b, cfg.Chat.StockQueue); err != nil {
+		return err
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+	return nil
+}
+
+// brokerURL returns the broker URL, set by RABBITMQ_URL or the configuration file
+func brokerURL(cfg *config.Configuration) string {
+	if url := os.Getenv("RABBITMQ_URL"); url != "" {
+		return url
+	}
+	return cfg.Broker.URL
+}
+
+// NewProvider creates the configured stock quote provider: stooq.com, or the API set by
+// STOOQ_API_URL, with its replies cached
+func NewProvider(cfg *config.StockBot) QuoteProvider {
+	apiURL := os.Getenv("STOOQ_API_URL")
+	if apiURL == "" {
+		apiURL = cfg.APIURL
+	}
+	stooq := NewStooq(apiURL, time.Duration(cfg.Timeout)*time.Second)
+	return NewCache(stooq, time.Duration(cfg.CacheTTL)*time.Second)
+}
+
+// New creates a new stock bot
+func New(quotes QuoteProvider) *Bot {
+	return &Bot{quotes: quotes}
+}
+
+// Serve answers stock quote requests sent to the topic
+func (b *Bot) Serve(br broker.Broker, topic string) error {
+	return br.Respond(topic, b.Handle)
+}
+
+// Handle answers an encoded stock quote request with an encoded reply
+func (b *Bot) Handle(body []byte) []byte {
+	var req jobsity.StockRequest
+	var reply jobsity.StockReply
+	if err := json.Unmarshal(body, &req); err != nil || req.Code == "" {
+		reply.Error = "invalid stock quote request"
+	} else if price, err := b.quotes.Quote(req.Code); errors.Is(err, ErrQuoteNotFound) {
+		reply = jobsity.StockReply{Code: req.Code, Error: err.Error()}
+	} else if err != nil {
+		log.Printf("stockbot: quoting %s: %v", req.Code, err)
+		reply = jobsity.StockReply{Code: req.Code, Error: quoteUnavailable}
+	} else {
+		reply = jobsity.StockReply{Code: req.Code, Price: price}
+	}
+	data, _ := json.Marshal(reply)
+	return data
+}
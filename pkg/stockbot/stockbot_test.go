@@ -0,0 +1,60 @@
+package stockbot_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge/pkg/stockbot"
+)
+
+func TestHandle(t *testing.T) {
+	cases := []struct {
+		name     string
+		req      string
+		wantResp string
+	}{
+		{
+			name:     "Fail on invalid request",
+			req:      `aapl.us`,
+			wantResp: `{"code":"","error":"invalid stock quote request"}`,
+		},
+		{
+			name:     "Fail on missing code",
+			req:      `{}`,
+			wantResp: `{"code":"","error":"invalid stock quote request"}`,
+		},
+		{
+			name:     "Fail on unknown code",
+			req:      `{"code":"APPL.US"}`,
+			wantResp: `{"code":"APPL.US","error":"APPL.US quote not found"}`,
+		},
+		{
+			name:     "Fail on quote without details",
+			req:      `{"code":"DOWN.US"}`,
+			wantResp: `{"code":"DOWN.US","error":"stock quote is unavailable, please try again later"}`,
+		},
+		{
+			name:     "Success",
+			req:      `{"code":"AAPL.US"}`,
+			wantResp: `{"code":"AAPL.US","price":93.42}`,
+		},
+	}
+	quote := func(stockCode string) (float64, error) {
+		switch stockCode {
+		case "AAPL.US":
+			return 93.42, nil
+		case "APPL.US":
+			return 0, fmt.Errorf("%s %w", stockCode, stockbot.ErrQuoteNotFound)
+		}
+		return 0, errors.New("dial tcp stooq.com:443: connection refused")
+	}
+	bot := stockbot.New(stockbot.QuoteFunc(quote))
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantResp, string(bot.Handle([]byte(tt.req))))
+		})
+	}
+}
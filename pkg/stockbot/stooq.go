@@ -0,0 +1,92 @@
+package stockbot
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stooq defaults
+const (
+	DefaultStooqURL     = "https://stooq.com/q/l/"
+	defaultStooqTimeout = 5 * time.Second
+)
+
+// stooqNoData is the value stooq returns for unknown symbols
+const stooqNoData = "N/D"
+
+// ErrQuoteNotFound is returned for symbols without a quote
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// Stooq is a quote provider for the stooq.com CSV API
+type Stooq struct {
+	url    string
+	client *http.Client
+}
+
+// NewStooq creates a new stooq quote provider for the API at apiURL
+func NewStooq(apiURL string, timeout time.Duration) *Stooq {
+	if apiURL == "" {
+		apiURL = DefaultStooqURL
+	}
+	if timeout <= 0 {
+		timeout = defaultStooqTimeout
+	}
+	return &Stooq{
+		url:    apiURL,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Quote returns the last closing price of a stock
+func (s *Stooq) Quote(stockCode string) (float64, error) {
+	stockCode = strings.ToUpper(stockCode)
+	q := url.Values{"s": {strings.ToLower(stockCode)}, "f": {"sd2t2ohlcv"}, "e": {"csv"}}
+	// h requests the header row, and takes no value
+	resp, err := s.client.Get(s.url + "?" + q.Encode() + "&h")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("stooq responded with status %d", resp.StatusCode)
+	}
+
+	// The first record is the header, naming the columns of the quote record
+	reader := csv.NewReader(resp.Body)
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("invalid stooq response: %v", err)
+	}
+	closeIdx := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "Close") {
+			closeIdx = i
+		}
+	}
+	if closeIdx < 0 {
+		return 0, errors.New("invalid stooq response: missing Close column")
+	}
+
+	record, err := reader.Read()
+	if err == io.EOF {
+		return 0, fmt.Errorf("%s %w", stockCode, ErrQuoteNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid stooq response: %v", err)
+	}
+	if closeIdx >= len(record) || record[closeIdx] == stooqNoData {
+		return 0, fmt.Errorf("%s %w", stockCode, ErrQuoteNotFound)
+	}
+	price, err := strconv.ParseFloat(record[closeIdx], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stooq response: %v", err)
+	}
+	return price, nil
+}
@@ -0,0 +1,99 @@
+package stockbot_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge/pkg/stockbot"
+	"my-chat-jobsity-challenge/pkg/utl/mock"
+)
+
+func TestStooq(t *testing.T) {
+	cases := []struct {
+		name      string
+		handler   http.HandlerFunc
+		code      string
+		wantPrice float64
+		wantErr   string
+	}{
+		{
+			name:    "Fail on unknown symbol",
+			handler: mock.Stooq(map[string]float64{"AAPL.US": 165.02}),
+			code:    "appl.us",
+			wantErr: "APPL.US quote not found",
+		},
+		{
+			name: "Fail on server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			code:    "aapl.us",
+			wantErr: "stooq responded with status 503",
+		},
+		{
+			name: "Fail on missing header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "AAPL.US,2023-04-21,22:00:19,165.05,166.45,164.49,165.02,46467543")
+			},
+			code:    "aapl.us",
+			wantErr: "invalid stooq response: missing Close column",
+		},
+		{
+			name: "Fail on empty response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "Symbol,Date,Time,Open,High,Low,Close,Volume")
+			},
+			code:    "aapl.us",
+			wantErr: "AAPL.US quote not found",
+		},
+		{
+			name: "Fail on timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+			},
+			code:    "aapl.us",
+			wantErr: "Client.Timeout exceeded",
+		},
+		{
+			name:      "Success",
+			handler:   mock.Stooq(map[string]float64{"AAPL.US": 165.02}),
+			code:      "aapl.us",
+			wantPrice: 165.02,
+		},
+		{
+			name: "Success with reordered columns",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, "Symbol,Close,Date")
+				fmt.Fprintln(w, "AAPL.US,165.02,2023-04-21")
+			},
+			code:      "AAPL.US",
+			wantPrice: 165.02,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+			price, err := stockbot.NewStooq(ts.URL, 100*time.Millisecond).Quote(tt.code)
+			if tt.wantErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantPrice, price)
+		})
+	}
+
+	ts := httptest.NewServer(mock.Stooq(nil))
+	defer ts.Close()
+	_, err := stockbot.NewStooq(ts.URL, time.Second).Quote("appl.us")
+	assert.True(t, errors.Is(err, stockbot.ErrQuoteNotFound))
+}
@@ -0,0 +1,352 @@
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Reconnection backoff bounds
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// AMQP is a RabbitMQ broker. Published messages are routed through a topic exchange
+// to an exclusive queue per subscriber, while requests go through a durable queue named
+// after the topic, shared by its responders. Lost connections are re-established with
+// exponential backoff, declaring the topology and restoring subscriptions and responders.
+type AMQP struct {
+	url      string
+	exchange string
+
+	// mu guards the connection, and is held while the topology is declared,
+	// so subscriptions are never set up twice on the same channel
+	mu         sync.RWMutex
+	conn       *amqp.Connection
+	ch         *amqp.Channel
+	replyTo    string
+	queues     map[string]bool
+	subs       []*subscription
+	responders []responder
+
+	pmu     sync.Mutex
+	pending map[string]chan []byte
+
+	done chan struct{}
+	once sync.Once
+}
+
+type subscription struct {
+	b     *AMQP
+	topic string
+	h     Handler
+	tag   string
+}
+
+type responder struct {
+	topic string
+	r     Responder
+}
+
+// NewAMQP connects to RabbitMQ and declares the exchange
+func NewAMQP(url, exchange string) (*AMQP, error) {
+	b := &AMQP{
+		url:      url,
+		exchange: exchange,
+		pending:  make(map[string]chan []byte),
+		done:     make(chan struct{}),
+	}
+	b.mu.Lock()
+	err := b.connect()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	go b.watch()
+	return b, nil
+}
+
+// connect dials the broker and declares the topology. It must be called with mu held.
+func (b *AMQP) connect() error {
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := ch.ExchangeDeclare(b.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	replies, err := ch.Consume(replyQueue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	go b.route(replies)
+
+	b.conn, b.ch, b.replyTo = conn, ch, replyQueue.Name
+	b.queues = make(map[string]bool)
+	for _, s := range b.subs {
+		if err := b.subscribe(s); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	for _, r := range b.responders {
+		if err := b.respond(r); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// watch re-establishes the connection whenever it or its channel gets closed, until the broker is closed
+func (b *AMQP) watch() {
+	for {
+		b.mu.RLock()
+		connClosed := b.conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := b.ch.NotifyClose(make(chan *amqp.Error, 1))
+		conn := b.conn
+		b.mu.RUnlock()
+
+		select {
+		case err := <-connClosed:
+			log.Println("Broker connection closed:", err)
+		case err := <-chClosed:
+			log.Println("Broker channel closed:", err)
+			conn.Close()
+		case <-b.done:
+			return
+		}
+
+		for delay := minReconnectDelay; ; delay *= 2 {
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+			select {
+			case <-time.After(delay):
+			case <-b.done:
+				return
+			}
+			b.mu.Lock()
+			err := b.connect()
+			b.mu.Unlock()
+			if err == nil {
+				break
+			}
+			log.Println("Error reconnecting to broker:", err)
+		}
+	}
+}
+
+// Publish routes the message through the exchange to every subscriber of the topic
+func (b *AMQP) Publish(topic string, body []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ch.Publish(b.exchange, topic, false, false, amqp.Publishing{Body: body})
+}
+
+// Subscribe binds an exclusive queue to the topic and calls the handler sequentially for each of its messages
+func (b *AMQP) Subscribe(topic string, h Handler) (Subscription, error) {
+	s := &subscription{b: b, topic: topic, h: h}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.subscribe(s); err != nil {
+		return nil, err
+	}
+	b.subs = append(b.subs, s)
+	return s, nil
+}
+
+func (b *AMQP) subscribe(s *subscription) error {
+	tag, err := correlationID()
+	if err != nil {
+		return err
+	}
+	q, err := b.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+	if err := b.ch.QueueBind(q.Name, s.topic, b.exchange, false, nil); err != nil {
+		return err
+	}
+	deliveries, err := b.ch.Consume(q.Name, tag, true, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+	s.tag = tag
+	go func() {
+		for d := range deliveries {
+			s.h(d.Body)
+		}
+	}()
+	return nil
+}
+
+// Unsubscribe cancels the subscription's consumer, which deletes its queue
+func (s *subscription) Unsubscribe() error {
+	b := s.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, v := range b.subs {
+		if v == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return b.ch.Cancel(s.tag, false)
+		}
+	}
+	return nil
+}
+
+// Request publishes the request to the topic's queue and waits for the reply,
+// matched by correlation ID. Requests expire in the queue once nobody waits for them.
+func (b *AMQP) Request(topic string, body []byte, timeout time.Duration) ([]byte, error) {
+	id, err := correlationID()
+	if err != nil {
+		return nil, err
+	}
+	reply := make(chan []byte, 1)
+	b.pmu.Lock()
+	b.pending[id] = reply
+	b.pmu.Unlock()
+	defer func() {
+		b.pmu.Lock()
+		delete(b.pending, id)
+		b.pmu.Unlock()
+	}()
+
+	if err := b.publishRequest(topic, id, body, timeout); err != nil {
+		return nil, err
+	}
+
+	select {
+	case data := <-reply:
+		return data, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-b.done:
+		return nil, ErrClosed
+	}
+}
+
+func (b *AMQP) publishRequest(topic, id string, body []byte, timeout time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.declareQueue(topic); err != nil {
+		return err
+	}
+	return b.ch.Publish("", topic, false, false, amqp.Publishing{
+		CorrelationId: id,
+		ReplyTo:       b.replyTo,
+		Expiration:    strconv.FormatInt(timeout.Milliseconds(), 10),
+		Body:          body,
+	})
+}
+
+// route delivers the replies to the requests waiting for them. Replies arriving
+// after their request timed out are discarded.
+func (b *AMQP) route(replies <-chan amqp.Delivery) {
+	for d := range replies {
+		b.pmu.Lock()
+		reply, ok := b.pending[d.CorrelationId]
+		b.pmu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case reply <- d.Body:
+		default:
+			// duplicate reply
+		}
+	}
+}
+
+// Respond consumes the topic's requests and publishes the replies to the queues they name
+func (b *AMQP) Respond(topic string, r Responder) error {
+	resp := responder{topic: topic, r: r}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.respond(resp); err != nil {
+		return err
+	}
+	b.responders = append(b.responders, resp)
+	return nil
+}
+
+func (b *AMQP) respond(resp responder) error {
+	if err := b.declareQueue(resp.topic); err != nil {
+		return err
+	}
+	deliveries, err := b.ch.Consume(resp.topic, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	ch := b.ch
+	go func() {
+		for d := range deliveries {
+			if d.ReplyTo == "" || d.CorrelationId == "" {
+				// Nobody is waiting for the reply
+				d.Ack(false)
+				continue
+			}
+			err := ch.Publish("", d.ReplyTo, false, false, amqp.Publishing{
+				CorrelationId: d.CorrelationId,
+				Body:          resp.r(d.Body),
+			})
+			if err != nil {
+				log.Println("Error publishing reply:", err)
+				d.Nack(false, true)
+				continue
+			}
+			d.Ack(false)
+		}
+	}()
+	return nil
+}
+
+// declareQueue declares the durable queue of a request topic once per channel. It must be called with mu held.
+func (b *AMQP) declareQueue(topic string) error {
+	if b.queues[topic] {
+		return nil
+	}
+	if _, err := b.ch.QueueDeclare(topic, true, false, false, false, nil); err != nil {
+		return err
+	}
+	b.queues[topic] = true
+	return nil
+}
+
+// Close closes the connection, without reconnecting
+func (b *AMQP) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.done)
+		b.mu.RLock()
+		err = b.conn.Close()
+		b.mu.RUnlock()
+	})
+	return err
+}
+
+func correlationID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,60 @@
+// Package broker provides publish/subscribe and request/reply messaging,
+// either in-process or over RabbitMQ
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported drivers
+const (
+	DriverMemory = "memory"
+	DriverAMQP   = "amqp"
+)
+
+// Custom errors
+var (
+	ErrTimeout     = errors.New("no reply received in time")
+	ErrNoResponder = errors.New("no responder for topic")
+	ErrClosed      = errors.New("broker closed")
+)
+
+// Handler processes a message published to a subscribed topic
+type Handler func(body []byte)
+
+// Responder answers a request with a reply
+type Responder func(body []byte) []byte
+
+// Subscription represents a topic subscription
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Broker represents message broker interface. Every subscriber of a topic receives
+// each message published to it, while each request is answered by a single responder.
+type Broker interface {
+	Publish(topic string, body []byte) error
+	Subscribe(topic string, h Handler) (Subscription, error)
+	Request(topic string, body []byte, timeout time.Duration) ([]byte, error)
+	Respond(topic string, r Responder) error
+	Close() error
+}
+
+// New creates a broker of the given driver. The AMQP driver connects to url and
+// publishes messages to exchange.
+func New(driver, url, exchange string) (Broker, error) {
+	switch driver {
+	case "", DriverMemory:
+		return NewMemory(), nil
+	case DriverAMQP:
+		b, err := NewAMQP(url, exchange)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown broker driver %q", driver)
+	}
+}
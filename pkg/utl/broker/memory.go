@@ -0,0 +1,143 @@
+package broker
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriptionQueueSize is the number of messages buffered for each in-process subscriber
+const subscriptionQueueSize = 256
+
+// Memory is an in-process broker, used by tests and single-node deployments
+type Memory struct {
+	mu         sync.RWMutex
+	subs       map[string][]*memorySub
+	responders map[string][]Responder
+	next       map[string]int
+	done       chan struct{}
+	once       sync.Once
+}
+
+type memorySub struct {
+	m     *Memory
+	topic string
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewMemory creates a new in-process broker
+func NewMemory() *Memory {
+	return &Memory{
+		subs:       make(map[string][]*memorySub),
+		responders: make(map[string][]Responder),
+		next:       make(map[string]int),
+		done:       make(chan struct{}),
+	}
+}
+
+// Publish delivers the message to every subscriber of the topic, in the order it was published
+func (m *Memory) Publish(topic string, body []byte) error {
+	m.mu.RLock()
+	subs := m.subs[topic]
+	m.mu.RUnlock()
+	for _, s := range subs {
+		select {
+		case s.queue <- body:
+		case <-s.done:
+		case <-m.done:
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a handler, called sequentially for each message published to the topic
+func (m *Memory) Subscribe(topic string, h Handler) (Subscription, error) {
+	s := &memorySub{
+		m:     m,
+		topic: topic,
+		queue: make(chan []byte, subscriptionQueueSize),
+		done:  make(chan struct{}),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.done:
+		return nil, ErrClosed
+	default:
+	}
+	m.subs[topic] = append(m.subs[topic], s)
+	go func() {
+		for {
+			select {
+			case body := <-s.queue:
+				h(body)
+			case <-s.done:
+				return
+			case <-m.done:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Unsubscribe stops the subscription's handler. Messages not handled yet are dropped.
+func (s *memorySub) Unsubscribe() error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	subs := s.m.subs[s.topic]
+	for i, v := range subs {
+		if v == s {
+			s.m.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.once.Do(func() {
+		close(s.done)
+	})
+	return nil
+}
+
+// Request sends the request to one of the topic's responders, chosen round-robin, and waits for the reply
+func (m *Memory) Request(topic string, body []byte, timeout time.Duration) ([]byte, error) {
+	m.mu.Lock()
+	responders := m.responders[topic]
+	if len(responders) == 0 {
+		m.mu.Unlock()
+		return nil, ErrNoResponder
+	}
+	r := responders[m.next[topic]%len(responders)]
+	m.next[topic]++
+	m.mu.Unlock()
+
+	reply := make(chan []byte, 1)
+	go func() {
+		reply <- r(body)
+	}()
+	select {
+	case data := <-reply:
+		return data, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	case <-m.done:
+		return nil, ErrClosed
+	}
+}
+
+// Respond registers a responder for the topic's requests
+func (m *Memory) Respond(topic string, r Responder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responders[topic] = append(m.responders[topic], r)
+	return nil
+}
+
+// Close stops the subscribers and fails pending requests
+func (m *Memory) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+	})
+	return nil
+}
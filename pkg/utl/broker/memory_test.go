@@ -0,0 +1,86 @@
+package broker_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"my-chat-jobsity-challenge/pkg/utl/broker"
+)
+
+func TestMemoryPubSub(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+
+	const n = 100
+	var wg sync.WaitGroup
+	got := make([][]string, 2)
+	for i := range got {
+		i := i
+		wg.Add(n)
+		_, err := b.Subscribe("chat.general", func(body []byte) {
+			got[i] = append(got[i], string(body))
+			wg.Done()
+		})
+		assert.Nil(t, err)
+	}
+	_, err := b.Subscribe("chat.random", func(body []byte) {
+		t.Errorf("unexpected message %s", body)
+	})
+	assert.Nil(t, err)
+	sub, err := b.Subscribe("chat.general", func(body []byte) {
+		t.Errorf("unexpected message %s", body)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, sub.Unsubscribe())
+
+	var want []string
+	for i := 0; i < n; i++ {
+		msg := string(rune('a' + i%26))
+		want = append(want, msg)
+		assert.Nil(t, b.Publish("chat.general", []byte(msg)))
+	}
+	wg.Wait()
+	for i := range got {
+		assert.Equal(t, want, got[i])
+	}
+}
+
+func TestMemoryRequest(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+
+	_, err := b.Request("stock", []byte("aapl.us"), time.Second)
+	assert.Equal(t, broker.ErrNoResponder, err)
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	for _, name := range []string{"first", "second"} {
+		name := name
+		assert.Nil(t, b.Respond("stock", func(body []byte) []byte {
+			mu.Lock()
+			calls[name]++
+			mu.Unlock()
+			if string(body) == "slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			return append([]byte("re: "), body...)
+		}))
+	}
+
+	for i := 0; i < 4; i++ {
+		reply, err := b.Request("stock", []byte("aapl.us"), time.Second)
+		assert.Nil(t, err)
+		assert.Equal(t, "re: aapl.us", string(reply))
+	}
+	assert.Equal(t, map[string]int{"first": 2, "second": 2}, calls)
+
+	_, err = b.Request("stock", []byte("slow"), 10*time.Millisecond)
+	assert.Equal(t, broker.ErrTimeout, err)
+
+	b.Close()
+	_, err = b.Subscribe("stock", func([]byte) {})
+	assert.Equal(t, broker.ErrClosed, err)
+}
@@ -22,10 +22,13 @@ func Load(path string) (*Configuration, error) {
 
 // Configuration holds data necessary for configuring application
 type Configuration struct {
-	Server *Server      `yaml:"server,omitempty"`
-	DB     *Database    `yaml:"database,omitempty"`
-	JWT    *JWT         `yaml:"jwt,omitempty"`
-	App    *Application `yaml:"application,omitempty"`
+	Server   *Server      `yaml:"server,omitempty"`
+	DB       *Database    `yaml:"database,omitempty"`
+	JWT      *JWT         `yaml:"jwt,omitempty"`
+	App      *Application `yaml:"application,omitempty"`
+	Chat     *Chat        `yaml:"chat,omitempty"`
+	Broker   *Broker      `yaml:"broker,omitempty"`
+	StockBot *StockBot    `yaml:"stockbot,omitempty"`
 }
 
 // Database holds data necessary for database configuration
@@ -57,3 +60,54 @@ type Application struct {
 	MinPasswordStr int    `yaml:"min_password_strength,omitempty"`
 	SwaggerUIPath  string `yaml:"swagger_ui_path,omitempty"`
 }
+
+// Chat holds chat service configuration details
+type Chat struct {
+	Rooms         []string   `yaml:"rooms,omitempty"`
+	QueueSize     int        `yaml:"queue_size,omitempty"`
+	HighWaterMark int        `yaml:"high_water_mark,omitempty"`
+	WriteTimeout  int        `yaml:"write_timeout_seconds,omitempty"`
+	StockQueue    string     `yaml:"stock_queue,omitempty"`
+	StockTimeout  int        `yaml:"stock_timeout_seconds,omitempty"`
+	RateLimit     *RateLimit `yaml:"rate_limit,omitempty"`
+	Filters       *Filters   `yaml:"filters,omitempty"`
+	// ReadReceipts is the largest number of users present in a room for read receipts to be sent to it
+	ReadReceipts int `yaml:"read_receipts_max_users,omitempty"`
+	// EditWindow is how long senders may edit or delete their messages, 15 minutes when left out
+	EditWindow int `yaml:"edit_window_seconds,omitempty"`
+	// Heartbeat is how often chat instances tell the others they are alive, 10 seconds when left out
+	Heartbeat int `yaml:"heartbeat_seconds,omitempty"`
+}
+
+// RateLimit holds chat message rate limiting configuration details
+type RateLimit struct {
+	UserMessages    int `yaml:"user_messages,omitempty"`
+	UserInterval    int `yaml:"user_interval_seconds,omitempty"`
+	RoomMessages    int `yaml:"room_messages,omitempty"`
+	RoomInterval    int `yaml:"room_interval_seconds,omitempty"`
+	Violations      int `yaml:"violations,omitempty"`
+	ViolationWindow int `yaml:"violation_window_seconds,omitempty"`
+	MuteDuration    int `yaml:"mute_seconds,omitempty"`
+}
+
+// Broker holds message broker configuration details
+type Broker struct {
+	Driver   string `yaml:"driver,omitempty"`
+	URL      string `yaml:"url,omitempty"`
+	Exchange string `yaml:"exchange,omitempty"`
+}
+
+// Filters holds chat message filters configuration details
+type Filters struct {
+	Default          []string `yaml:"default,omitempty"`
+	MaxLength        int      `yaml:"max_message_length,omitempty"`
+	ProfanityFile    string   `yaml:"profanity_file,omitempty"`
+	BlockedWordsFile string   `yaml:"blocked_words_file,omitempty"`
+}
+
+// StockBot holds stock bot configuration details
+type StockBot struct {
+	APIURL   string `yaml:"api_url,omitempty"`
+	Timeout  int    `yaml:"timeout_seconds,omitempty"`
+	CacheTTL int    `yaml:"cache_ttl_seconds,omitempty"`
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo"
@@ -18,7 +19,7 @@ type TokenParser interface {
 func Middleware(tokenParser TokenParser) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			token, err := tokenParser.ParseToken(c.Request().Header.Get("Authorization"))
+			token, err := tokenParser.ParseToken(authHeader(c))
 			if err != nil || !token.Valid {
 				return c.NoContent(http.StatusUnauthorized)
 			}
@@ -43,3 +44,17 @@ func Middleware(tokenParser TokenParser) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// authHeader returns the Authorization header of the request. Browsers cannot set
+// headers on WebSocket handshakes, so upgrade requests may pass the token in the
+// token query parameter instead.
+func authHeader(c echo.Context) string {
+	header := c.Request().Header.Get("Authorization")
+	if header != "" || !strings.EqualFold(c.Request().Header.Get("Upgrade"), "websocket") {
+		return header
+	}
+	if token := c.QueryParam("token"); token != "" {
+		return "Bearer " + token
+	}
+	return ""
+}
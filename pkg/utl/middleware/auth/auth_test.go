@@ -54,11 +54,26 @@ func TestMWFunc(t *testing.T) {
 	cases := map[string]struct {
 		wantStatus int
 		header     string
+		query      string
+		upgrade    bool
 		signMethod string
 	}{
 		"Empty header": {
 			wantStatus: http.StatusUnauthorized,
 		},
+		"Query token without websocket upgrade": {
+			query:      "?token=123",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"Websocket upgrade without token": {
+			upgrade:    true,
+			wantStatus: http.StatusUnauthorized,
+		},
+		"Query token on websocket upgrade": {
+			query:      "?token=123",
+			upgrade:    true,
+			wantStatus: http.StatusOK,
+		},
 		"Success": {
 			header:     "Bearer 123",
 			wantStatus: http.StatusOK,
@@ -71,8 +86,11 @@ func TestMWFunc(t *testing.T) {
 
 	for name, tt := range cases {
 		t.Run(name, func(t *testing.T) {
-			req, _ := http.NewRequest("GET", path, nil)
+			req, _ := http.NewRequest("GET", path+tt.query, nil)
 			req.Header.Set("Authorization", tt.header)
+			if tt.upgrade {
+				req.Header.Set("Upgrade", "websocket")
+			}
 			res, err := client.Do(req)
 			if err != nil {
 				t.Fatal("Cannot create http request")
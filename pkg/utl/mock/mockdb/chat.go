@@ -0,0 +1,33 @@
+package mockdb
+
+import "my-chat-jobsity-challenge/pkg/api/chat"
+
+// ChatRepositories fills the chat repositories left nil with the in-memory repository mocks, so tests only set
+// the ones they check
+func ChatRepositories(repos chat.Repositories) chat.Repositories {
+	if repos.Messages == nil {
+		repos.Messages = NewMessage()
+	}
+	if repos.Rooms == nil {
+		repos.Rooms = NewRoom()
+	}
+	if repos.Members == nil {
+		repos.Members = NewMember()
+	}
+	if repos.Tenants == nil {
+		repos.Tenants = NewTenant()
+	}
+	if repos.Moderation == nil {
+		repos.Moderation = NewModeration()
+	}
+	if repos.Users == nil {
+		repos.Users = NewUser()
+	}
+	if repos.Directs == nil {
+		repos.Directs = NewDirect()
+	}
+	if repos.Reads == nil {
+		repos.Reads = NewRead()
+	}
+	return repos
+}
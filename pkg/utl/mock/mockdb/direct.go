@@ -0,0 +1,76 @@
+package mockdb
+
+import (
+	"sync"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Direct database mock
+type Direct struct {
+	TouchFn    func(orm.DB, int, int) error
+	ListFn     func(orm.DB, int, jobsity.Pagination) ([]jobsity.Conversation, error)
+	MarkReadFn func(orm.DB, int, int, int) error
+}
+
+// NewDirect returns a direct conversation repository mock keeping the conversations in memory, latest first
+func NewDirect() *Direct {
+	var mu sync.Mutex
+	var convs []jobsity.Conversation
+	return &Direct{
+		TouchFn: func(db orm.DB, userID, peerID int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, side := range [][2]int{{userID, peerID}, {peerID, userID}} {
+				conv := jobsity.Conversation{UserID: side[0], PeerID: side[1]}
+				for i, c := range convs {
+					if c.UserID == side[0] && c.PeerID == side[1] {
+						conv = c
+						convs = append(convs[:i], convs[i+1:]...)
+						break
+					}
+				}
+				convs = append([]jobsity.Conversation{conv}, convs...)
+			}
+			return nil
+		},
+		ListFn: func(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Conversation, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.Conversation
+			for _, c := range convs {
+				if c.UserID == userID {
+					list = append(list, c)
+				}
+			}
+			return list, nil
+		},
+		MarkReadFn: func(db orm.DB, userID, peerID, messageID int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for i, c := range convs {
+				if c.UserID == userID && c.PeerID == peerID && c.LastReadID < messageID {
+					convs[i].LastReadID = messageID
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Touch mock
+func (d *Direct) Touch(db orm.DB, userID, peerID int) error {
+	return d.TouchFn(db, userID, peerID)
+}
+
+// List mock
+func (d *Direct) List(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Conversation, error) {
+	return d.ListFn(db, userID, p)
+}
+
+// MarkRead mock
+func (d *Direct) MarkRead(db orm.DB, userID, peerID, messageID int) error {
+	return d.MarkReadFn(db, userID, peerID, messageID)
+}
@@ -0,0 +1,92 @@
+package mockdb
+
+import (
+	"sync"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Member database mock
+type Member struct {
+	CreateFn func(orm.DB, jobsity.RoomMember) (jobsity.RoomMember, error)
+	ViewFn   func(orm.DB, int, int) (jobsity.RoomMember, error)
+	ListFn   func(orm.DB, int) ([]jobsity.RoomMember, error)
+	DeleteFn func(orm.DB, jobsity.RoomMember) error
+}
+
+// NewMember returns a room member repository mock keeping the memberships in memory
+func NewMember() *Member {
+	var mu sync.Mutex
+	var members []jobsity.RoomMember
+	find := func(roomID, userID int) int {
+		for i, m := range members {
+			if m.RoomID == roomID && m.UserID == userID {
+				return i
+			}
+		}
+		return -1
+	}
+	return &Member{
+		CreateFn: func(db orm.DB, member jobsity.RoomMember) (jobsity.RoomMember, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if find(member.RoomID, member.UserID) >= 0 {
+				return jobsity.RoomMember{}, pgsql.ErrAlreadyMember
+			}
+			member.ID = len(members) + 1
+			members = append(members, member)
+			return member, nil
+		},
+		ViewFn: func(db orm.DB, roomID, userID int) (jobsity.RoomMember, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			i := find(roomID, userID)
+			if i < 0 {
+				return jobsity.RoomMember{}, pgsql.ErrMemberNotFound
+			}
+			return members[i], nil
+		},
+		ListFn: func(db orm.DB, roomID int) ([]jobsity.RoomMember, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.RoomMember
+			for _, m := range members {
+				if m.RoomID == roomID {
+					list = append(list, m)
+				}
+			}
+			return list, nil
+		},
+		DeleteFn: func(db orm.DB, member jobsity.RoomMember) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if i := find(member.RoomID, member.UserID); i >= 0 {
+				members = append(members[:i], members[i+1:]...)
+			}
+			return nil
+		},
+	}
+}
+
+// Create mock
+func (m *Member) Create(db orm.DB, member jobsity.RoomMember) (jobsity.RoomMember, error) {
+	return m.CreateFn(db, member)
+}
+
+// View mock
+func (m *Member) View(db orm.DB, roomID, userID int) (jobsity.RoomMember, error) {
+	return m.ViewFn(db, roomID, userID)
+}
+
+// List mock
+func (m *Member) List(db orm.DB, roomID int) ([]jobsity.RoomMember, error) {
+	return m.ListFn(db, roomID)
+}
+
+// Delete mock
+func (m *Member) Delete(db orm.DB, member jobsity.RoomMember) error {
+	return m.DeleteFn(db, member)
+}
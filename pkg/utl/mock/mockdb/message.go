@@ -0,0 +1,214 @@
+package mockdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Message database mock
+type Message struct {
+	CreateFn         func(orm.DB, jobsity.Message) (jobsity.Message, error)
+	ViewFn           func(orm.DB, int) (jobsity.Message, error)
+	ListFn           func(orm.DB, string, jobsity.Pagination) ([]jobsity.Message, error)
+	ListRepliesFn    func(orm.DB, int, jobsity.Pagination) ([]jobsity.Message, error)
+	UpdateFn         func(orm.DB, jobsity.Message) error
+	DeleteFn         func(orm.DB, jobsity.Message) error
+	CreateEditFn     func(orm.DB, jobsity.MessageEdit) (jobsity.MessageEdit, error)
+	ListEditsFn      func(orm.DB, int) ([]jobsity.MessageEdit, error)
+	ToggleReactionFn func(orm.DB, jobsity.Reaction) (bool, error)
+	CreateMentionsFn func(orm.DB, []jobsity.Mention) error
+	ListMentionsFn   func(orm.DB, int, jobsity.Pagination) ([]jobsity.Mention, error)
+}
+
+// NewMessage returns a message repository mock keeping the messages, their edits, reactions and mentions in memory
+func NewMessage() *Message {
+	var mu sync.Mutex
+	var msgs []jobsity.Message
+	var edits []jobsity.MessageEdit
+	var reactions []jobsity.Reaction
+	var mentions []jobsity.Mention
+	aggregate := func(msg jobsity.Message) jobsity.Message {
+		for _, m := range msgs {
+			if m.ParentID == msg.ID && m.DeletedAt.IsZero() {
+				msg.Replies++
+			}
+		}
+		var own []jobsity.Reaction
+		for _, r := range reactions {
+			if r.MessageID == msg.ID {
+				own = append(own, r)
+			}
+		}
+		msg.Reactions = jobsity.CountReactions(own)
+		return msg
+	}
+	return &Message{
+		CreateFn: func(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			msg.ID = len(msgs) + 1
+			msg.CreatedAt = time.Now()
+			msgs = append(msgs, msg)
+			return msg, nil
+		},
+		ViewFn: func(db orm.DB, id int) (jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if id < 1 || id > len(msgs) || !msgs[id-1].DeletedAt.IsZero() {
+				return jobsity.Message{}, pgsql.ErrMessageNotFound
+			}
+			return aggregate(msgs[id-1]), nil
+		},
+		ListFn: func(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.Message
+			for _, m := range msgs {
+				if m.Room == room && m.ParentID == 0 && m.DeletedAt.IsZero() {
+					list = append(list, aggregate(m))
+				}
+			}
+			if p.Limit > 0 && len(list) > p.Limit {
+				list = list[len(list)-p.Limit:]
+			}
+			return list, nil
+		},
+		ListRepliesFn: func(db orm.DB, parentID int, p jobsity.Pagination) ([]jobsity.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.Message
+			for _, m := range msgs {
+				if m.ParentID == parentID && m.DeletedAt.IsZero() {
+					list = append(list, aggregate(m))
+				}
+			}
+			return list, nil
+		},
+		UpdateFn: func(db orm.DB, msg jobsity.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			msgs[msg.ID-1].Body = msg.Body
+			msgs[msg.ID-1].Mentions = msg.Mentions
+			msgs[msg.ID-1].EditedAt = msg.EditedAt
+			return nil
+		},
+		DeleteFn: func(db orm.DB, msg jobsity.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			msgs[msg.ID-1].DeletedAt = time.Now()
+			return nil
+		},
+		CreateEditFn: func(db orm.DB, edit jobsity.MessageEdit) (jobsity.MessageEdit, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			edit.ID = len(edits) + 1
+			edits = append(edits, edit)
+			return edit, nil
+		},
+		ListEditsFn: func(db orm.DB, messageID int) ([]jobsity.MessageEdit, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.MessageEdit
+			for _, e := range edits {
+				if e.MessageID == messageID {
+					list = append(list, e)
+				}
+			}
+			return list, nil
+		},
+		ToggleReactionFn: func(db orm.DB, r jobsity.Reaction) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for i, existing := range reactions {
+				if existing.MessageID == r.MessageID && existing.UserID == r.UserID && existing.Emoji == r.Emoji {
+					reactions = append(reactions[:i], reactions[i+1:]...)
+					return false, nil
+				}
+			}
+			reactions = append(reactions, r)
+			return true, nil
+		},
+		CreateMentionsFn: func(db orm.DB, list []jobsity.Mention) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, m := range list {
+				m.ID = len(mentions) + 1
+				mentions = append(mentions, m)
+			}
+			return nil
+		},
+		ListMentionsFn: func(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Mention, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.Mention
+			for i := len(mentions) - 1; i >= 0; i-- {
+				m := mentions[i]
+				if msg := msgs[m.MessageID-1]; m.UserID == userID && msg.DeletedAt.IsZero() {
+					m.Message = &msg
+					list = append(list, m)
+				}
+			}
+			return list, nil
+		},
+	}
+}
+
+// Create mock
+func (m *Message) Create(db orm.DB, msg jobsity.Message) (jobsity.Message, error) {
+	return m.CreateFn(db, msg)
+}
+
+// View mock
+func (m *Message) View(db orm.DB, id int) (jobsity.Message, error) {
+	return m.ViewFn(db, id)
+}
+
+// List mock
+func (m *Message) List(db orm.DB, room string, p jobsity.Pagination) ([]jobsity.Message, error) {
+	return m.ListFn(db, room, p)
+}
+
+// ListReplies mock
+func (m *Message) ListReplies(db orm.DB, parentID int, p jobsity.Pagination) ([]jobsity.Message, error) {
+	return m.ListRepliesFn(db, parentID, p)
+}
+
+// Update mock
+func (m *Message) Update(db orm.DB, msg jobsity.Message) error {
+	return m.UpdateFn(db, msg)
+}
+
+// Delete mock
+func (m *Message) Delete(db orm.DB, msg jobsity.Message) error {
+	return m.DeleteFn(db, msg)
+}
+
+// CreateEdit mock
+func (m *Message) CreateEdit(db orm.DB, edit jobsity.MessageEdit) (jobsity.MessageEdit, error) {
+	return m.CreateEditFn(db, edit)
+}
+
+// ListEdits mock
+func (m *Message) ListEdits(db orm.DB, messageID int) ([]jobsity.MessageEdit, error) {
+	return m.ListEditsFn(db, messageID)
+}
+
+// ToggleReaction mock
+func (m *Message) ToggleReaction(db orm.DB, r jobsity.Reaction) (bool, error) {
+	return m.ToggleReactionFn(db, r)
+}
+
+// CreateMentions mock
+func (m *Message) CreateMentions(db orm.DB, mentions []jobsity.Mention) error {
+	return m.CreateMentionsFn(db, mentions)
+}
+
+// ListMentions mock
+func (m *Message) ListMentions(db orm.DB, userID int, p jobsity.Pagination) ([]jobsity.Mention, error) {
+	return m.ListMentionsFn(db, userID, p)
+}
@@ -0,0 +1,103 @@
+package mockdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Moderation database mock
+type Moderation struct {
+	CreateSanctionFn func(orm.DB, jobsity.Sanction) (jobsity.Sanction, error)
+	ViewSanctionFn   func(orm.DB, int, int, jobsity.SanctionKind) (jobsity.Sanction, error)
+	DeleteSanctionFn func(orm.DB, jobsity.Sanction) error
+	CreateLogFn      func(orm.DB, jobsity.ModerationLog) (jobsity.ModerationLog, error)
+	ListLogFn        func(orm.DB, int, jobsity.Pagination) ([]jobsity.ModerationLog, error)
+}
+
+// NewModeration returns a sanction and moderation log repository mock keeping them in memory
+func NewModeration() *Moderation {
+	var mu sync.Mutex
+	var sanctions []jobsity.Sanction
+	var logs []jobsity.ModerationLog
+	return &Moderation{
+		CreateSanctionFn: func(db orm.DB, s jobsity.Sanction) (jobsity.Sanction, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			// The user's sanction of the same kind is replaced
+			for i, old := range sanctions {
+				if old.RoomID == s.RoomID && old.UserID == s.UserID && old.Kind == s.Kind {
+					sanctions[i].DeletedAt = time.Now()
+				}
+			}
+			s.ID = len(sanctions) + 1
+			sanctions = append(sanctions, s)
+			return s, nil
+		},
+		ViewSanctionFn: func(db orm.DB, roomID, userID int, kind jobsity.SanctionKind) (jobsity.Sanction, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for i := len(sanctions) - 1; i >= 0; i-- {
+				s := sanctions[i]
+				if s.RoomID == roomID && s.UserID == userID && s.Kind == kind && s.DeletedAt.IsZero() &&
+					(s.ExpiresAt == nil || s.ExpiresAt.After(time.Now())) {
+					return s, nil
+				}
+			}
+			return jobsity.Sanction{}, pgsql.ErrSanctionNotFound
+		},
+		DeleteSanctionFn: func(db orm.DB, s jobsity.Sanction) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sanctions[s.ID-1].DeletedAt = time.Now()
+			return nil
+		},
+		CreateLogFn: func(db orm.DB, l jobsity.ModerationLog) (jobsity.ModerationLog, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			l.ID = len(logs) + 1
+			logs = append(logs, l)
+			return l, nil
+		},
+		ListLogFn: func(db orm.DB, roomID int, p jobsity.Pagination) ([]jobsity.ModerationLog, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.ModerationLog
+			for i := len(logs) - 1; i >= 0; i-- {
+				if logs[i].RoomID == roomID {
+					list = append(list, logs[i])
+				}
+			}
+			return list, nil
+		},
+	}
+}
+
+// CreateSanction mock
+func (m *Moderation) CreateSanction(db orm.DB, s jobsity.Sanction) (jobsity.Sanction, error) {
+	return m.CreateSanctionFn(db, s)
+}
+
+// ViewSanction mock
+func (m *Moderation) ViewSanction(db orm.DB, roomID, userID int, kind jobsity.SanctionKind) (jobsity.Sanction, error) {
+	return m.ViewSanctionFn(db, roomID, userID, kind)
+}
+
+// DeleteSanction mock
+func (m *Moderation) DeleteSanction(db orm.DB, s jobsity.Sanction) error {
+	return m.DeleteSanctionFn(db, s)
+}
+
+// CreateLog mock
+func (m *Moderation) CreateLog(db orm.DB, l jobsity.ModerationLog) (jobsity.ModerationLog, error) {
+	return m.CreateLogFn(db, l)
+}
+
+// ListLog mock
+func (m *Moderation) ListLog(db orm.DB, roomID int, p jobsity.Pagination) ([]jobsity.ModerationLog, error) {
+	return m.ListLogFn(db, roomID, p)
+}
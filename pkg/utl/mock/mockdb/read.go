@@ -0,0 +1,55 @@
+package mockdb
+
+import (
+	"sync"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+)
+
+// Read database mock
+type Read struct {
+	MarkReadFn func(orm.DB, int, string, int) (bool, error)
+	ListFn     func(orm.DB, int, []string) ([]jobsity.ReadCursor, error)
+}
+
+// NewRead returns a read cursor repository mock keeping the cursors in memory, without unread messages
+func NewRead() *Read {
+	type cursor struct {
+		userID int
+		room   string
+	}
+	var mu sync.Mutex
+	cursors := make(map[cursor]int)
+	return &Read{
+		MarkReadFn: func(db orm.DB, userID int, room string, messageID int) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if cursors[cursor{userID, room}] >= messageID {
+				return false, nil
+			}
+			cursors[cursor{userID, room}] = messageID
+			return true, nil
+		},
+		ListFn: func(db orm.DB, userID int, rooms []string) ([]jobsity.ReadCursor, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.ReadCursor
+			for _, room := range rooms {
+				list = append(list, jobsity.ReadCursor{UserID: userID, Room: room, LastReadID: cursors[cursor{userID, room}]})
+			}
+			return list, nil
+		},
+	}
+}
+
+// MarkRead mock
+func (r *Read) MarkRead(db orm.DB, userID int, room string, messageID int) (bool, error) {
+	return r.MarkReadFn(db, userID, room, messageID)
+}
+
+// List mock
+func (r *Read) List(db orm.DB, userID int, rooms []string) ([]jobsity.ReadCursor, error) {
+	return r.ListFn(db, userID, rooms)
+}
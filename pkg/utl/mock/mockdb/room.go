@@ -0,0 +1,101 @@
+package mockdb
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Room database mock
+type Room struct {
+	CreateFn func(orm.DB, jobsity.Room) (jobsity.Room, error)
+	ViewFn   func(orm.DB, string, int) (jobsity.Room, error)
+	ListFn   func(orm.DB, []jobsity.ListQuery, bool, jobsity.Pagination) ([]jobsity.Room, error)
+	UpdateFn func(orm.DB, jobsity.Room) error
+	DeleteFn func(orm.DB, jobsity.Room) error
+}
+
+// NewRoom returns a room repository mock keeping the rooms in memory
+func NewRoom() *Room {
+	var mu sync.Mutex
+	rooms := map[string]jobsity.Room{}
+	return &Room{
+		CreateFn: func(db orm.DB, room jobsity.Room) (jobsity.Room, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := rooms[room.Key()]; ok {
+				return jobsity.Room{}, pgsql.ErrRoomAlreadyExists
+			}
+			room.ID = len(rooms) + 1
+			rooms[room.Key()] = room
+			return room, nil
+		},
+		ViewFn: func(db orm.DB, name string, companyID int) (jobsity.Room, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if room, ok := rooms[jobsity.Room{Name: name, CompanyID: companyID}.Key()]; ok {
+				return room, nil
+			}
+			room, ok := rooms[name]
+			if !ok {
+				return jobsity.Room{}, pgsql.ErrRoomNotFound
+			}
+			return room, nil
+		},
+		ListFn: func(db orm.DB, qp []jobsity.ListQuery, archived bool, p jobsity.Pagination) ([]jobsity.Room, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var list []jobsity.Room
+			for _, room := range rooms {
+				if room.Archived == archived {
+					list = append(list, room)
+				}
+			}
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].Name < list[j].Name
+			})
+			return list, nil
+		},
+		UpdateFn: func(db orm.DB, room jobsity.Room) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rooms[room.Key()] = room
+			return nil
+		},
+		DeleteFn: func(db orm.DB, room jobsity.Room) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(rooms, room.Key())
+			return nil
+		},
+	}
+}
+
+// Create mock
+func (r *Room) Create(db orm.DB, room jobsity.Room) (jobsity.Room, error) {
+	return r.CreateFn(db, room)
+}
+
+// View mock
+func (r *Room) View(db orm.DB, name string, companyID int) (jobsity.Room, error) {
+	return r.ViewFn(db, name, companyID)
+}
+
+// List mock
+func (r *Room) List(db orm.DB, qp []jobsity.ListQuery, archived bool, p jobsity.Pagination) ([]jobsity.Room, error) {
+	return r.ListFn(db, qp, archived, p)
+}
+
+// Update mock
+func (r *Room) Update(db orm.DB, room jobsity.Room) error {
+	return r.UpdateFn(db, room)
+}
+
+// Delete mock
+func (r *Room) Delete(db orm.DB, room jobsity.Room) error {
+	return r.DeleteFn(db, room)
+}
@@ -0,0 +1,54 @@
+package mockdb
+
+import (
+	"github.com/go-pg/pg/v9/orm"
+
+	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
+)
+
+// Tenant database mock
+type Tenant struct {
+	CompaniesFn func(orm.DB) ([]jobsity.Company, error)
+	LocationsFn func(orm.DB) ([]jobsity.Location, error)
+	CompanyFn   func(orm.DB, int) (jobsity.Company, error)
+	LocationFn  func(orm.DB, int) (jobsity.Location, error)
+}
+
+// NewTenant returns a company and location repository mock without any company
+func NewTenant() *Tenant {
+	return &Tenant{
+		CompaniesFn: func(db orm.DB) ([]jobsity.Company, error) {
+			return nil, nil
+		},
+		LocationsFn: func(db orm.DB) ([]jobsity.Location, error) {
+			return nil, nil
+		},
+		CompanyFn: func(db orm.DB, id int) (jobsity.Company, error) {
+			return jobsity.Company{}, pgsql.ErrCompanyNotFound
+		},
+		LocationFn: func(db orm.DB, id int) (jobsity.Location, error) {
+			return jobsity.Location{}, pgsql.ErrLocationNotFound
+		},
+	}
+}
+
+// Companies mock
+func (t *Tenant) Companies(db orm.DB) ([]jobsity.Company, error) {
+	return t.CompaniesFn(db)
+}
+
+// Company mock
+func (t *Tenant) Company(db orm.DB, id int) (jobsity.Company, error) {
+	return t.CompanyFn(db, id)
+}
+
+// Location mock
+func (t *Tenant) Location(db orm.DB, id int) (jobsity.Location, error) {
+	return t.LocationFn(db, id)
+}
+
+// Locations mock
+func (t *Tenant) Locations(db orm.DB) ([]jobsity.Location, error) {
+	return t.LocationsFn(db)
+}
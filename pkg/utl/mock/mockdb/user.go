@@ -1,9 +1,12 @@
 package mockdb
 
 import (
+	"time"
+
 	"github.com/go-pg/pg/v9/orm"
 
 	"my-chat-jobsity-challenge"
+	"my-chat-jobsity-challenge/pkg/api/chat/platform/pgsql"
 )
 
 // User database mock
@@ -15,6 +18,38 @@ type User struct {
 	ListFn           func(orm.DB, *jobsity.ListQuery, jobsity.Pagination) ([]jobsity.User, error)
 	DeleteFn         func(orm.DB, jobsity.User) error
 	UpdateFn         func(orm.DB, jobsity.User) error
+	UpdateLastSeenFn func(orm.DB, int, time.Time) error
+}
+
+// NewUser returns a user repository mock finding the given users
+func NewUser(users ...jobsity.User) *User {
+	// Users without a role are regular users
+	for i := range users {
+		if users[i].RoleID == 0 {
+			users[i].RoleID = jobsity.UserRole
+		}
+	}
+	return &User{
+		ViewFn: func(db orm.DB, id int) (jobsity.User, error) {
+			for _, u := range users {
+				if u.ID == id {
+					return u, nil
+				}
+			}
+			return jobsity.User{}, pgsql.ErrUserNotFound
+		},
+		FindByUsernameFn: func(db orm.DB, username string) (jobsity.User, error) {
+			for _, u := range users {
+				if u.Username == username {
+					return u, nil
+				}
+			}
+			return jobsity.User{}, pgsql.ErrUserNotFound
+		},
+		UpdateLastSeenFn: func(db orm.DB, id int, t time.Time) error {
+			return nil
+		},
+	}
 }
 
 // Create mock
@@ -51,3 +86,8 @@ func (u *User) Delete(db orm.DB, usr jobsity.User) error {
 func (u *User) Update(db orm.DB, usr jobsity.User) error {
 	return u.UpdateFn(db, usr)
 }
+
+// UpdateLastSeen mock
+func (u *User) UpdateLastSeen(db orm.DB, id int, t time.Time) error {
+	return u.UpdateLastSeenFn(db, id, t)
+}
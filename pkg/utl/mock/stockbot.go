@@ -0,0 +1,15 @@
+package mock
+
+import (
+	"my-chat-jobsity-challenge"
+)
+
+// StockBot mock
+type StockBot struct {
+	QuoteFn func(string) (jobsity.StockReply, error)
+}
+
+// Quote mock
+func (s StockBot) Quote(stockCode string) (jobsity.StockReply, error) {
+	return s.QuoteFn(stockCode)
+}
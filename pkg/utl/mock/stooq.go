@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Stooq returns a fake stooq.com CSV API handler serving the closing prices of quotes.
+// Unknown symbols are answered with N/D values, the way stooq does.
+func Stooq(quotes map[string]float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := strings.ToUpper(r.URL.Query().Get("s"))
+		w.Header().Set("Content-Type", "text/csv")
+		if _, ok := r.URL.Query()["h"]; ok {
+			fmt.Fprintln(w, "Symbol,Date,Time,Open,High,Low,Close,Volume")
+		}
+		price, ok := quotes[symbol]
+		if !ok {
+			fmt.Fprintf(w, "%s,N/D,N/D,N/D,N/D,N/D,N/D,N/D\n", symbol)
+			return
+		}
+		fmt.Fprintf(w, "%s,2023-04-21,22:00:19,%.2f,%.2f,%.2f,%.2f,46467543\n", symbol, price, price, price, price)
+	}
+}
@@ -0,0 +1,44 @@
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+// NewWSConn returns the server and client ends of a WebSocket connection
+// served by a test server, which is shut down when the test finishes.
+// The first of the subprotocols requested by the client is accepted by the server.
+func NewWSConn(t *testing.T, protocol ...string) (*websocket.Conn, *websocket.Conn) {
+	conns := make(chan *websocket.Conn)
+	done := make(chan struct{})
+	ts := httptest.NewServer(websocket.Server{
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			if len(cfg.Protocol) > 1 {
+				cfg.Protocol = cfg.Protocol[:1]
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			conns <- ws
+			<-done
+		},
+	})
+
+	cfg, err := websocket.NewConfig("ws"+strings.TrimPrefix(ts.URL, "http"), ts.URL)
+	fatalErr(t, err)
+	cfg.Protocol = protocol
+	client, err := websocket.DialConfig(cfg)
+	fatalErr(t, err)
+	server := <-conns
+
+	t.Cleanup(func() {
+		client.Close()
+		close(done)
+		ts.Close()
+	})
+	return server, client
+}
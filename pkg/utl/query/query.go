@@ -19,3 +19,19 @@ func List(u jobsity.AuthUser) (*jobsity.ListQuery, error) {
 		return nil, echo.ErrForbidden
 	}
 }
+
+// Rooms prepares the tenant scope of chat room list queries. Rooms are listed along with the
+// global ones to the users of their company, and location rooms only to the users of their location.
+func Rooms(u jobsity.AuthUser) []jobsity.ListQuery {
+	switch true {
+	case u.Role <= jobsity.AdminRole: // user is SuperAdmin or Admin
+		return nil
+	case u.Role == jobsity.CompanyAdminRole:
+		return []jobsity.ListQuery{{Query: "company_id in (0, ?)", ID: u.CompanyID}}
+	default:
+		return []jobsity.ListQuery{
+			{Query: "company_id in (0, ?)", ID: u.CompanyID},
+			{Query: "location_id in (0, ?)", ID: u.LocationID},
+		}
+	}
+}
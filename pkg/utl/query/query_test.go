@@ -65,3 +65,36 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestRooms(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     jobsity.AuthUser
+		wantData []jobsity.ListQuery
+	}{
+		{
+			name: "Admin user",
+			user: jobsity.AuthUser{Role: jobsity.AdminRole, CompanyID: 1, LocationID: 1},
+		},
+		{
+			name: "Company admin user",
+			user: jobsity.AuthUser{Role: jobsity.CompanyAdminRole, CompanyID: 1, LocationID: 2},
+			wantData: []jobsity.ListQuery{
+				{Query: "company_id in (0, ?)", ID: 1},
+			},
+		},
+		{
+			name: "Normal user",
+			user: jobsity.AuthUser{Role: jobsity.UserRole, CompanyID: 1, LocationID: 2},
+			wantData: []jobsity.ListQuery{
+				{Query: "company_id in (0, ?)", ID: 1},
+				{Query: "location_id in (0, ?)", ID: 2},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantData, query.Rooms(tt.user))
+		})
+	}
+}
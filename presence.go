@@ -0,0 +1,15 @@
+package jobsity
+
+// Presence statuses of the online users
+const (
+	StatusOnline = "online"
+	// StatusAway is the status of the users whose every chat connection is away
+	StatusAway = "away"
+)
+
+// Presence represents an online user's presence in the chat
+type Presence struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
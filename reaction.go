@@ -0,0 +1,35 @@
+package jobsity
+
+// Reaction represents a user's emoji reaction to a message
+type Reaction struct {
+	Base
+	MessageID int    `json:"message_id"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Emoji     string `json:"emoji"`
+}
+
+// ReactionCount represents the reactions to a message with the same emoji
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// Users are the usernames of the users who reacted, in the order they did
+	Users []string `json:"users"`
+}
+
+// CountReactions aggregates the reactions to a message by emoji, in the order the emojis were first used
+func CountReactions(reactions []Reaction) []ReactionCount {
+	var counts []ReactionCount
+	index := make(map[string]int)
+	for _, r := range reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(counts)
+			index[r.Emoji] = i
+			counts = append(counts, ReactionCount{Emoji: r.Emoji})
+		}
+		counts[i].Count++
+		counts[i].Users = append(counts[i].Users, r.Username)
+	}
+	return counts
+}
@@ -0,0 +1,11 @@
+package jobsity
+
+// ReadCursor represents a user's read position in a room, the ID of the last message the user read there
+type ReadCursor struct {
+	Base
+	UserID     int    `json:"user_id"`
+	Room       string `json:"room"`
+	LastReadID int    `json:"last_read_id"`
+
+	Unread int `json:"unread" pg:"-"`
+}
@@ -1,24 +1,58 @@
 package jobsity
 
-import (
-	"github.com/streadway/amqp"
-	"golang.org/x/net/websocket"
-)
+import "fmt"
 
+// Room represents chat room domain model. Rooms without a company are global, and rooms
+// without a location are shared by every location of their company.
 type Room struct {
-	Name      string
-	Clients   map[*websocket.Conn]bool
-	Broadcast chan []byte
-	Quit      chan bool
-	rabbit    *amqp.Connection
+	Base
+	Name        string `json:"name"`
+	Topic       string `json:"topic"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	Archived    bool   `json:"archived"`
+	CreatedBy   int    `json:"created_by"`
+	// SlowMode is the number of seconds users wait between their messages to the room, 0 when off
+	SlowMode int `json:"slow_mode"`
+	// Filters are the names of the message filters the room applies after the chat's default ones
+	Filters []string `json:"filters" pg:",array"`
+
+	CompanyID  int `json:"company_id" pg:",use_zero"`
+	LocationID int `json:"location_id" pg:",use_zero"`
+
+	// LastReadID and Unread hold the current user's read cursor of the room, when listed
+	LastReadID int `json:"last_read_id" pg:"-"`
+	Unread     int `json:"unread" pg:"-"`
 }
 
-func NewRoom(name string, rabbit *amqp.Connection) *Room {
-	return &Room{
-		Name:      name,
-		Clients:   make(map[*websocket.Conn]bool),
-		Broadcast: make(chan []byte),
-		Quit:      make(chan bool),
-		rabbit:    rabbit,
+// Key returns the room's identifier across companies, since room names are only unique per company
+func (r Room) Key() string {
+	if r.CompanyID == 0 {
+		return r.Name
 	}
+	return fmt.Sprintf("%d:%s", r.CompanyID, r.Name)
+}
+
+// RoomRole represents room-level role type
+type RoomRole int
+
+const (
+	// RoomOwnerRole can manage the room's members, including other owners
+	RoomOwnerRole RoomRole = 100
+
+	// RoomModeratorRole can invite and remove members, except owners
+	RoomModeratorRole RoomRole = 110
+
+	// RoomMemberRole is a standard room member
+	RoomMemberRole RoomRole = 200
+)
+
+// RoomMember represents room membership domain model
+type RoomMember struct {
+	Base
+	RoomID int      `json:"room_id"`
+	UserID int      `json:"user_id"`
+	Role   RoomRole `json:"role"`
+
+	User *User `json:"user,omitempty"`
 }
@@ -0,0 +1,16 @@
+package jobsity
+
+// StockBotName is the sender of the stock bot's messages
+const StockBotName = "StockBot"
+
+// StockRequest represents stock quote request sent to the stock bot
+type StockRequest struct {
+	Code string `json:"code"`
+}
+
+// StockReply represents stock bot reply to a stock quote request
+type StockReply struct {
+	Code  string  `json:"code"`
+	Price float64 `json:"price,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
@@ -21,6 +21,8 @@ type User struct {
 
 	LastLogin          time.Time `json:"last_login,omitempty"`
 	LastPasswordChange time.Time `json:"last_password_change,omitempty"`
+	// LastSeen is the last time the user was connected to the chat
+	LastSeen time.Time `json:"last_seen,omitempty"`
 
 	Token string `json:"-"`
 